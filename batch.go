@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// --- Batched Commands (/batch) ---
+
+const (
+	batchCommand     = "/batch"
+	batchUsage       = "Usage: /batch followed by one command per line, e.g.\n/batch\n/price btc\n/market eth"
+	maxBatchCommands = 5
+)
+
+// batchLines reports whether input is a /batch message and returns its
+// sub-command lines. Anything after /batch on the first line counts as the
+// first sub-command, so "/batch /price btc" works too.
+func batchLines(input string) ([]string, bool) {
+	trimmed := strings.TrimSpace(input)
+	fields := strings.Fields(trimmed)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != batchCommand {
+		return nil, false
+	}
+
+	var lines []string
+	for _, line := range strings.Split(trimmed[len(fields[0]):], "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, true
+}
+
+// runBatch dispatches each sub-command concurrently and joins the results in
+// input order, each labeled with the command that produced it.
+func (a *PMOAgent) runBatch(ctx context.Context, s session, lines []string) (string, error) {
+	if len(lines) == 0 {
+		return batchUsage, nil
+	}
+	if len(lines) > maxBatchCommands {
+		return fmt.Sprintf("Please send at most %d commands per batch.\n%s", maxBatchCommands, batchUsage), nil
+	}
+
+	outputs := make([]string, len(lines))
+	var wg sync.WaitGroup
+	for i, line := range lines {
+		wg.Add(1)
+		safeGo(fmt.Sprintf("batch command %q", line), func() {
+			defer wg.Done()
+			// Left in place if the sub-command panics
+			outputs[i] = internalErrorMessage
+			if _, nested := batchLines(line); nested {
+				outputs[i] = "A batch cannot contain another /batch."
+				return
+			}
+			output, err := a.dispatch(ctx, s, tokenizeInput(line))
+			if err != nil {
+				slog.Error("Batch command failed", "command", line, "err", err)
+			}
+			outputs[i] = strings.TrimSpace(output)
+		})
+	}
+	wg.Wait()
+
+	blocks := make([]string, len(lines))
+	for i, line := range lines {
+		blocks[i] = fmt.Sprintf("▶️ `%s`\n\n%s", line, outputs[i])
+	}
+	return strings.Join(blocks, "\n\n---\n\n"), nil
+}
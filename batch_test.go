@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBatchRunsMixedCommands(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("symbol") == "ETH" {
+				respond(w, http.StatusOK, cmcQuote("ETH", 3000))
+				return
+			}
+			respond(w, http.StatusOK, cmcQuote("BTC", 60000))
+		},
+	})
+	a := newTestAgent(t, f.env())
+
+	response, err := a.processTask(context.Background(), session{}, "/batch\n/price btc\n/market eth")
+	if err != nil {
+		t.Fatalf("processTask: %v", err)
+	}
+	blocks := strings.Split(response, "\n\n---\n\n")
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks, want one per command:\n%s", len(blocks), response)
+	}
+	if !strings.HasPrefix(blocks[0], "▶️ `/price btc`") || !strings.Contains(blocks[0], "60,000") {
+		t.Errorf("first block is not the labeled BTC price:\n%s", blocks[0])
+	}
+	if !strings.HasPrefix(blocks[1], "▶️ `/market eth`") || !strings.Contains(blocks[1], "3,000") {
+		t.Errorf("second block is not the labeled ETH market data:\n%s", blocks[1])
+	}
+}
+
+func TestBatchCapsSubcommands(t *testing.T) {
+	a := newTestAgent(t, nil)
+	lines := make([]string, maxBatchCommands+1)
+	for i := range lines {
+		lines[i] = "/help"
+	}
+
+	response, _ := a.processTask(context.Background(), session{}, "/batch\n"+strings.Join(lines, "\n"))
+	if !strings.Contains(response, fmt.Sprintf("at most %d commands", maxBatchCommands)) {
+		t.Errorf("oversized batch response = %q, want the cap message", response)
+	}
+	if response, _ := a.processTask(context.Background(), session{}, "/batch"); response != batchUsage {
+		t.Errorf("empty batch response = %q, want the usage", response)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// --- Response Cache ---
+
+// maxCacheEntries caps each cache. Per-request options such as --minliq are
+// part of the key, so without a cap the key space is unbounded.
+const maxCacheEntries = 10000
+
+// cacheEntry is one cached raw provider response. storedAt is when the fetch
+// that produced it started, i.e. how current the data is.
+type cacheEntry struct {
+	value     string
+	storedAt  time.Time
+	expiresAt time.Time
+}
+
+// responseCache is a TTL cache of raw provider responses shared by all
+// concurrent ProcessTask calls. Reads vastly outnumber writes, so lookups
+// share a read lock. Hit and miss counters are atomic so /stats can read them
+// without taking the lock at all. Expired entries are kept for up to maxStale
+// so getStale can serve them during an outage, and evicted once the cache is
+// full.
+type responseCache struct {
+	mu         sync.RWMutex
+	ttl        time.Duration
+	maxStale   time.Duration
+	maxEntries int
+	entries    map[string]cacheEntry
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// newResponseCache creates a cache; a zero TTL disables caching entirely and
+// a zero maxStale never serves expired entries.
+func newResponseCache(ttl, maxStale time.Duration) *responseCache {
+	return &responseCache{
+		ttl:        ttl,
+		maxStale:   maxStale,
+		maxEntries: maxCacheEntries,
+		entries:    make(map[string]cacheEntry),
+	}
+}
+
+// cacheKey builds the key for a lookup target and the options that affect its
+// content. The quote currency is always part of it: a USD response must never
+// answer a EUR request, however the currency was asked for.
+func cacheKey(target, currency, source string, options ...string) string {
+	return strings.ToLower(target) + "|" + strings.ToLower(currency) + "|" + source + "|" + strings.Join(options, "|")
+}
+
+// get returns an unexpired entry, counting the lookup as a hit or miss.
+func (c *responseCache) get(key string) (string, bool) {
+	if c.ttl <= 0 {
+		return "", false
+	}
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		c.misses.Add(1)
+		return "", false
+	}
+	c.hits.Add(1)
+	return entry.value, true
+}
+
+// getStale returns an entry even if it has expired, with its age, for serving
+// old data when every provider is down. Entries expired for longer than
+// maxStale are too old to be useful and are not returned. It doesn't touch
+// the hit counters.
+func (c *responseCache) getStale(key string) (string, time.Duration, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if !ok || c.pastMaxStale(entry, time.Now()) {
+		return "", 0, false
+	}
+	return entry.value, time.Since(entry.storedAt), true
+}
+
+// pastMaxStale reports whether an entry has been expired for too long to serve.
+func (c *responseCache) pastMaxStale(entry cacheEntry, now time.Time) bool {
+	return now.After(entry.expiresAt.Add(c.maxStale))
+}
+
+// set stores a value fetched at fetchedAt for the configured TTL. Concurrent
+// lookups of the same key can finish out of order, so a value from a fetch
+// that started before the cached one is dropped rather than clobbering it.
+func (c *responseCache) set(key, value string, fetchedAt time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	existing, ok := c.entries[key]
+	if ok && existing.storedAt.After(fetchedAt) {
+		return
+	}
+	if !ok && len(c.entries) >= c.maxEntries {
+		c.evictLocked(time.Now())
+	}
+	c.entries[key] = cacheEntry{value: value, storedAt: fetchedAt, expiresAt: fetchedAt.Add(c.ttl)}
+}
+
+// evictLocked makes room in a full cache. Entries past maxStale go first; if
+// that isn't enough, the oldest tenth is dropped so the next few inserts
+// don't each pay for a full scan. The caller must hold the write lock.
+func (c *responseCache) evictLocked(now time.Time) {
+	for key, entry := range c.entries {
+		if c.pastMaxStale(entry, now) {
+			delete(c.entries, key)
+		}
+	}
+	if len(c.entries) < c.maxEntries {
+		return
+	}
+
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(x, y string) int {
+		return c.entries[x].storedAt.Compare(c.entries[y].storedAt)
+	})
+	for _, key := range keys[:len(keys)-c.maxEntries*9/10] {
+		delete(c.entries, key)
+	}
+}
+
+// size returns the number of entries currently held.
+func (c *responseCache) size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// stats returns the hit and miss counts since startup.
+func (c *responseCache) stats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// hitRatio returns hits / (hits + misses) as a percentage, or 0 with no lookups.
+func (c *responseCache) hitRatio() float64 {
+	hits, misses := c.stats()
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses) * 100
+}
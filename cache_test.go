@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetStaleStopsAfterMaxStale(t *testing.T) {
+	c := newResponseCache(time.Minute, time.Hour)
+	now := time.Now()
+	c.set("recent", "a", now.Add(-30*time.Minute))
+	c.set("ancient", "b", now.Add(-2*time.Hour))
+
+	if _, ok := c.get("recent"); ok {
+		t.Error("get served an expired entry")
+	}
+	if value, _, ok := c.getStale("recent"); !ok || value != "a" {
+		t.Errorf("getStale(recent) = %q, %v; want a, true", value, ok)
+	}
+	if _, _, ok := c.getStale("ancient"); ok {
+		t.Error("getStale served an entry older than maxStale")
+	}
+}
+
+func TestSetEvictsWhenFull(t *testing.T) {
+	c := newResponseCache(time.Minute, time.Hour)
+	c.maxEntries = 10
+	start := time.Now().Add(-time.Minute)
+	for i := range 10 {
+		c.set(fmt.Sprintf("key%d", i), "v", start.Add(time.Duration(i)*time.Second))
+	}
+
+	c.set("new", "v", time.Now())
+	if size := c.size(); size > c.maxEntries {
+		t.Fatalf("size = %d, want at most %d", size, c.maxEntries)
+	}
+	if _, ok := c.get("new"); !ok {
+		t.Error("the new entry was not stored")
+	}
+	if _, ok := c.get("key0"); ok {
+		t.Error("the oldest entry survived eviction")
+	}
+	if _, ok := c.get("key9"); !ok {
+		t.Error("the newest existing entry was evicted")
+	}
+}
+
+func TestSetEvictsPastMaxStaleFirst(t *testing.T) {
+	c := newResponseCache(time.Minute, time.Hour)
+	c.maxEntries = 3
+	now := time.Now()
+	c.set("dead", "v", now.Add(-3*time.Hour))
+	c.set("old", "v", now.Add(-10*time.Second))
+	c.set("fresh", "v", now)
+
+	c.set("new", "v", now)
+	for _, key := range []string{"old", "fresh", "new"} {
+		if _, ok := c.get(key); !ok {
+			t.Errorf("%s was evicted, want only the entry past maxStale gone", key)
+		}
+	}
+	if _, _, ok := c.getStale("dead"); ok {
+		t.Error("the entry past maxStale was kept")
+	}
+}
+
+func TestCacheCountsHitsAndMisses(t *testing.T) {
+	c := newResponseCache(time.Minute, 0)
+	c.set("btc", "v", time.Now())
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.get("btc")
+		}()
+		go func() {
+			defer wg.Done()
+			c.get("eth")
+		}()
+	}
+	wg.Wait()
+
+	if hits, misses := c.stats(); hits != 50 || misses != 50 {
+		t.Errorf("stats = %d hits, %d misses; want 50, 50", hits, misses)
+	}
+	if ratio := c.hitRatio(); ratio != 50 {
+		t.Errorf("hitRatio = %v, want 50", ratio)
+	}
+}
+
+func TestHitRatioWithoutLookups(t *testing.T) {
+	if ratio := newResponseCache(time.Minute, 0).hitRatio(); ratio != 0 {
+		t.Errorf("hitRatio = %v, want 0 before any lookup", ratio)
+	}
+}
+
+func TestStatsReportsCacheCounters(t *testing.T) {
+	a := newTestAgent(t, nil)
+	a.cache.set("btc", "v", time.Now())
+	a.cache.get("btc")
+	a.cache.get("btc")
+	a.cache.get("btc")
+	a.cache.get("eth")
+
+	response, err := a.getStats(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"**Cache Hits:** 3", "**Cache Misses:** 1", "**Hit Ratio:** 75.0%"} {
+		if !strings.Contains(response, want) {
+			t.Errorf("/stats missing %q:\n%s", want, response)
+		}
+	}
+}
+
+func TestServeStaleOnError(t *testing.T) {
+	for _, serveStale := range []bool{true, false} {
+		var down atomic.Bool
+		f := newFakeProviders(t, map[string]http.HandlerFunc{
+			"cmc": func(w http.ResponseWriter, r *http.Request) {
+				if down.Load() {
+					respond(w, http.StatusInternalServerError, `{}`)
+					return
+				}
+				respond(w, http.StatusOK, cmcQuote("BTC", 60000))
+			},
+		})
+		env := f.env()
+		env["SERVE_STALE_ON_ERROR"] = fmt.Sprint(serveStale)
+		env["RETRY_BUDGET_PER_MINUTE"] = "0"
+		a := newTestAgent(t, env)
+		ctx := context.Background()
+
+		if response, _ := a.processTask(ctx, session{}, "/price btc"); !strings.Contains(response, "60,000") {
+			t.Fatalf("priming lookup failed:\n%s", response)
+		}
+		// Expire the entry, then take every provider down
+		a.cache.mu.Lock()
+		for key, entry := range a.cache.entries {
+			entry.storedAt = entry.storedAt.Add(-10 * time.Minute)
+			entry.expiresAt = entry.expiresAt.Add(-10 * time.Minute)
+			a.cache.entries[key] = entry
+		}
+		a.cache.mu.Unlock()
+		down.Store(true)
+
+		response, _ := a.processTask(ctx, session{}, "/price btc")
+		served := strings.Contains(response, "60,000") && strings.Contains(response, "All sources unavailable, showing cached data from 10m")
+		if served != serveStale {
+			t.Errorf("SERVE_STALE_ON_ERROR=%v: response =\n%s", serveStale, response)
+		}
+	}
+}
+
+func TestCacheConcurrentAccess(t *testing.T) {
+	c := newResponseCache(time.Minute, time.Hour)
+	c.maxEntries = 50 // small enough that eviction runs during the hammering
+
+	var wg sync.WaitGroup
+	for g := range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				key := fmt.Sprintf("key%d", (g*7+i)%80)
+				c.set(key, "v", time.Now())
+				c.get(key)
+				c.getStale(key)
+				c.size()
+				c.hitRatio()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if size := c.size(); size > c.maxEntries {
+		t.Errorf("size = %d, want at most %d", size, c.maxEntries)
+	}
+	if hits, misses := c.stats(); hits+misses != 32*200 {
+		t.Errorf("counted %d lookups, want %d", hits+misses, 32*200)
+	}
+}
+
+func TestConcurrentSetKeepsFreshestValue(t *testing.T) {
+	c := newResponseCache(time.Minute, time.Hour)
+	base := time.Now()
+
+	// Fetches started at base+0..63ms finish in random order; whatever order
+	// the writes land in, the one from the latest fetch must survive
+	var wg sync.WaitGroup
+	for i := range 64 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.set("btc", fmt.Sprint(i), base.Add(time.Duration(i)*time.Millisecond))
+		}()
+	}
+	wg.Wait()
+
+	if got, _ := c.get("btc"); got != "63" {
+		t.Errorf("cached value = %q, want the freshest fetch (63)", got)
+	}
+
+	// A late write from an older fetch is dropped
+	c.set("btc", "stale", base.Add(-time.Second))
+	if got, _ := c.get("btc"); got != "63" {
+		t.Errorf("cached value = %q after an older write, want 63 kept", got)
+	}
+}
+
+func TestCacheKeepsCurrenciesApart(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			convert := r.URL.Query().Get("convert")
+			price := map[string]float64{"USD": 60000, "EUR": 55000}[convert]
+			respond(w, http.StatusOK, strings.Replace(cmcQuote("BTC", price), `"USD"`, `"`+convert+`"`, 1))
+		},
+	})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	for range 2 {
+		if response, _ := a.processTask(ctx, session{}, "/price btc"); !strings.Contains(response, "$60,000.00") {
+			t.Errorf("USD lookup:\n%s", response)
+		}
+		if response, _ := a.processTask(ctx, session{}, "/price btc in eur"); !strings.Contains(response, "€55,000.00") {
+			t.Errorf("EUR lookup was served the USD entry:\n%s", response)
+		}
+	}
+	if n := f.count("cmc"); n != 2 {
+		t.Errorf("CMC called %d times, want one call per currency", n)
+	}
+	if key := cacheKey("BTC", "USD", ""); key != cacheKey("btc", "usd", "") || key == cacheKey("btc", "eur", "") {
+		t.Errorf("cacheKey does not separate currencies case-insensitively")
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultCategoryCoins  = 10
+	maxCategoryCoins      = 25
+	maxCategoriesListed   = 50
+	maxCategorySuggestion = 5
+)
+
+// --- CoinGecko Category Structs (For /category and /categories) ---
+type CoinGeckoCategory struct {
+	CategoryID string `json:"category_id"`
+	Name       string `json:"name"`
+}
+
+// getCategoryList fetches every category CoinGecko knows about. On failure it
+// returns a human-readable message alongside the error (which may be nil).
+func (a *PMOAgent) getCategoryList(ctx context.Context) ([]CoinGeckoCategory, string, error) {
+	req, err := a.newCoinGeckoRequest(ctx, "/coins/categories/list")
+	if err != nil {
+		slog.Error("Error creating CG categories request", "err", err)
+		return nil, "Error creating HTTP request.", err
+	}
+
+	var categories []CoinGeckoCategory
+	status, err := a.fetchJSON(req, &categories)
+	if errors.Is(err, errResponseTooLarge) {
+		return nil, "Error: CoinGecko response too large.", err
+	}
+	if status == 0 {
+		return nil, "Error contacting CoinGecko API.", err
+	}
+	if status != http.StatusOK {
+		slog.Warn("CoinGecko categories API returned an error status", "status", status)
+		return nil, fmt.Sprintf("Error: CoinGecko API returned status %d. Could not load categories.", status), nil
+	}
+	if err != nil {
+		return nil, "Error processing CG API response.", err
+	}
+
+	return categories, "", nil
+}
+
+// findCategory matches a query against category IDs and display names,
+// case-insensitively. Spaces in the query are treated as hyphens for IDs.
+func findCategory(categories []CoinGeckoCategory, query string) (CoinGeckoCategory, bool) {
+	id := strings.ToLower(strings.Join(strings.Fields(query), "-"))
+	for _, category := range categories {
+		if category.CategoryID == id || strings.EqualFold(category.Name, query) {
+			return category, true
+		}
+	}
+	return CoinGeckoCategory{}, false
+}
+
+// suggestCategories returns the category IDs closest to query: those that
+// contain it first, then by edit distance, dropping anything too far off.
+func suggestCategories(categories []CoinGeckoCategory, query string) []string {
+	query = strings.ToLower(strings.Join(strings.Fields(query), "-"))
+	maxDistance := len(query)/3 + 1
+
+	type candidate struct {
+		id       string
+		distance int
+	}
+	var candidates []candidate
+	for _, category := range categories {
+		distance := editDistance(query, category.CategoryID)
+		if strings.Contains(category.CategoryID, query) {
+			distance = 0
+		}
+		if distance <= maxDistance {
+			candidates = append(candidates, candidate{category.CategoryID, distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	var suggestions []string
+	for i := 0; i < len(candidates) && i < maxCategorySuggestion; i++ {
+		suggestions = append(suggestions, candidates[i].id)
+	}
+	return suggestions
+}
+
+// editDistance is the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// getCategoryCoins handles `/category <name> [count]`, listing the largest
+// coins in a CoinGecko category by market cap.
+func (a *PMOAgent) getCategoryCoins(ctx context.Context, args []string, _ map[string]string) (string, error) {
+	count := defaultCategoryCoins
+	if len(args) > 1 {
+		if n, err := strconv.Atoi(args[len(args)-1]); err == nil {
+			if n < 1 || n > maxCategoryCoins {
+				return withUsage(fmt.Sprintf("Please provide a count between 1 and %d.", maxCategoryCoins), "/category"), nil
+			}
+			count = n
+			args = args[:len(args)-1]
+		}
+	}
+	query := strings.Join(args, " ")
+
+	categories, message, err := a.getCategoryList(ctx)
+	if categories == nil {
+		return message, err
+	}
+
+	category, ok := findCategory(categories, query)
+	if !ok {
+		response := fmt.Sprintf("Unknown category: %s.", query)
+		if suggestions := suggestCategories(categories, query); len(suggestions) > 0 {
+			response += fmt.Sprintf(" Did you mean: %s?", strings.Join(suggestions, ", "))
+		}
+		return response + " Use /categories to list them.", nil
+	}
+
+	params := url.Values{}
+	params.Set("category", category.CategoryID)
+	params.Set("order", "market_cap_desc")
+	params.Set("per_page", strconv.Itoa(count))
+	params.Set("page", "1")
+	markets, message, err := a.getCoinMarkets(ctx, params)
+	if markets == nil {
+		return message, err
+	}
+	if len(markets) == 0 {
+		return fmt.Sprintf("CoinGecko lists no coins in the %s category.", category.Name), nil
+	}
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString(fmt.Sprintf("🗂️ **Top %d in %s**\n", len(markets), category.Name))
+	for i, market := range markets {
+		line := fmt.Sprintf("%d. **%s** (%s) %s", i+1, market.Name, strings.ToUpper(market.Symbol), formatPrice(market.CurrentPrice, "usd"))
+		if change, ok := market.changeFor("24h"); ok {
+			line += fmt.Sprintf(" (%s)", a.formatSignedChange(change))
+		}
+		if market.MarketCap > 0 {
+			line += fmt.Sprintf(" — MCap %s", formatCurrency(market.MarketCap, "usd"))
+		}
+		responseBuilder.WriteString(line + "\n")
+	}
+	if message != "" {
+		responseBuilder.WriteString("\n" + message + "\n")
+	}
+	responseBuilder.WriteString("\n*(Data provided by COINGECKO)*")
+
+	return responseBuilder.String(), nil
+}
+
+// listCategories handles `/categories [filter]`. CoinGecko has hundreds of
+// categories, so the list is capped and an optional filter narrows it.
+func (a *PMOAgent) listCategories(ctx context.Context, args []string, _ map[string]string) (string, error) {
+	categories, message, err := a.getCategoryList(ctx)
+	if categories == nil {
+		return message, err
+	}
+
+	filter := strings.ToLower(strings.Join(args, " "))
+	var matched []CoinGeckoCategory
+	for _, category := range categories {
+		if filter == "" || strings.Contains(category.CategoryID, filter) || strings.Contains(strings.ToLower(category.Name), filter) {
+			matched = append(matched, category)
+		}
+	}
+	if len(matched) == 0 {
+		return fmt.Sprintf("No categories match %q.", filter), nil
+	}
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString(fmt.Sprintf("🗂️ **CoinGecko Categories** (%d)\n", len(matched)))
+	for i, category := range matched {
+		if i == maxCategoriesListed {
+			responseBuilder.WriteString(fmt.Sprintf("…and %d more. Narrow the list with /categories <filter>.\n", len(matched)-maxCategoriesListed))
+			break
+		}
+		responseBuilder.WriteString(fmt.Sprintf("- `%s` — %s\n", category.CategoryID, category.Name))
+	}
+	responseBuilder.WriteString("\n*(Data provided by COINGECKO)*")
+
+	return responseBuilder.String(), nil
+}
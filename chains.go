@@ -0,0 +1,43 @@
+package main
+
+import "strings"
+
+// --- Chain Table ---
+
+// chainInfo is what each integration calls one chain. An empty ID means the
+// integration doesn't cover the chain.
+type chainInfo struct {
+	display   string // Shown to users
+	coingecko string // CoinGecko asset platform ID
+	goplus    string // EVM chain ID GoPlus expects
+	llama     string // DefiLlama coin prefix, for chains with price history
+}
+
+// chainTable maps Dexscreener chain IDs, which the rest of the agent uses, to
+// each integration's name for the chain. A new chain needs only a row here.
+var chainTable = map[string]chainInfo{
+	"ethereum":  {display: "Ethereum", coingecko: "ethereum", goplus: "1", llama: "ethereum"},
+	"bsc":       {display: "BNB Chain", coingecko: "binance-smart-chain", goplus: "56", llama: "bsc"},
+	"polygon":   {display: "Polygon", coingecko: "polygon-pos", goplus: "137", llama: "polygon"},
+	"arbitrum":  {display: "Arbitrum", coingecko: "arbitrum-one", goplus: "42161", llama: "arbitrum"},
+	"base":      {display: "Base", coingecko: "base", goplus: "8453", llama: "base"},
+	"optimism":  {display: "Optimism", coingecko: "optimistic-ethereum", goplus: "10", llama: "optimism"},
+	"avalanche": {display: "Avalanche", coingecko: "avalanche", goplus: "43114", llama: "avax"},
+	"fantom":    {display: "Fantom", coingecko: "fantom", goplus: "250", llama: "fantom"},
+	"solana":    {display: "Solana"},
+}
+
+// lookupChain returns the table row for a Dexscreener chain ID, ignoring case.
+// Unknown chains get the zero row, so every integration ID is empty.
+func lookupChain(id string) chainInfo {
+	return chainTable[strings.ToLower(id)]
+}
+
+// chainDisplayName returns the chain's display name, falling back to
+// capitalising the ID for chains not in the table.
+func chainDisplayName(id string) string {
+	if info := lookupChain(id); info.display != "" {
+		return info.display
+	}
+	return displayName(nil, id)
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestLookupChain(t *testing.T) {
+	if got := lookupChain("BSC"); got.coingecko != "binance-smart-chain" || got.goplus != "56" || got.llama != "bsc" {
+		t.Errorf("lookupChain(BSC) = %+v, want the bsc row", got)
+	}
+	// Solana is only displayed; none of the EVM integrations cover it
+	if got := lookupChain("solana"); got.coingecko != "" || got.goplus != "" || got.llama != "" {
+		t.Errorf("lookupChain(solana) = %+v, want no integration IDs", got)
+	}
+	if got := lookupChain("newchain"); got != (chainInfo{}) {
+		t.Errorf("lookupChain(newchain) = %+v, want the zero row", got)
+	}
+	for id, info := range chainTable {
+		if info.display == "" {
+			t.Errorf("chain %s has no display name", id)
+		}
+	}
+}
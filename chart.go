@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// --- CoinGecko Market Chart (Historical Series) ---
+
+// CoinGeckoMarketChart is the /coins/{id}/market_chart response. Each point
+// is a [unix_millis, value] pair in chronological order.
+type CoinGeckoMarketChart struct {
+	Prices     [][2]float64 `json:"prices"`
+	MarketCaps [][2]float64 `json:"market_caps"`
+}
+
+// pricePoint is one timestamped price from a market chart series.
+type pricePoint struct {
+	time  time.Time
+	price float64
+}
+
+// points converts the raw price pairs into timestamped points.
+func (c CoinGeckoMarketChart) points() []pricePoint {
+	return seriesPoints(c.Prices)
+}
+
+// marketCapPoints converts the raw market cap pairs into timestamped points,
+// skipping the zero caps CoinGecko reports before a coin's supply was known.
+func (c CoinGeckoMarketChart) marketCapPoints() []pricePoint {
+	points := seriesPoints(c.MarketCaps)
+	known := points[:0]
+	for _, p := range points {
+		if p.price > 0 {
+			known = append(known, p)
+		}
+	}
+	return known
+}
+
+// seriesPoints converts [unix_millis, value] pairs into timestamped points.
+func seriesPoints(series [][2]float64) []pricePoint {
+	points := make([]pricePoint, 0, len(series))
+	for _, p := range series {
+		points = append(points, pricePoint{time: time.UnixMilli(int64(p[0])).UTC(), price: p[1]})
+	}
+	return points
+}
+
+// getMarketChart fetches `days` of daily history for a CoinGecko ID in USD.
+// On failure it returns a human-readable message alongside the error (which may be nil).
+func (a *PMOAgent) getMarketChart(ctx context.Context, coinID string, days int) (*CoinGeckoMarketChart, string, error) {
+	path := fmt.Sprintf("/coins/%s/market_chart?vs_currency=usd&days=%d&interval=daily", coinID, days)
+
+	req, err := a.newCoinGeckoRequest(ctx, path)
+	if err != nil {
+		slog.Error("Error creating CG market chart request", "err", err)
+		return nil, "Error creating HTTP request.", err
+	}
+
+	var chart CoinGeckoMarketChart
+	status, err := a.fetchJSON(req, &chart)
+	if errors.Is(err, errResponseTooLarge) {
+		return nil, "Error: CoinGecko response too large.", err
+	}
+	if status == 0 {
+		return nil, "Error contacting CoinGecko API.", err
+	}
+	if status != http.StatusOK {
+		slog.Warn("CoinGecko market chart API returned an error status", "status", status, "id", coinID)
+		return nil, fmt.Sprintf("Could not find price history for %s on CoinGecko.", coinID), nil
+	}
+	if err != nil {
+		return nil, "Error processing CG API response.", err
+	}
+	if len(chart.Prices) == 0 {
+		return nil, fmt.Sprintf("CoinGecko returned no price history for %s.", coinID), nil
+	}
+
+	return &chart, "", nil
+}
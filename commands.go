@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// --- Command Dispatcher ---
+
+// command is one entry of the dispatcher. usage is shown whenever the command
+// is called with missing or malformed arguments. Commands that keep per-room
+// state (such as /watch) set runInSession instead of run.
+type command struct {
+	usage        string
+	minArgs      int
+	run          func(a *PMOAgent, ctx context.Context, args []string, flags map[string]string) (string, error)
+	runInSession func(a *PMOAgent, ctx context.Context, s session, args []string, flags map[string]string) (string, error)
+}
+
+// commands maps every supported command to its handler. It is populated in
+// init because handlers refer back to it for usage strings.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"/price": {
+			usage:   "/price <symbol|address|cmc:id> [more symbols...] [in <fiat>] [--source=<provider>] [--fresh] [--debug] [--table] [--minliq=<usd>] [--chain=<dex chain>] [--quote] [--raw] [--details (adds the low-trust volume flag)] [--format=<markdown|csv|json>] [--compact|--full] [--vs=<usdt|usdc|dai>] [--scaled] [--nosource] [--logo]",
+			minArgs: 1,
+			run:     (*PMOAgent).priceCommand,
+		},
+		"/market": {
+			usage:   "/market <symbol|address|cmc:id> [more symbols...] [in <fiat>] [--source=<provider>] [--fresh] [--debug] [--table] [--minliq=<usd>] [--chain=<dex chain>] [--quote] [--raw] [--format=<markdown|csv|json>] [--compact|--full] [--vs=<usdt|usdc|dai>] [--scaled] [--nosource] [--logo]",
+			minArgs: 1,
+			run:     (*PMOAgent).marketCommand,
+		},
+		"/convert": {
+			usage:   "/convert <amount> <from> [to] [--inverse] [--fees]",
+			minArgs: 2,
+			run:     (*PMOAgent).convertAmount,
+		},
+		"/fiats": {
+			usage:   "/fiats <symbol>",
+			minArgs: 1,
+			run:     (*PMOAgent).getFiatPrices,
+		},
+		"/info": {
+			usage:   "/info <symbol>",
+			minArgs: 1,
+			run:     (*PMOAgent).getTokenInfo,
+		},
+		"/perf": {
+			usage:   "/perf <symbol> <symbol> [...] [over <1h|24h|7d|14d|30d|200d|1y>]",
+			minArgs: 2,
+			run:     (*PMOAgent).getPerformance,
+		},
+		"/compare": {
+			usage:   "/compare <symbol> <symbol> [...] [in <fiat>]",
+			minArgs: 2,
+			run:     (*PMOAgent).compareTokens,
+		},
+		"/diffpct": {
+			usage:   "/diffpct <symbol> <symbol>",
+			minArgs: 2,
+			run:     (*PMOAgent).getDiffPct,
+		},
+		"/ema": {
+			usage:   "/ema <symbol> <period>",
+			minArgs: 2,
+			run:     (*PMOAgent).getEMA,
+		},
+		"/history": {
+			usage:   "/history <symbol> <days> [--mcap] [--csv]",
+			minArgs: 2,
+			run:     (*PMOAgent).getHistory,
+		},
+		"/priceat": {
+			usage:   "/priceat <address> <block> [chain]",
+			minArgs: 2,
+			run:     (*PMOAgent).getPriceAtBlock,
+		},
+		"/dca": {
+			usage:   "/dca <symbol> <usd amount> <daily|weekly|biweekly|monthly> <periods>",
+			minArgs: 4,
+			run:     (*PMOAgent).getDCA,
+		},
+		"/portfolio": {
+			usage:   "/portfolio <symbol:amount>... | /portfolio total=<usd> <symbol:percent%>...",
+			minArgs: 1,
+			run:     (*PMOAgent).getPortfolio,
+		},
+		"/exchanges": {
+			usage: "/exchanges [count]",
+			run:   (*PMOAgent).getTopExchanges,
+		},
+		"/category": {
+			usage:   "/category <category-id|name> [count]",
+			minArgs: 1,
+			run:     (*PMOAgent).getCategoryCoins,
+		},
+		"/collisions": {
+			usage:   "/collisions <symbol>",
+			minArgs: 1,
+			run:     (*PMOAgent).getSymbolCollisions,
+		},
+		"/tvl": {
+			usage:   "/tvl <protocol>",
+			minArgs: 1,
+			run:     (*PMOAgent).getProtocolTVL,
+		},
+		"/fear": {
+			usage: "/fear [days]",
+			run:   (*PMOAgent).getFearIndex,
+		},
+		"/categories": {
+			usage: "/categories [filter]",
+			run:   (*PMOAgent).listCategories,
+		},
+		"/testalert": {
+			usage: "/testalert",
+			run:   (*PMOAgent).sendTestAlert,
+		},
+		"/stats": {
+			usage: "/stats",
+			run:   (*PMOAgent).getStats,
+		},
+		"/status": {
+			usage: "/status",
+			run:   (*PMOAgent).getStatus,
+		},
+		"/watch": {
+			usage:        "/watch <symbol> <above|below> <usd price> | /watch <symbol> volume <multiplier> | /watch clear | /watch",
+			runInSession: (*PMOAgent).watchCommand,
+		},
+		"/unwatch": {
+			usage:        "/unwatch <symbol>",
+			minArgs:      1,
+			runInSession: (*PMOAgent).unwatchCommand,
+		},
+	}
+}
+
+// commandAliases maps alternative command names onto the command they run.
+// An alias behaves exactly like its command, including its usage line, and
+// is listed in help after commandList; commands never register aliases of
+// their own.
+var commandAliases = map[string]string{
+	"/tokeninfo":         "/info",
+	"/symbol-collisions": "/collisions",
+	"/fearhistory":       "/fear",
+	"/uptime":            "/status",
+}
+
+// commandList is the user-facing list of commands, in help order.
+const commandList = "/price, /market, /convert, /fiats, /info, /perf, /compare, /diffpct, /ema, /history, /priceat, /dca, /portfolio, /exchanges, /category, /categories, /collisions, /tvl, /fear, /watch, /unwatch, /testalert, /stats, /status or /batch"
+
+// commandHelp is commandList followed by every alias and its command.
+func commandHelp() string {
+	aliases := make([]string, 0, len(commandAliases))
+	for alias, name := range commandAliases {
+		aliases = append(aliases, fmt.Sprintf("%s for %s", alias, name))
+	}
+	slices.Sort(aliases)
+	return fmt.Sprintf("%s (aliases: %s)", commandList, strings.Join(aliases, ", "))
+}
+
+// resolveCommand returns the command name input refers to, following aliases.
+func resolveCommand(input string) string {
+	name := strings.ToLower(input)
+	if target, ok := commandAliases[name]; ok {
+		return target
+	}
+	return name
+}
+
+// usageFor returns the "Usage: ..." line for a command.
+func usageFor(name string) string {
+	return "Usage: " + commands[name].usage
+}
+
+// withUsage appends the command's usage line to an argument error message.
+func withUsage(message, name string) string {
+	return fmt.Sprintf("%s\n%s", message, usageFor(name))
+}
+
+// dispatch routes tokenized input to its command handler, enforcing the
+// minimum argument count before the handler runs.
+func (a *PMOAgent) dispatch(ctx context.Context, s session, parts []string) (string, error) {
+	if len(parts) == 0 {
+		return fmt.Sprintf("Please specify a command (%s) and a token symbol or contract address.", commandHelp()), nil
+	}
+
+	name := resolveCommand(parts[0])
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Sprintf("Unknown command: %s. Use %s.", strings.ToLower(parts[0]), commandHelp()), nil
+	}
+
+	args, flags := parseFlags(parts[1:])
+	if len(args) < cmd.minArgs {
+		return usageFor(name), nil
+	}
+
+	if cmd.runInSession != nil {
+		return cmd.runInSession(a, ctx, s, args, flags)
+	}
+	return cmd.run(a, ctx, args, flags)
+}
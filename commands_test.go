@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMalformedCommandsShowUsage(t *testing.T) {
+	a := newTestAgent(t, nil)
+	convertUsage := "Usage: /convert <amount> <from> [to] [--inverse] [--fees]"
+	historyUsage := "Usage: /history <symbol> <days> [--mcap] [--csv]"
+
+	tests := []struct {
+		input, want string
+	}{
+		{"/convert", convertUsage},
+		{"/convert 5", convertUsage},
+		{"/convert abc eth usd", convertUsage},
+		{"/history", historyUsage},
+		{"/history btc", historyUsage},
+		{"/history btc 0", historyUsage},
+	}
+	for _, tt := range tests {
+		response, err := a.processTask(context.Background(), session{}, tt.input)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.input, err)
+		}
+		if !strings.Contains(response, tt.want) {
+			t.Errorf("%s: response = %q, want %q", tt.input, response, tt.want)
+		}
+	}
+}
+
+func TestCommandAliases(t *testing.T) {
+	for alias, name := range commandAliases {
+		if _, ok := commands[name]; !ok {
+			t.Errorf("alias %s points at unknown command %s", alias, name)
+		}
+		if _, ok := commands[alias]; ok {
+			t.Errorf("alias %s is also registered as a command", alias)
+		}
+	}
+
+	a := newTestAgent(t, nil)
+	ctx := context.Background()
+	if response, _ := a.processTask(ctx, session{}, "/TokenInfo"); response != usageFor("/info") {
+		t.Errorf("/TokenInfo response = %q, want the /info usage", response)
+	}
+	response, _ := a.processTask(ctx, session{}, "/nope")
+	if !strings.HasPrefix(response, "Unknown command: /nope.") || !strings.Contains(response, "/uptime for /status") {
+		t.Errorf("unknown command response = %q, want the aliases listed", response)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// --- Multi-Token Comparison (/compare) ---
+
+const (
+	minCompareTokens = 2
+	maxCompareTokens = 5
+	// maxCompareLookups bounds how many lookups /compare runs at once
+	maxCompareLookups = 3
+	// bestMarker flags the best cell of a row; emoji would break the alignment
+	bestMarker = " *"
+)
+
+// compareColumn is one token's figures in a /compare table. has* report
+// whether the provider supplied that figure.
+type compareColumn struct {
+	label     string
+	source    string
+	price     string
+	changeRaw string
+	change    float64
+	marketCap float64
+	rank      int
+	currency  string
+	found     bool
+
+	hasChange, hasMarketCap, hasRank bool
+}
+
+// newCompareColumn extracts the comparable figures from a lookup result.
+func newCompareColumn(result tokenResult) compareColumn {
+	parts := parseRawOutput(result.raw)
+	column := compareColumn{label: tableSymbol(result, parts), found: result.found}
+	if !result.found {
+		return column
+	}
+
+	column.source = strings.ToUpper(parts["token_source"])
+	column.currency = responseCurrency(result.raw)
+	column.price = parts["current_price_"+column.currency]
+	column.changeRaw = parts["24h_change"]
+	column.change, column.hasChange = parseDisplayNumber(column.changeRaw)
+	column.marketCap, column.hasMarketCap = parseDisplayNumber(parts["market_cap_"+column.currency])
+	column.hasMarketCap = column.hasMarketCap && column.marketCap > 0
+	if rank, err := strconv.Atoi(parts["rank"]); err == nil && rank > 0 {
+		column.rank, column.hasRank = rank, true
+	}
+	return column
+}
+
+// bestColumn returns the index of the best column under better, among those
+// for which has reports a value, or -1 when fewer than two columns qualify.
+func bestColumn(columns []compareColumn, has func(compareColumn) bool, better func(a, b compareColumn) bool) int {
+	best, candidates := -1, 0
+	for i, column := range columns {
+		if !has(column) {
+			continue
+		}
+		candidates++
+		if best < 0 || better(column, columns[best]) {
+			best = i
+		}
+	}
+	if candidates < 2 {
+		return -1
+	}
+	return best
+}
+
+// compareTokens handles `/compare <symbol> <symbol> [...] [in <fiat>]`,
+// laying out price, 24h change, market cap and rank side by side. Lookups
+// run concurrently but at most maxCompareLookups at a time, so a wide
+// comparison doesn't burst the upstream rate limits.
+func (a *PMOAgent) compareTokens(ctx context.Context, args []string, flags map[string]string) (string, error) {
+	args = extractCurrencyShorthand(trimTargets(args), flags)
+	if len(args) < minCompareTokens {
+		return withUsage("Please provide at least two tokens to compare.", "/compare"), nil
+	}
+	if len(args) > maxCompareTokens {
+		return withUsage(fmt.Sprintf("Please compare at most %d tokens at a time.", maxCompareTokens), "/compare"), nil
+	}
+
+	results := make([]tokenResult, len(args))
+	slots := make(chan struct{}, maxCompareLookups)
+	var wg sync.WaitGroup
+	for i, target := range args {
+		wg.Add(1)
+		safeGo("compare lookup for "+target, func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			// A panicking lookup leaves the target reported as not found
+			results[i] = tokenResult{target: target}
+			results[i] = a.lookupToken(ctx, target, flags)
+		})
+	}
+	wg.Wait()
+
+	columns := make([]compareColumn, len(results))
+	var sources []string
+	for i, result := range results {
+		if !result.found && result.err != nil {
+			slog.Error("Compare lookup failed", "target", result.target, "err", result.err)
+		}
+		columns[i] = newCompareColumn(result)
+		if result.found && !slices.Contains(sources, columns[i].source) {
+			sources = append(sources, columns[i].source)
+		}
+	}
+	if len(sources) == 0 {
+		return "None of those tokens could be found. Please check the symbols.", nil
+	}
+
+	bestChange := bestColumn(columns, func(c compareColumn) bool { return c.hasChange },
+		func(a, b compareColumn) bool { return a.change > b.change })
+	bestMarketCap := bestColumn(columns, func(c compareColumn) bool { return c.hasMarketCap },
+		func(a, b compareColumn) bool { return a.marketCap > b.marketCap })
+	bestRank := bestColumn(columns, func(c compareColumn) bool { return c.hasRank },
+		func(a, b compareColumn) bool { return a.rank < b.rank })
+
+	header := []string{""}
+	rows := [][]string{{"Price"}, {"24h"}, {"Mkt Cap"}, {"Rank"}}
+	rightAligned := []bool{false}
+	for i, column := range columns {
+		header = append(header, column.label)
+		rightAligned = append(rightAligned, true)
+
+		cells := []string{"n/a", "n/a", "n/a", "n/a"}
+		if column.price != "" {
+			cells[0] = column.price
+		}
+		if column.hasChange {
+			cells[1] = tableChange(column.changeRaw)
+		}
+		if column.hasMarketCap {
+			cells[2] = formatLargeCurrency(column.marketCap, column.currency)
+		}
+		if column.hasRank {
+			cells[3] = fmt.Sprintf("#%d", column.rank)
+		}
+		for row, best := range []int{-1, bestChange, bestMarketCap, bestRank} {
+			if row > 0 && best == i {
+				cells[row] += bestMarker
+			}
+			rows[row] = append(rows[row], cells[row])
+		}
+	}
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString("⚖️ **Token Comparison**\n")
+	responseBuilder.WriteString(formatGrid(header, rows, rightAligned))
+	responseBuilder.WriteString("\n`*` marks the best value in each row\n")
+	responseBuilder.WriteString(fmt.Sprintf("\n*(Data provided by %s)*", strings.Join(sources, ", ")))
+	return responseBuilder.String(), nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// compareQuote is a CMC quote with its own rank and 24h change.
+func compareQuote(symbol string, price, change float64, rank int) string {
+	return fmt.Sprintf(`{"status":{"error_code":0},"data":{%q:{"id":%d,"name":%q,"symbol":%q,"cmc_rank":%d,
+		"quote":{"USD":{"price":%v,"volume_24h":1000000,"market_cap":%v,"percent_change_24h":%v}}}}}`,
+		symbol, rank, symbol, symbol, rank, price, price*1e8, change)
+}
+
+func TestCompareThreeTokens(t *testing.T) {
+	quotes := map[string]string{
+		"BTC": compareQuote("BTC", 600, 1.5, 1),
+		"ETH": compareQuote("ETH", 30, 4.2, 2),
+		"SOL": compareQuote("SOL", 1.5, -3.1, 5),
+	}
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			respond(w, http.StatusOK, quotes[r.URL.Query().Get("symbol")])
+		},
+	})
+	a := newTestAgent(t, f.env())
+
+	response, err := a.processTask(context.Background(), session{}, "/compare btc eth sol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "        |       BTC |      ETH |      SOL\n" +
+		"--------+-----------+----------+---------\n" +
+		"Price   |   $600.00 |   $30.00 |    $1.50\n" +
+		"24h     |    +1.50% | +4.20% * |   -3.10%\n" +
+		"Mkt Cap | $60.00B * |   $3.00B | $150.00M\n" +
+		"Rank    |      #1 * |       #2 |       #5\n"
+	if !strings.Contains(response, want) {
+		t.Errorf("comparison table is not\n%s\ngot:\n%s", want, response)
+	}
+}
+
+func TestCompareMarksUnresolvedToken(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			switch symbol := r.URL.Query().Get("symbol"); symbol {
+			case "BTC", "ETH":
+				respond(w, http.StatusOK, cmcQuote(symbol, 100))
+			default:
+				respond(w, http.StatusOK, `{"status":{"error_code":0},"data":{}}`)
+			}
+		},
+	})
+	env := f.env()
+	env["RETRY_BUDGET_PER_MINUTE"] = "0"
+	a := newTestAgent(t, env)
+
+	response, _ := a.processTask(context.Background(), session{}, "/compare btc eth nosuchcoin")
+	var row string
+	for _, line := range strings.Split(response, "\n") {
+		if strings.HasPrefix(line, "Price") {
+			row = line
+		}
+	}
+	if cells := strings.Split(row, "|"); len(cells) != 4 || strings.TrimSpace(cells[3]) != "n/a" {
+		t.Errorf("price row = %q, want n/a for the unresolved token:\n%s", row, response)
+	}
+
+	if response, _ := a.processTask(context.Background(), session{}, "/compare btc"); !strings.HasPrefix(response, "Usage: /compare") {
+		t.Errorf("/compare with one token = %q, want the usage", response)
+	}
+}
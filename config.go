@@ -0,0 +1,504 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// --- Application Configuration ---
+
+// AppConfig holds every setting read from the environment (.env file or real
+// env vars). It is loaded and validated once at startup by LoadConfig and then
+// passed to the agent, so nothing else needs to call os.Getenv.
+type AppConfig struct {
+	// Provider credentials
+	CMCAPIKey       string
+	CoinGeckoAPIKey string
+
+	// Provider base URLs (overridable for caching proxies or mock servers)
+	CMCBaseURL       string
+	CoinGeckoBaseURL string
+	// CoinGeckoProBaseURL is used instead once the API key is detected as a Pro key
+	CoinGeckoProBaseURL string
+	DexscreenerBaseURL  string
+	BinanceBaseURL      string
+	GoPlusBaseURL       string
+	// DefiLlamaBaseURL serves protocol TVL for /tvl
+	DefiLlamaBaseURL string
+	// DefiLlamaCoinsBaseURL serves historical token prices for /priceat
+	DefiLlamaCoinsBaseURL string
+	// EVMRPCURLs maps Dexscreener chain IDs to JSON-RPC nodes used to
+	// resolve block numbers to timestamps (chains without one can't use
+	// /priceat) and to price gas for /convert --fees
+	EVMRPCURLs map[string]string
+	// FearGreedBaseURL serves the Crypto Fear & Greed Index for /fear
+	FearGreedBaseURL string
+
+	// HTTP behaviour
+	HTTPTimeout               time.Duration
+	MaxResponseBytes          int64
+	DefiLlamaMaxResponseBytes int64 // replaces MaxResponseBytes for DefiLlama, whose /protocols list and /protocol/{slug} histories run to megabytes
+	RetryBudgetPerMinute      int
+	FollowRedirects           bool
+	UserAgent                 string // sent on every outbound request
+
+	// SessionRequestsPerMinute caps requests from one room, direct caller or
+	// HTTP API client (0 disables)
+	SessionRequestsPerMinute int
+
+	// ProviderOrder is the CEX failover order, using canonical provider names
+	ProviderOrder []string
+
+	// CrossCheckPrices queries a second CEX provider to detect ticker
+	// collisions (on by default). It only compares directly resolvable IDs,
+	// never search matches, so it costs at most one extra upstream request
+	// per uncached symbol lookup; PRICE_CROSS_CHECK=false saves it
+	CrossCheckPrices bool
+
+	// DefaultFiat is the quote currency for /price, /market and /convert when a
+	// request doesn't name one (lower-case, e.g. "eur")
+	DefaultFiat string
+
+	// MinLiquidityUSD skips DEX pools shallower than this (0 disables)
+	MinLiquidityUSD float64
+
+	// TrustedQuoteTokens ranks DEX pairs by quote token symbol, most authoritative first
+	TrustedQuoteTokens []string
+
+	// CacheTTL is how long successful lookups are reused (0 disables caching)
+	CacheTTL time.Duration
+
+	// ServeStaleOnError serves an expired cache entry when every provider fails
+	ServeStaleOnError bool
+
+	// CacheMaxStale is how old an expired entry may get and still be served
+	// (0 never serves expired entries); older entries are evicted
+	CacheMaxStale time.Duration
+
+	// StaleThreshold is the data age after which a staleness warning is shown (0 disables)
+	StaleThreshold time.Duration
+
+	// LogLevel is the minimum level logged; per-request tracing is debug
+	LogLevel slog.Level
+
+	// HealthSummaryInterval is how often provider error rates are logged (0 disables)
+	HealthSummaryInterval time.Duration
+
+	// HTTPAPIPort enables the REST API on that port (0 disables)
+	HTTPAPIPort int
+
+	// WatchInterval is how often /watch alerts are checked (0 disables checking)
+	WatchInterval time.Duration
+
+	// Alert delivery. Every configured notifier is used; with none, alerts are logged
+	AlertWebhookURL        string // generic JSON webhook ({"text": ...})
+	AlertDiscordWebhookURL string
+	SMTPHost               string
+	SMTPPort               int
+	SMTPUsername           string
+	SMTPPassword           string
+	AlertEmailFrom         string
+	AlertEmailTo           []string
+
+	// MarketTemplate overrides the market overview layout (nil uses the built-in one)
+	MarketTemplate *template.Template
+
+	// CompactNumbers abbreviates market cap, volume, liquidity and FDV ($1.23T)
+	CompactNumbers bool
+
+	// ChangeDecimals is the number of decimals shown for 24h changes
+	ChangeDecimals int
+
+	// ConvertSignificantDigits is how many significant digits /convert keeps
+	// for crypto results, so tiny amounts aren't padded with noise digits
+	ConvertSignificantDigits int
+
+	// OutputFormat is the default response formatter name, overridable with --format
+	OutputFormat string
+
+	// HideSource drops the "Data provided by" footer from /price and /market
+	// output (formatOutput and the --table view), as --nosource does per
+	// request. Other commands keep their own footers
+	HideSource bool
+
+	// Teneo agent identity
+	PrivateKey   string
+	NFTTokenID   string
+	OwnerAddress string
+}
+
+const (
+	defaultHTTPTimeout      = 15 * time.Second
+	defaultMaxResponseBytes = int64(2 << 20)  // 2MB
+	defaultDefiLlamaBytes   = int64(64 << 20) // 64MB
+	defaultStaleMinutes     = 10
+	defaultCacheTTLSeconds  = 60
+	defaultMaxStaleMinutes  = 60
+	defaultHealthMinutes    = 5
+	defaultSMTPPort         = 587
+	defaultWatchSeconds     = 60
+	defaultChangeDecimals   = 2
+	maxChangeDecimals       = 8
+	defaultConvertDigits    = 6
+	maxConvertDigits        = 15
+)
+
+// defaultTrustedQuoteTokens are preferred, in order, when picking a DEX pair.
+var defaultTrustedQuoteTokens = []string{"USDC", "USDT", "DAI", "WETH"}
+
+// defaultProviderOrder is the historical CMC -> CoinGecko chain. Binance is
+// only queried when PROVIDER_ORDER includes it or a request forces it with
+// --source=binance.
+var defaultProviderOrder = []string{"cmc", "coingecko"}
+
+// LoadConfig reads and validates all supported environment variables,
+// applying the same defaults the agent has always used when they are unset.
+func LoadConfig() (*AppConfig, error) {
+	cfg := &AppConfig{
+		CMCAPIKey:       os.Getenv("CMC_API_KEY"),
+		CoinGeckoAPIKey: os.Getenv("COINGECKO_API_KEY"),
+		PrivateKey:      os.Getenv("PRIVATE_KEY"),
+		NFTTokenID:      os.Getenv("NFT_TOKEN_ID"),
+		OwnerAddress:    os.Getenv("OWNER_ADDRESS"),
+	}
+
+	var err error
+
+	// 1. Base URLs
+	baseURLs := []struct {
+		envVar   string
+		fallback string
+		target   *string
+	}{
+		{"CMC_BASE_URL", "https://pro-api.coinmarketcap.com", &cfg.CMCBaseURL},
+		{"COINGECKO_BASE_URL", "https://api.coingecko.com/api/v3", &cfg.CoinGeckoBaseURL},
+		{"COINGECKO_PRO_BASE_URL", "https://pro-api.coingecko.com/api/v3", &cfg.CoinGeckoProBaseURL},
+		{"DEXSCREENER_BASE_URL", "https://api.dexscreener.com", &cfg.DexscreenerBaseURL},
+		{"BINANCE_BASE_URL", "https://api.binance.com", &cfg.BinanceBaseURL},
+		{"GOPLUS_BASE_URL", "https://api.gopluslabs.io", &cfg.GoPlusBaseURL},
+		{"DEFILLAMA_BASE_URL", "https://api.llama.fi", &cfg.DefiLlamaBaseURL},
+		{"DEFILLAMA_COINS_BASE_URL", "https://coins.llama.fi", &cfg.DefiLlamaCoinsBaseURL},
+		{"FEAR_GREED_BASE_URL", "https://api.alternative.me", &cfg.FearGreedBaseURL},
+	}
+	for _, b := range baseURLs {
+		if *b.target, err = envBaseURL(b.envVar, b.fallback); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.EVMRPCURLs, err = envRPCURLs("EVM_RPC_URLS"); err != nil {
+		return nil, err
+	}
+
+	// 2. Default currency
+	cfg.DefaultFiat = strings.ToLower(strings.TrimSpace(os.Getenv("DEFAULT_FIAT")))
+	if cfg.DefaultFiat == "" {
+		cfg.DefaultFiat = "usd"
+	} else if !isFiat(cfg.DefaultFiat) {
+		return nil, fmt.Errorf("DEFAULT_FIAT must be a supported fiat currency, got %q", cfg.DefaultFiat)
+	}
+
+	// 3. HTTP behaviour
+	timeoutSeconds, err := envInt("HTTP_TIMEOUT_SECONDS", int(defaultHTTPTimeout/time.Second), 1)
+	if err != nil {
+		return nil, err
+	}
+	cfg.HTTPTimeout = time.Duration(timeoutSeconds) * time.Second
+
+	maxBytes, err := envInt("MAX_RESPONSE_BYTES", int(defaultMaxResponseBytes), 1)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MaxResponseBytes = int64(maxBytes)
+
+	defiLlamaBytes, err := envInt("DEFILLAMA_MAX_RESPONSE_BYTES", int(defaultDefiLlamaBytes), 1)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DefiLlamaMaxResponseBytes = int64(defiLlamaBytes)
+
+	if cfg.RetryBudgetPerMinute, err = envInt("RETRY_BUDGET_PER_MINUTE", defaultRetriesPerMinute, 0); err != nil {
+		return nil, err
+	}
+
+	if cfg.SessionRequestsPerMinute, err = envInt("SESSION_REQUESTS_PER_MINUTE", defaultSessionRequestsPerMinute, 0); err != nil {
+		return nil, err
+	}
+
+	if cfg.FollowRedirects, err = envBool("FOLLOW_REDIRECTS", false); err != nil {
+		return nil, err
+	}
+
+	if cfg.UserAgent = strings.TrimSpace(os.Getenv("USER_AGENT")); cfg.UserAgent == "" {
+		cfg.UserAgent = "teneo-crypto-agent/" + version
+	}
+
+	// 4. Provider order
+	if cfg.ProviderOrder, err = envProviderOrder("PROVIDER_ORDER"); err != nil {
+		return nil, err
+	}
+
+	if cfg.CrossCheckPrices, err = envBool("PRICE_CROSS_CHECK", true); err != nil {
+		return nil, err
+	}
+
+	minLiquidity, err := envInt("MIN_LIQUIDITY_USD", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MinLiquidityUSD = float64(minLiquidity)
+
+	cfg.TrustedQuoteTokens = append([]string(nil), defaultTrustedQuoteTokens...)
+	if raw := strings.TrimSpace(os.Getenv("TRUSTED_QUOTE_TOKENS")); raw != "" {
+		cfg.TrustedQuoteTokens = nil
+		for _, symbol := range strings.Split(raw, ",") {
+			if symbol = strings.ToUpper(strings.TrimSpace(symbol)); symbol != "" {
+				cfg.TrustedQuoteTokens = append(cfg.TrustedQuoteTokens, symbol)
+			}
+		}
+	}
+
+	// 5. Caching and data freshness
+	cacheSeconds, err := envInt("CACHE_TTL_SECONDS", defaultCacheTTLSeconds, 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.CacheTTL = time.Duration(cacheSeconds) * time.Second
+
+	if cfg.ServeStaleOnError, err = envBool("SERVE_STALE_ON_ERROR", false); err != nil {
+		return nil, err
+	}
+
+	maxStaleMinutes, err := envInt("CACHE_MAX_STALE_MINUTES", defaultMaxStaleMinutes, 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.CacheMaxStale = time.Duration(maxStaleMinutes) * time.Minute
+
+	staleMinutes, err := envInt("STALE_THRESHOLD_MINUTES", defaultStaleMinutes, 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.StaleThreshold = time.Duration(staleMinutes) * time.Minute
+
+	// 6. Operational logging and the HTTP API
+	if cfg.LogLevel, err = envLogLevel("LOG_LEVEL", slog.LevelInfo); err != nil {
+		return nil, err
+	}
+
+	healthMinutes, err := envInt("HEALTH_SUMMARY_MINUTES", defaultHealthMinutes, 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.HealthSummaryInterval = time.Duration(healthMinutes) * time.Minute
+
+	if cfg.HTTPAPIPort, err = envInt("HTTP_API_PORT", 0, 0); err != nil {
+		return nil, err
+	}
+	if cfg.HTTPAPIPort > 65535 {
+		return nil, fmt.Errorf("HTTP_API_PORT must be a valid TCP port, got %d", cfg.HTTPAPIPort)
+	}
+
+	// 7. Alerts and their delivery
+	watchSeconds, err := envInt("WATCH_INTERVAL_SECONDS", defaultWatchSeconds, 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.WatchInterval = time.Duration(watchSeconds) * time.Second
+
+	if cfg.AlertWebhookURL, err = envURL("ALERT_WEBHOOK_URL"); err != nil {
+		return nil, err
+	}
+	if cfg.AlertDiscordWebhookURL, err = envURL("ALERT_DISCORD_WEBHOOK_URL"); err != nil {
+		return nil, err
+	}
+
+	cfg.SMTPHost = strings.TrimSpace(os.Getenv("SMTP_HOST"))
+	cfg.SMTPUsername = os.Getenv("SMTP_USERNAME")
+	cfg.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	cfg.AlertEmailFrom = strings.TrimSpace(os.Getenv("ALERT_EMAIL_FROM"))
+	for _, recipient := range strings.Split(os.Getenv("ALERT_EMAIL_TO"), ",") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			cfg.AlertEmailTo = append(cfg.AlertEmailTo, recipient)
+		}
+	}
+	if cfg.SMTPPort, err = envInt("SMTP_PORT", defaultSMTPPort, 1); err != nil {
+		return nil, err
+	}
+	if cfg.SMTPHost != "" && (cfg.AlertEmailFrom == "" || len(cfg.AlertEmailTo) == 0) {
+		return nil, fmt.Errorf("SMTP_HOST requires ALERT_EMAIL_FROM and ALERT_EMAIL_TO")
+	}
+
+	// 8. Presentation
+	if path := strings.TrimSpace(os.Getenv("TEMPLATE_FILE")); path != "" {
+		if cfg.MarketTemplate, err = loadMarketTemplate(path); err != nil {
+			return nil, fmt.Errorf("TEMPLATE_FILE: %w", err)
+		}
+	}
+
+	if cfg.CompactNumbers, err = envBool("COMPACT_NUMBERS", false); err != nil {
+		return nil, err
+	}
+
+	if cfg.ChangeDecimals, err = envInt("CHANGE_DECIMALS", defaultChangeDecimals, 0); err != nil {
+		return nil, err
+	}
+	if cfg.ChangeDecimals > maxChangeDecimals {
+		return nil, fmt.Errorf("CHANGE_DECIMALS must be at most %d, got %d", maxChangeDecimals, cfg.ChangeDecimals)
+	}
+
+	if cfg.ConvertSignificantDigits, err = envInt("CONVERT_SIGNIFICANT_DIGITS", defaultConvertDigits, 1); err != nil {
+		return nil, err
+	}
+	if cfg.ConvertSignificantDigits > maxConvertDigits {
+		return nil, fmt.Errorf("CONVERT_SIGNIFICANT_DIGITS must be at most %d, got %d", maxConvertDigits, cfg.ConvertSignificantDigits)
+	}
+
+	if cfg.HideSource, err = envBool("HIDE_SOURCE", false); err != nil {
+		return nil, err
+	}
+
+	cfg.OutputFormat = strings.ToLower(strings.TrimSpace(os.Getenv("OUTPUT_FORMAT")))
+	if cfg.OutputFormat == "" {
+		cfg.OutputFormat = defaultFormat
+	} else if _, ok := formatters[cfg.OutputFormat]; !ok {
+		return nil, fmt.Errorf("OUTPUT_FORMAT must be %s, got %q", formatNames(), cfg.OutputFormat)
+	}
+
+	return cfg, nil
+}
+
+// loadDotEnv loads path into the environment. A missing file is fine (in
+// production variables are usually set directly) and only logged, but a file
+// that exists and can't be parsed is an error rather than a silent half-load.
+func loadDotEnv(path string) error {
+	err := godotenv.Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("No dotenv file found, using the process environment only", "path", path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// envBaseURL returns the override for envVar if set, validated as an absolute
+// http(s) URL without a trailing slash, or fallback otherwise.
+func envBaseURL(envVar, fallback string) (string, error) {
+	raw, err := envURL(envVar)
+	if err != nil || raw == "" {
+		return fallback, err
+	}
+	return strings.TrimRight(raw, "/"), nil
+}
+
+// envURL returns envVar validated as an absolute http(s) URL, or "" if unset.
+func envURL(envVar string) (string, error) {
+	raw := strings.TrimSpace(os.Getenv(envVar))
+	if raw == "" {
+		return "", nil
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("%s must be an absolute http(s) URL, got %q", envVar, raw)
+	}
+	return raw, nil
+}
+
+// envRPCURLs parses an optional "chain=url,chain=url" list, validating each
+// URL like envURL. Chain IDs are lower-cased.
+func envRPCURLs(envVar string) (map[string]string, error) {
+	urls := map[string]string{}
+	raw := strings.TrimSpace(os.Getenv(envVar))
+	if raw == "" {
+		return urls, nil
+	}
+	for _, entry := range strings.Split(raw, ",") {
+		chain, rpcURL, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		chain = strings.ToLower(strings.TrimSpace(chain))
+		rpcURL = strings.TrimSpace(rpcURL)
+		parsed, err := url.Parse(rpcURL)
+		if !ok || chain == "" || err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("%s must be a comma-separated list of chain=http(s) URL pairs, got %q", envVar, entry)
+		}
+		urls[chain] = rpcURL
+	}
+	return urls, nil
+}
+
+// envInt parses an optional integer env var no smaller than min.
+func envInt(envVar string, fallback, min int) (int, error) {
+	raw := strings.TrimSpace(os.Getenv(envVar))
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < min {
+		return 0, fmt.Errorf("%s must be an integer >= %d, got %q", envVar, min, raw)
+	}
+	return value, nil
+}
+
+// envBool parses an optional boolean env var (true/false, 1/0, yes/no).
+func envBool(envVar string, fallback bool) (bool, error) {
+	raw := strings.ToLower(strings.TrimSpace(os.Getenv(envVar)))
+	switch raw {
+	case "":
+		return fallback, nil
+	case "1", "true", "yes", "on":
+		return true, nil
+	case "0", "false", "no", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("%s must be true or false, got %q", envVar, raw)
+}
+
+// envLogLevel parses one of debug, info, warn or error (any case).
+func envLogLevel(envVar string, fallback slog.Level) (slog.Level, error) {
+	switch raw := strings.ToLower(strings.TrimSpace(os.Getenv(envVar))); raw {
+	case "":
+		return fallback, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return fallback, fmt.Errorf("%s must be debug, info, warn or error, got %q", envVar, raw)
+	}
+}
+
+// envProviderOrder parses a comma-separated list of CEX provider names.
+func envProviderOrder(envVar string) ([]string, error) {
+	raw := strings.TrimSpace(os.Getenv(envVar))
+	if raw == "" {
+		return append([]string(nil), defaultProviderOrder...), nil
+	}
+
+	var order []string
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(raw, ",") {
+		name, ok := sourceAliases[strings.ToLower(strings.TrimSpace(item))]
+		if !ok || name == "dexscreener" {
+			return nil, fmt.Errorf("%s contains unknown CEX provider %q", envVar, strings.TrimSpace(item))
+		}
+		if !seen[name] {
+			seen[name] = true
+			order = append(order, name)
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigBaseURLOverrides(t *testing.T) {
+	t.Setenv("CMC_BASE_URL", "https://proxy.example.com/cmc/")
+	t.Setenv("COINGECKO_BASE_URL", "")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.CMCBaseURL != "https://proxy.example.com/cmc" {
+		t.Errorf("CMCBaseURL = %q, want the override without its trailing slash", cfg.CMCBaseURL)
+	}
+	if cfg.CoinGeckoBaseURL != "https://api.coingecko.com/api/v3" {
+		t.Errorf("CoinGeckoBaseURL = %q, want the public default", cfg.CoinGeckoBaseURL)
+	}
+}
+
+func TestLoadConfigRejectsInvalidBaseURL(t *testing.T) {
+	for _, raw := range []string{"proxy.example.com", "ftp://proxy.example.com", "https://"} {
+		t.Setenv("COINGECKO_BASE_URL", raw)
+		if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "COINGECKO_BASE_URL") {
+			t.Errorf("LoadConfig with COINGECKO_BASE_URL=%q: err = %v, want a COINGECKO_BASE_URL error", raw, err)
+		}
+	}
+}
+
+func TestProvidersUseOverriddenBaseURL(t *testing.T) {
+	var paths []string
+	server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/cmc/"):
+			respond(w, http.StatusOK, cmcQuote("BTC", 60000))
+		default:
+			respond(w, http.StatusOK, cgCoin("bitcoin", "btc", 60000))
+		}
+	})
+	a := newTestAgent(t, map[string]string{
+		"CMC_API_KEY":        "key",
+		"CMC_BASE_URL":       server.URL + "/cmc",
+		"COINGECKO_BASE_URL": server.URL + "/cg",
+	})
+
+	if response, err := a.getCMCData(context.Background(), "btc", "usd"); err != nil || !strings.Contains(response, "token_source:coinmarketcap") {
+		t.Errorf("getCMCData = %q, %v", response, err)
+	}
+	if response, err := a.getCoinGeckoData(context.Background(), "bitcoin", "usd"); err != nil || !strings.Contains(response, "token_source:coingecko") {
+		t.Errorf("getCoinGeckoData = %q, %v", response, err)
+	}
+	want := []string{"/cmc/v1/cryptocurrency/quotes/latest", "/cg/coins/bitcoin"}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("requested paths = %q, want %q", paths, want)
+	}
+}
+
+func TestLoadConfigDefaultFiat(t *testing.T) {
+	t.Setenv("DEFAULT_FIAT", "")
+	if cfg, err := LoadConfig(); err != nil || cfg.DefaultFiat != "usd" {
+		t.Errorf("DefaultFiat without DEFAULT_FIAT = %v, %v; want usd", cfg.DefaultFiat, err)
+	}
+	t.Setenv("DEFAULT_FIAT", " EUR ")
+	if cfg, err := LoadConfig(); err != nil || cfg.DefaultFiat != "eur" {
+		t.Errorf("DefaultFiat = %v, %v; want eur", cfg.DefaultFiat, err)
+	}
+	t.Setenv("DEFAULT_FIAT", "doge")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "DEFAULT_FIAT") {
+		t.Errorf("LoadConfig with DEFAULT_FIAT=doge: err = %v, want a DEFAULT_FIAT error", err)
+	}
+}
+
+func TestDefaultFiatApplies(t *testing.T) {
+	var quotes []string
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			convert := r.URL.Query().Get("convert")
+			quotes = append(quotes, convert)
+			respond(w, http.StatusOK, strings.Replace(cmcQuote("BTC", 60000), `"USD"`, `"`+convert+`"`, 1))
+		},
+		"coingecko": func(w http.ResponseWriter, r *http.Request) {
+			quotes = append(quotes, r.URL.Query().Get("vs_currencies"))
+			respond(w, http.StatusOK, `{"bitcoin":{"eur":54000}}`)
+		},
+	})
+	env := f.env()
+	env["DEFAULT_FIAT"] = "eur"
+	env["PRICE_CROSS_CHECK"] = "false" // only count the lookups themselves
+	a := newTestAgent(t, env)
+	ctx := context.Background()
+
+	a.processTask(ctx, session{}, "/price btc")
+	a.processTask(ctx, session{}, "/price btc --currency=gbp")
+	response, _ := a.processTask(ctx, session{}, "/convert 1 btc")
+
+	if want := []string{"EUR", "GBP", "eur"}; !slices.Equal(quotes, want) {
+		t.Errorf("providers were asked for %q, want %q", quotes, want)
+	}
+	if !strings.Contains(response, "**€54,000.00**") {
+		t.Errorf("/convert without a target = %q, want EUR", response)
+	}
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	dir := t.TempDir()
+	if err := loadDotEnv(filepath.Join(dir, ".env")); err != nil {
+		t.Errorf("missing .env: %v, want it ignored", err)
+	}
+
+	malformed := filepath.Join(dir, "malformed.env")
+	if err := os.WriteFile(malformed, []byte("CMC_API_KEY=\"unterminated\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := loadDotEnv(malformed)
+	if err == nil || !strings.Contains(err.Error(), "could not parse "+malformed) {
+		t.Errorf("malformed .env: err = %v, want a parse error naming the file", err)
+	}
+}
+
+func TestLoadConfigLogLevel(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want slog.Level
+	}{
+		{"", slog.LevelInfo},
+		{"debug", slog.LevelDebug},
+		{" WARN ", slog.LevelWarn},
+		{"error", slog.LevelError},
+	}
+	for _, tt := range tests {
+		t.Setenv("LOG_LEVEL", tt.raw)
+		cfg, err := LoadConfig()
+		if err != nil || cfg.LogLevel != tt.want {
+			t.Errorf("LOG_LEVEL=%q: LogLevel = %v, %v; want %v", tt.raw, cfg.LogLevel, err, tt.want)
+		}
+	}
+
+	t.Setenv("LOG_LEVEL", "verbose")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("LOG_LEVEL=verbose was accepted")
+	}
+}
+
+func TestLogLevelSuppressesDebugLogs(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	for _, level := range []string{"info", "debug"} {
+		f := newFakeProviders(t, map[string]http.HandlerFunc{"cmc": body(cmcQuote("BTC", 60000))})
+		env := f.env()
+		env["LOG_LEVEL"] = level
+		a := newTestAgent(t, env)
+		var logs bytes.Buffer
+		slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: a.config.LogLevel})))
+
+		a.processTask(context.Background(), session{}, "/price btc")
+		attempts := strings.Contains(logs.String(), `level=DEBUG msg="Attempting provider lookup" provider=cmc`)
+		if attempts != (level == "debug") {
+			t.Errorf("LOG_LEVEL=%s: provider attempt logged = %v:\n%s", level, attempts, logs.String())
+		}
+		if level == "info" && strings.Contains(logs.String(), "level=DEBUG") {
+			t.Errorf("LOG_LEVEL=info logged debug lines:\n%s", logs.String())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// --- CoinGecko Contract Details (Enriching DEX Results) ---
+
+// contractDetailFields looks the DEX token up on CoinGecko by contract and
+// returns the supply, rank and ATH fields to merge into the response. The
+// fields are cg_-prefixed so the overview can attribute them to CoinGecko.
+// Enrichment is best effort: any failure simply yields "".
+func (a *PMOAgent) contractDetailFields(ctx context.Context, dexResponse string) string {
+	parts := parseRawOutput(dexResponse)
+	platform := lookupChain(parts["chain_id"]).coingecko
+	address := strings.ToLower(parts["contract_address"])
+	if platform == "" || address == "" {
+		return ""
+	}
+
+	req, err := a.newCoinGeckoRequest(ctx, fmt.Sprintf("/coins/%s/contract/%s", platform, address))
+	if err != nil {
+		slog.Error("Error creating CG contract request", "err", err)
+		return ""
+	}
+
+	var coin CoinGeckoResponse
+	status, err := a.fetchJSON(req, &coin)
+	if status != http.StatusOK || err != nil || coin.Error != "" || coin.ID == "" {
+		slog.Warn("No CoinGecko details for contract", "address", address, "platform", platform, "status", status, "err", err)
+		return ""
+	}
+
+	var fields strings.Builder
+	if coin.MarketCapRank > 0 {
+		fields.WriteString(fmt.Sprintf(";cg_rank:%d", coin.MarketCapRank))
+	}
+	if marketCap := coin.MarketData.MarketCap.value("usd"); marketCap > 0 {
+		fields.WriteString(";cg_market_cap:" + formatCurrency(marketCap, "usd"))
+	}
+	if coin.MarketData.CirculatingSupply > 0 {
+		fields.WriteString(";cg_circulating_supply:" + formatQuantity(coin.MarketData.CirculatingSupply))
+	}
+	if coin.MarketData.TotalSupply > 0 {
+		fields.WriteString(";cg_total_supply:" + formatQuantity(coin.MarketData.TotalSupply))
+	}
+	if ath := coin.MarketData.ATH.value("usd"); ath > 0 {
+		fields.WriteString(";cg_ath:" + formatPrice(ath, "usd"))
+	}
+	return fields.String()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMarketMergesDexPriceWithCoinGeckoDetails(t *testing.T) {
+	address := "0x6982508145454ce325ddbe47a25d4ec3d2311933"
+	var contractPaths []string
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"dexscreener": body(`{"pairs":[{"chainId":"ethereum","priceUsd":"0.0000105","baseToken":{"address":"` + address + `","symbol":"PEPE"},
+			"quoteToken":{"symbol":"WETH"},"liquidity":{"usd":25000000}}]}`),
+		"coingecko": func(w http.ResponseWriter, r *http.Request) {
+			contractPaths = append(contractPaths, r.URL.Path)
+			respond(w, http.StatusOK, `{"id":"pepe","symbol":"pepe","name":"Pepe","market_cap_rank":30,"market_data":{
+				"current_price":{"usd":0.00001},"market_cap":{"usd":4400000000},"ath":{"usd":0.00002803},
+				"circulating_supply":420690000000000,"total_supply":420690000000000}}`)
+		},
+	})
+	env := f.env()
+	env["GOPLUS_BASE_URL"] = f.url + "/goplus" // unrouted, so the scan is simply unavailable
+	a := newTestAgent(t, env)
+
+	response, err := a.processTask(context.Background(), session{}, "/market "+address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/coins/ethereum/contract/" + address; len(contractPaths) != 1 || contractPaths[0] != want {
+		t.Errorf("CoinGecko requests = %v, want %s", contractPaths, want)
+	}
+	dex, details, ok := strings.Cut(response, "📇 **CoinGecko Details**")
+	if !ok {
+		t.Fatalf("response has no CoinGecko section:\n%s", response)
+	}
+	if !strings.Contains(dex, "- **Price (USD):** $0.0000105") {
+		t.Errorf("the price is not Dexscreener's:\n%s", response)
+	}
+	for _, want := range []string{
+		"- **Market Cap Rank:** #30",
+		"- **Market Cap:** $4,400,000,000.00",
+		"- **Circulating Supply:** 420,690,000,000,000 (420.69T)",
+		"- **All-Time High:** $0.00002803",
+	} {
+		if !strings.Contains(details, want) {
+			t.Errorf("CoinGecko section missing %q:\n%s", want, response)
+		}
+	}
+	if !strings.Contains(response, "*(Data provided by DEXSCREENER, details by COINGECKO") {
+		t.Errorf("the footer does not attribute both sources:\n%s", response)
+	}
+}
+
+func TestContractDetailsSkipUnknownPlatform(t *testing.T) {
+	f := newFakeProviders(t, nil)
+	a := newTestAgent(t, f.env())
+
+	if fields := a.contractDetailFields(context.Background(), "chain_id:testchain;contract_address:0xabc"); fields != "" || f.count("coingecko") != 0 {
+		t.Errorf("contractDetailFields = %q after %d calls, want nothing for a chain CoinGecko doesn't map", fields, f.count("coingecko"))
+	}
+}
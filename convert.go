@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// supportedFiats are the fiat codes CoinGecko accepts as vs_currencies.
+var supportedFiats = map[string]bool{
+	"usd": true, "eur": true, "gbp": true, "jpy": true, "cny": true,
+	"krw": true, "inr": true, "cad": true, "aud": true, "chf": true,
+	"brl": true, "rub": true, "try": true, "sgd": true, "hkd": true,
+	"mxn": true, "zar": true, "sek": true, "nok": true, "pln": true,
+}
+
+func isFiat(code string) bool {
+	return supportedFiats[strings.ToLower(code)]
+}
+
+// --- CoinGecko Simple Price (For /convert) ---
+
+// getSimplePrices fetches prices for the given CoinGecko IDs in the given
+// vs_currencies, keyed by ID and then currency code.
+func (a *PMOAgent) getSimplePrices(ctx context.Context, ids []string, vsCurrencies []string) (map[string]map[string]float64, error) {
+	path := fmt.Sprintf("/simple/price?ids=%s&vs_currencies=%s",
+		url.QueryEscape(strings.Join(ids, ",")),
+		url.QueryEscape(strings.Join(vsCurrencies, ",")),
+	)
+
+	req, err := a.newCoinGeckoRequest(ctx, path)
+	if err != nil {
+		slog.Error("Error creating CG simple price request", "err", err)
+		return nil, err
+	}
+
+	prices := make(map[string]map[string]float64)
+	status, err := a.fetchJSON(req, &prices)
+	if errors.Is(err, errResponseTooLarge) || status == 0 {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("CoinGecko API returned status %d", status)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+// coinPrices fetches vsCurrencies prices for crypto symbols, keyed by the
+// symbol. Like lookupToken, a symbol whose guessed CoinGecko ID is unknown
+// gets one retry with its /search match; a symbol neither resolves is an
+// error, so callers never treat a typo as a priced asset.
+func (a *PMOAgent) coinPrices(ctx context.Context, symbols []string, vsCurrencies []string) (map[string]map[string]float64, error) {
+	ids := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		ids[i] = getCoinID(symbol)
+	}
+	prices, err := a.getSimplePrices(ctx, ids, vsCurrencies)
+	if err != nil {
+		return nil, err
+	}
+
+	bySymbol := make(map[string]map[string]float64, len(symbols))
+	for i, symbol := range symbols {
+		quote, ok := prices[ids[i]]
+		if !ok {
+			// /simple/price silently drops unknown IDs instead of returning 404
+			id, found := a.searchCoinGeckoID(ctx, ids[i])
+			if !found || id == ids[i] {
+				return nil, fmt.Errorf("unknown asset: %s", symbol)
+			}
+			retry, err := a.getSimplePrices(ctx, []string{id}, vsCurrencies)
+			if err != nil {
+				return nil, err
+			}
+			if quote, ok = retry[id]; !ok {
+				return nil, fmt.Errorf("unknown asset: %s", symbol)
+			}
+		}
+		bySymbol[symbol] = quote
+	}
+	return bySymbol, nil
+}
+
+// fiatQuote is the price of one unit of an asset in a fiat currency. The
+// zero value means no fiat price is known.
+type fiatQuote struct {
+	price    float64
+	currency string
+}
+
+// conversionRate returns how many units of `to` one unit of `from` is worth.
+// Either side may be a fiat code or a crypto symbol/ID; crypto symbols are
+// resolved as coinPrices does, and all legs are priced against CoinGecko in
+// one response per symbol. Converting an asset into itself still checks that
+// the asset exists. For a crypto target it also returns the target's fiat
+// price from the same response, which --fees uses to value the fee.
+func (a *PMOAgent) conversionRate(ctx context.Context, from, to string) (float64, fiatQuote, error) {
+	from, to = strings.ToLower(from), strings.ToLower(to)
+
+	switch {
+	case isFiat(from) && isFiat(to):
+		if from == to {
+			return 1, fiatQuote{}, nil
+		}
+		// Cross the two fiats through BTC, which CoinGecko prices in every fiat
+		prices, err := a.getSimplePrices(ctx, []string{"bitcoin"}, []string{from, to})
+		if err != nil {
+			return 0, fiatQuote{}, err
+		}
+		rate, err := ratio(prices["bitcoin"], to, prices["bitcoin"], from)
+		return rate, fiatQuote{}, err
+
+	case isFiat(to):
+		prices, err := a.coinPrices(ctx, []string{from}, []string{to})
+		if err != nil {
+			return 0, fiatQuote{}, err
+		}
+		quote, ok := prices[from][to]
+		if !ok {
+			return 0, fiatQuote{}, fmt.Errorf("no %s price for %s", strings.ToUpper(to), from)
+		}
+		return quote, fiatQuote{}, nil
+
+	case isFiat(from):
+		prices, err := a.coinPrices(ctx, []string{to}, []string{from})
+		if err != nil {
+			return 0, fiatQuote{}, err
+		}
+		quote, ok := prices[to][from]
+		if !ok || quote == 0 {
+			return 0, fiatQuote{}, fmt.Errorf("no %s price for %s", strings.ToUpper(from), to)
+		}
+		return 1 / quote, fiatQuote{quote, from}, nil
+
+	default:
+		symbols := []string{from, to}
+		if from == to {
+			symbols = symbols[:1]
+		}
+		prices, err := a.coinPrices(ctx, symbols, []string{"usd"})
+		if err != nil {
+			return 0, fiatQuote{}, err
+		}
+		rate, err := ratio(prices[from], "usd", prices[to], "usd")
+		return rate, fiatQuote{prices[to]["usd"], "usd"}, err
+	}
+}
+
+// ratio divides numerator[numKey] by denominator[denKey], failing on missing legs.
+func ratio(numerator map[string]float64, numKey string, denominator map[string]float64, denKey string) (float64, error) {
+	num, ok := numerator[numKey]
+	if !ok {
+		return 0, fmt.Errorf("missing %s price", numKey)
+	}
+	den, ok := denominator[denKey]
+	if !ok || den == 0 {
+		return 0, fmt.Errorf("missing %s price", denKey)
+	}
+	return num / den, nil
+}
+
+// formatConvertedAmount renders an amount of the given currency or token.
+func formatConvertedAmount(amount float64, code string) string {
+	if isFiat(code) {
+		return formatCurrency(amount, code)
+	}
+	formatted := strings.TrimRight(strings.TrimRight(strconv.FormatFloat(amount, 'f', 8, 64), "0"), ".")
+	return fmt.Sprintf("%s %s", formatted, strings.ToUpper(code))
+}
+
+// maxConvertDecimals is the finest crypto amount /convert shows (one
+// satoshi for BTC); anything smaller is reported as effectively zero.
+const maxConvertDecimals = 8
+
+// roundSignificant formats amount with sigFigs significant digits, keeping every
+// integer digit and never more than maxConvertDecimals decimals.
+func roundSignificant(amount float64, sigFigs int) string {
+	decimals := maxConvertDecimals
+	if amount != 0 {
+		magnitude := int(math.Floor(math.Log10(math.Abs(amount))))
+		decimals = max(0, min(sigFigs-1-magnitude, maxConvertDecimals))
+	}
+	formatted := strconv.FormatFloat(amount, 'f', decimals, 64)
+	if strings.Contains(formatted, ".") {
+		formatted = strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
+	}
+	return formatted
+}
+
+// formatConvertResult renders a computed /convert amount. Fiat keeps its
+// currency format; crypto is rounded to CONVERT_SIGNIFICANT_DIGITS, and an
+// amount too small to show at maxConvertDecimals is flagged as dust instead
+// of printing as 0.
+func (a *PMOAgent) formatConvertResult(amount float64, code string) string {
+	if isFiat(code) {
+		return formatCurrency(amount, code)
+	}
+	if smallest := math.Pow10(-maxConvertDecimals); amount > 0 && amount < smallest/2 {
+		return fmt.Sprintf("< %s %s (effectively zero)", strconv.FormatFloat(smallest, 'f', maxConvertDecimals, 64), strings.ToUpper(code))
+	}
+	return fmt.Sprintf("%s %s", roundSignificant(amount, a.config.ConvertSignificantDigits), strings.ToUpper(code))
+}
+
+// formatFeeAmount renders a --fees estimate in the target coin, followed by
+// its value in the fiat the conversion priced the target in, if any.
+func formatFeeAmount(fee float64, code string, quote fiatQuote) string {
+	amount := "~" + formatConvertedAmount(fee, code)
+	if quote.price > 0 {
+		amount += fmt.Sprintf(" (%s)", formatPrice(fee*quote.price, quote.currency))
+	}
+	return amount
+}
+
+// convertAmount handles `/convert <amount> <from> [to] [--inverse] [--fees]`.
+// The target defaults to DEFAULT_FIAT when omitted. --fees subtracts the live
+// gas cost of a transfer for EVM native coins with an RPC node configured, or
+// a typical withdrawal fee for other coins with a known fee, and shows the fee
+// in fiat too. Arguments are read purely by position, so a numeric ticker
+// such as 404 is a symbol in the from/to slots and only ever an amount in the
+// first one.
+func (a *PMOAgent) convertAmount(ctx context.Context, args []string, flags map[string]string) (string, error) {
+	if len(args) < 2 {
+		return usageFor("/convert"), nil
+	}
+
+	amount, ok := parseAmount(args[0])
+	if !ok {
+		return withUsage(fmt.Sprintf("Invalid amount: %s. Please provide a positive number.", args[0]), "/convert"), nil
+	}
+	from, to := args[1], a.defaultFiat()
+	if len(args) > 2 {
+		to = args[2]
+	}
+
+	rate, toQuote, err := a.conversionRate(ctx, from, to)
+	if err != nil {
+		slog.Error("Conversion failed", "from", from, "to", to, "err", err)
+		return fmt.Sprintf("Could not convert %s to %s. Please check both symbols.", strings.ToUpper(from), strings.ToUpper(to)), nil
+	}
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString("🔄 **Conversion**\n")
+	responseBuilder.WriteString(fmt.Sprintf("- %s = **%s**\n", formatConvertedAmount(amount, from), a.formatConvertResult(amount*rate, to)))
+
+	// The inverse comes from the same rate, so no extra API call is needed
+	if _, ok := flags["inverse"]; ok && rate != 0 {
+		responseBuilder.WriteString(fmt.Sprintf("- %s = %s\n", formatConvertedAmount(1, to), a.formatConvertResult(1/rate, from)))
+	}
+
+	if _, ok := flags["fees"]; ok && !isFiat(to) {
+		if fee, live, known := a.networkFee(ctx, to); known {
+			label := "withdrawal fee"
+			if live {
+				label = "gas"
+			}
+			responseBuilder.WriteString(fmt.Sprintf("- After %s %s: **%s**\n", formatFeeAmount(fee, to, toQuote), label, a.formatConvertResult(netAfterFee(amount*rate, fee), to)))
+		}
+	}
+
+	responseBuilder.WriteString("\n*(Data provided by COINGECKO)*")
+	return responseBuilder.String(), nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestConvertInverse(t *testing.T) {
+	providers := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": body(`{"ethereum":{"usd":2000},"bitcoin":{"usd":40000}}`),
+	})
+	a := newTestAgent(t, providers.env())
+
+	tests := []struct {
+		input   string
+		forward string
+		inverse string
+	}{
+		// Fiat leg: 1 USD is worth 1/2000 ETH
+		{"/convert 2 eth usd --inverse", "- 2 ETH = **$4,000.00**", "- $1.00 = 0.0005 ETH"},
+		// Crypto leg: ETH/BTC is 0.05, so one BTC buys 20 ETH
+		{"/convert 1 eth btc --inverse", "- 1 ETH = **0.05 BTC**", "- 1 BTC = 20 ETH"},
+	}
+	for _, tt := range tests {
+		before := providers.count("coingecko")
+		response, err := a.processTask(context.Background(), session{}, tt.input)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.input, err)
+		}
+		if calls := providers.count("coingecko") - before; calls != 1 {
+			t.Errorf("%s: made %d CoinGecko calls, want 1", tt.input, calls)
+		}
+		for _, want := range []string{tt.forward, tt.inverse} {
+			if !strings.Contains(response, want) {
+				t.Errorf("%s: response missing %q:\n%s", tt.input, want, response)
+			}
+		}
+	}
+}
+
+func TestConvertWithoutInverseFlag(t *testing.T) {
+	providers := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": body(`{"ethereum":{"usd":2000}}`),
+	})
+	a := newTestAgent(t, providers.env())
+
+	response, err := a.processTask(context.Background(), session{}, "/convert 2 eth usd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(response, "$1.00 =") {
+		t.Errorf("inverse shown without --inverse:\n%s", response)
+	}
+}
+
+func TestConvertNumericTicker(t *testing.T) {
+	providers := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": func(w http.ResponseWriter, r *http.Request) {
+			if ids := r.URL.Query().Get("ids"); ids != "404" {
+				t.Errorf("CoinGecko asked for ids=%q, want the 404 token", ids)
+			}
+			respond(w, http.StatusOK, `{"404":{"usd":0.5}}`)
+		},
+	})
+	a := newTestAgent(t, providers.env())
+	ctx := context.Background()
+
+	// In the second slot 404 is the token
+	if response, _ := a.processTask(ctx, session{}, "/convert 10 404 usd"); !strings.Contains(response, "- 10 404 = **$5.00**") {
+		t.Errorf("numeric ticker conversion:\n%s", response)
+	}
+
+	// In the first slot it's the amount, so this converts 404 USD into the default fiat
+	response, _ := a.processTask(ctx, session{}, "/convert 404 usd")
+	if !strings.Contains(response, "= **$404.00**") {
+		t.Errorf("/convert 404 usd:\n%s", response)
+	}
+	if providers.count("coingecko") != 1 {
+		t.Error("/convert 404 usd looked 404 up as a token")
+	}
+}
+
+func TestRoundSignificant(t *testing.T) {
+	tests := []struct {
+		amount  float64
+		sigFigs int
+		want    string
+	}{
+		{0.123456789, 6, "0.123457"},
+		{1234.56789, 6, "1234.57"},
+		{123456789.4, 6, "123456789"}, // integer digits are never dropped
+		{0.000012345678, 6, "0.00001235"},
+		{0.000012345678, 3, "0.0000123"},
+		{0.5, 6, "0.5"},
+		{0, 6, "0"},
+	}
+	for _, tt := range tests {
+		if got := roundSignificant(tt.amount, tt.sigFigs); got != tt.want {
+			t.Errorf("roundSignificant(%v, %d) = %q, want %q", tt.amount, tt.sigFigs, got, tt.want)
+		}
+	}
+}
+
+func TestConvertTinyCryptoResults(t *testing.T) {
+	providers := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": body(`{"bitcoin":{"usd":60000}}`),
+	})
+	a := newTestAgent(t, providers.env())
+	ctx := context.Background()
+
+	tests := map[string]string{
+		"/convert 0.1 usd btc":    "- $0.10 = **0.00000167 BTC**",
+		"/convert 0.0004 usd btc": "- $0.00 = **0.00000001 BTC**",
+		"/convert 0.0001 usd btc": "- $0.00 = **< 0.00000001 BTC (effectively zero)**",
+	}
+	for input, want := range tests {
+		response, _ := a.processTask(ctx, session{}, input)
+		if !strings.Contains(response, want) {
+			t.Errorf("%s: response is missing %q:\n%s", input, want, response)
+		}
+		if strings.Contains(response, "0.000000000") {
+			t.Errorf("%s: response shows dust digits:\n%s", input, response)
+		}
+	}
+}
+
+func TestConvertResolvesSymbolsViaSearch(t *testing.T) {
+	providers := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			switch {
+			case r.URL.Path == "/search" && query.Get("query") == "pengu":
+				respond(w, http.StatusOK, `{"coins":[{"id":"pudgy-penguins","name":"Pudgy Penguins","symbol":"PENGU"}]}`)
+			case r.URL.Path == "/search":
+				respond(w, http.StatusOK, `{"coins":[]}`)
+			case query.Get("ids") == "pudgy-penguins":
+				respond(w, http.StatusOK, `{"pudgy-penguins":{"usd":0.02}}`)
+			default:
+				// Like CoinGecko, unknown IDs are left out rather than failing
+				respond(w, http.StatusOK, `{}`)
+			}
+		},
+	})
+	a := newTestAgent(t, providers.env())
+	ctx := context.Background()
+
+	response, _ := a.processTask(ctx, session{}, "/convert 100 pengu usd")
+	if !strings.Contains(response, "- 100 PENGU = **$2.00**") {
+		t.Errorf("/convert via search match:\n%s", response)
+	}
+
+	// An asset converted into itself must still exist
+	for _, input := range []string{"/convert 5 foo foo", "/convert 5 foo usd"} {
+		if response, _ := a.processTask(ctx, session{}, input); !strings.HasPrefix(response, "Could not convert FOO") {
+			t.Errorf("%s: response = %q, want the unknown asset rejected", input, response)
+		}
+	}
+	if response, _ := a.processTask(ctx, session{}, "/convert 5 usd usd"); !strings.Contains(response, "- $5.00 = **$5.00**") {
+		t.Errorf("/convert 5 usd usd:\n%s", response)
+	}
+}
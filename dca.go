@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDCALookbackDays caps simulations at the history CoinGecko's public API
+// serves at daily granularity.
+const maxDCALookbackDays = 365
+
+// dcaIntervals maps accepted interval names to their length in days.
+var dcaIntervals = map[string]int{
+	"daily": 1, "day": 1, "d": 1,
+	"weekly": 7, "week": 7, "w": 7,
+	"biweekly": 14,
+	"monthly":  30, "month": 30, "m": 30,
+}
+
+// dcaResult is the outcome of a dollar-cost-averaging simulation.
+type dcaResult struct {
+	buys     int
+	invested float64
+	coins    float64
+}
+
+// priceAt returns the price of the latest point at or before t, or the first
+// point when t predates the series. points must be in chronological order.
+func priceAt(points []pricePoint, t time.Time) float64 {
+	price := points[0].price
+	for _, p := range points {
+		if p.time.After(t) {
+			break
+		}
+		price = p.price
+	}
+	return price
+}
+
+// simulateDCA buys amount worth of the coin at each of the buy times.
+func simulateDCA(points []pricePoint, amount float64, buyTimes []time.Time) dcaResult {
+	var result dcaResult
+	for _, t := range buyTimes {
+		price := priceAt(points, t)
+		if price <= 0 {
+			continue
+		}
+		result.buys++
+		result.invested += amount
+		result.coins += amount / price
+	}
+	return result
+}
+
+// getDCA handles `/dca <symbol> <amount> <interval> <periods>`, e.g.
+// `/dca btc 100 weekly 52`: the last buy is one interval before today, and
+// the holdings are valued at the latest price.
+func (a *PMOAgent) getDCA(ctx context.Context, args []string, _ map[string]string) (string, error) {
+	const cmdName = "/dca"
+
+	amount, ok := parseAmount(args[1])
+	if !ok {
+		return withUsage(fmt.Sprintf("Invalid amount: %s. Please provide a positive USD amount.", args[1]), cmdName), nil
+	}
+	interval, ok := dcaIntervals[strings.ToLower(args[2])]
+	if !ok {
+		return withUsage(fmt.Sprintf("Unknown interval: %s. Use daily, weekly, biweekly or monthly.", args[2]), cmdName), nil
+	}
+	periods, err := strconv.Atoi(args[3])
+	if err != nil || periods < 1 {
+		return withUsage(fmt.Sprintf("Invalid number of periods: %s.", args[3]), cmdName), nil
+	}
+	lookback := interval * periods
+	if lookback > maxDCALookbackDays {
+		return withUsage(fmt.Sprintf("That schedule spans %d days; simulations are limited to %d days of history.", lookback, maxDCALookbackDays), cmdName), nil
+	}
+
+	coinID := getCoinID(args[0])
+	chart, message, err := a.getMarketChart(ctx, coinID, lookback+1)
+	if chart == nil {
+		return message, err
+	}
+	points := chart.points()
+
+	now := time.Now().UTC()
+	buyTimes := make([]time.Time, periods)
+	for k := range buyTimes {
+		buyTimes[k] = now.AddDate(0, 0, -(periods-k)*interval)
+	}
+
+	result := simulateDCA(points, amount, buyTimes)
+	if result.buys == 0 || result.coins == 0 {
+		return fmt.Sprintf("Not enough price history to simulate DCA into %s.", strings.ToUpper(args[0])), nil
+	}
+
+	current := points[len(points)-1].price
+	value := result.coins * current
+	roi := (value - result.invested) / result.invested * 100
+
+	symbol := strings.ToUpper(args[0])
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString(fmt.Sprintf("🧮 **DCA Simulation: %s %s %s × %d**\n", formatCurrency(amount, "usd"), symbol, strings.ToLower(args[2]), periods))
+	responseBuilder.WriteString(fmt.Sprintf("- **Total Invested:** %s over %d buys\n", formatCurrency(result.invested, "usd"), result.buys))
+	responseBuilder.WriteString(fmt.Sprintf("- **Accumulated:** %s\n", formatConvertedAmount(result.coins, symbol)))
+	responseBuilder.WriteString(fmt.Sprintf("- **Average Cost:** %s\n", formatPrice(result.invested/result.coins, "usd")))
+	responseBuilder.WriteString(fmt.Sprintf("- **Current Value:** %s (at %s)\n", formatCurrency(value, "usd"), formatPrice(current, "usd")))
+	if roi >= 0 {
+		responseBuilder.WriteString(fmt.Sprintf("- **ROI:** 🟢 +%.2f%%\n", roi))
+	} else {
+		responseBuilder.WriteString(fmt.Sprintf("- **ROI:** 🔴 %.2f%%\n", roi))
+	}
+	responseBuilder.WriteString("\n*(Data provided by COINGECKO)*")
+
+	return responseBuilder.String(), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimulateDCA(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	points := []pricePoint{
+		{time: start, price: 100},
+		{time: start.Add(day), price: 50},
+		{time: start.Add(2 * day), price: 200},
+		{time: start.Add(3 * day), price: 0}, // a gap in the data is skipped
+	}
+	buyTimes := []time.Time{start, start.Add(day), start.Add(2 * day), start.Add(3 * day)}
+
+	result := simulateDCA(points, 100, buyTimes)
+	if result.buys != 3 || result.invested != 300 {
+		t.Errorf("simulateDCA = %d buys, %v invested; want 3, 300", result.buys, result.invested)
+	}
+	// $100 buys 1 + 2 + 0.5 coins at 100, 50 and 200
+	if math.Abs(result.coins-3.5) > 1e-9 {
+		t.Errorf("coins = %v, want 3.5", result.coins)
+	}
+}
+
+func TestPriceAt(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := []pricePoint{{time: start, price: 10}, {time: start.Add(time.Hour), price: 20}}
+
+	tests := []struct {
+		at   time.Time
+		want float64
+	}{
+		{start.Add(-time.Hour), 10}, // before the series: the first point
+		{start.Add(30 * time.Minute), 10},
+		{start.Add(time.Hour), 20},
+		{start.Add(5 * time.Hour), 20},
+	}
+	for _, tt := range tests {
+		if got := priceAt(points, tt.at); got != tt.want {
+			t.Errorf("priceAt(%s) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestDCAValidatesInputs(t *testing.T) {
+	a := newTestAgent(t, nil)
+	tests := []struct {
+		input, want string
+	}{
+		{"/dca btc -5 weekly 52", "Invalid amount: -5."},
+		{"/dca btc 100 hourly 52", "Unknown interval: hourly."},
+		{"/dca btc 100 weekly 0", "Invalid number of periods: 0."},
+		{"/dca btc 100 monthly 24", "That schedule spans 720 days; simulations are limited to 365 days of history."},
+	}
+	for _, tt := range tests {
+		response, _ := a.processTask(context.Background(), session{}, tt.input)
+		if !strings.Contains(response, tt.want) {
+			t.Errorf("%s = %q, want %q", tt.input, response, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// --- Lookup Tracing (--debug) ---
+
+// providerAttempt is one provider call made while resolving a lookup.
+type providerAttempt struct {
+	provider  string
+	duration  time.Duration
+	succeeded bool
+}
+
+// lookupTrace collects provider timings for the --debug breakdown. A nil
+// trace is valid and records nothing, so callers needn't check the flag.
+type lookupTrace struct {
+	attempts []providerAttempt
+	cacheHit bool
+}
+
+// record notes a provider call that started at start.
+func (t *lookupTrace) record(provider string, start time.Time, succeeded bool) {
+	if t == nil {
+		return
+	}
+	t.attempts = append(t.attempts, providerAttempt{provider: provider, duration: time.Since(start), succeeded: succeeded})
+}
+
+// render formats the breakdown appended to the response.
+func (t *lookupTrace) render() string {
+	if t == nil {
+		return ""
+	}
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString("\n\n🛠 **Debug Timing**\n")
+	if t.cacheHit {
+		responseBuilder.WriteString("- Served from cache (no provider calls)\n")
+	}
+	for _, attempt := range t.attempts {
+		outcome := "❌ failed"
+		if attempt.succeeded {
+			outcome = "✅ served result"
+		}
+		responseBuilder.WriteString(fmt.Sprintf("- %s: %dms %s\n", attempt.provider, attempt.duration.Milliseconds(), outcome))
+	}
+	return strings.TrimRight(responseBuilder.String(), "\n")
+}
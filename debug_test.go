@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDebugFlagShowsTiming(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"coingecko": body(cgCoin("bitcoin", "btc", 60000))})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	response, err := a.processTask(ctx, session{}, "/price btc --debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// CMC 404s, so CoinGecko serves the result after it
+	for _, want := range []string{`🛠 \*\*Debug Timing\*\*`, `- cmc: \d+ms ❌ failed`, `- coingecko: \d+ms ✅ served result`} {
+		if !regexp.MustCompile(want).MatchString(response) {
+			t.Errorf("response does not match %q:\n%s", want, response)
+		}
+	}
+
+	response, err = a.processTask(ctx, session{}, "/price btc --fresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(response, "Debug Timing") {
+		t.Errorf("timing shown without --debug:\n%s", response)
+	}
+}
+
+func TestDebugFlagReportsCacheHit(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"cmc": body(cmcQuote("BTC", 60000))})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	a.processTask(ctx, session{}, "/price btc")
+	response, _ := a.processTask(ctx, session{}, "/price btc --debug")
+	if !strings.Contains(response, "- Served from cache (no provider calls)") {
+		t.Errorf("response does not report the cache hit:\n%s", response)
+	}
+}
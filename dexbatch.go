@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// --- Batched Dexscreener Lookups (/price 0xabc,0xdef) ---
+
+// addressList reports whether args is a single comma-separated list of two or
+// more contract addresses, returning them lower-cased and de-duplicated.
+func addressList(args []string) ([]string, bool) {
+	if len(args) != 1 || !strings.Contains(args[0], ",") {
+		return nil, false
+	}
+
+	var addresses []string
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(args[0], ",") {
+		address := strings.ToLower(strings.TrimSpace(item))
+		if address == "" {
+			continue
+		}
+		if !isContractAddress(address) {
+			return nil, false
+		}
+		if !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, len(addresses) > 1
+}
+
+// lookupDexBatch resolves several contract addresses with one tokens call,
+// mapping the returned pairs back to each address by base token. Cached
+// addresses are served without being re-requested.
+func (a *PMOAgent) lookupDexBatch(ctx context.Context, addresses []string, flags map[string]string) (string, error) {
+	minLiquidity := a.config.MinLiquidityUSD
+	if override, ok := parseMinLiquidity(flags["minliq"]); ok {
+		minLiquidity = override
+	}
+
+	results := make([]tokenResult, len(addresses))
+	var pending []int
+	for i, address := range addresses {
+		results[i].target = address
+		if wantsFresh(flags) {
+			pending = append(pending, i)
+			continue
+		}
+		if cached, ok := a.cache.get(a.lookupCacheKey(address, flags)); ok {
+			results[i].raw = a.presentationView(ctx, cached, flags)
+			results[i].output, results[i].found = a.formatOutput(results[i].raw, flags), true
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	if len(pending) > 0 {
+		requested := make([]string, len(pending))
+		for j, i := range pending {
+			requested[j] = addresses[i]
+		}
+		slog.Debug("Attempting batched Dexscreener lookup", "addresses", len(requested))
+
+		fetchedAt := time.Now()
+		pairs, message, err := a.fetchDexPairs(ctx, strings.Join(requested, ","))
+		if message != "" {
+			// Reported like a single lookup's failure: in the reply, not as a task error
+			if err != nil {
+				slog.Error("Batched Dexscreener lookup failed", "addresses", len(requested), "err", err)
+			}
+			return message, nil
+		}
+
+		byAddress := make(map[string][]DexPair)
+		for _, pair := range pairs {
+			address := strings.ToLower(pair.BaseToken.Address)
+			byAddress[address] = append(byAddress[address], pair)
+		}
+
+		for _, i := range pending {
+			chainPairs, response := filterPairsByChain(byAddress[addresses[i]], flags["chain"])
+			if response == "" {
+				response = dexPairResponse(chainPairs, minLiquidity, a.config.TrustedQuoteTokens)
+			}
+			succeeded := providerSucceeded(response, nil)
+			a.health.record(a.dexProvider.name, addresses[i], succeeded)
+			if !succeeded {
+				continue
+			}
+			response += a.dexFields(ctx, response, flags)
+			a.cache.set(a.lookupCacheKey(addresses[i], flags), response, fetchedAt)
+			results[i].raw = a.presentationView(ctx, response, flags)
+			results[i].output, results[i].found = a.formatOutput(results[i].raw, flags), true
+		}
+	}
+
+	return a.renderResults(results, flags), nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const (
+	batchAddressA = "0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	batchAddressB = "0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	batchAddressC = "0xcccccccccccccccccccccccccccccccccccccccc"
+)
+
+func TestAddressList(t *testing.T) {
+	got, ok := addressList([]string{batchAddressA + ", " + strings.ToUpper(batchAddressB[2:]) + "," + batchAddressA})
+	if ok {
+		t.Errorf("a list with a non-address was accepted: %v", got)
+	}
+	got, ok = addressList([]string{batchAddressA + "," + batchAddressB + "," + batchAddressA})
+	if !ok || !slices.Equal(got, []string{batchAddressA, batchAddressB}) {
+		t.Errorf("addressList = %v, %v; want both addresses once", got, ok)
+	}
+	if _, ok := addressList([]string{batchAddressA}); ok {
+		t.Error("a single address was treated as a list")
+	}
+}
+
+func TestPriceMultipleAddressesInOneCall(t *testing.T) {
+	var paths []string
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"dexscreener": func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		// B has no pairs; testchain has no GoPlus coverage, so no risk scan runs
+		respond(w, http.StatusOK, `{"pairs":[
+			{"chainId":"testchain","priceUsd":"1.25","baseToken":{"address":"`+batchAddressA+`","symbol":"AAA"},"quoteToken":{"symbol":"USDC"},"liquidity":{"usd":50000}},
+			{"chainId":"testchain","priceUsd":"0.75","baseToken":{"address":"`+batchAddressC+`","symbol":"CCC"},"quoteToken":{"symbol":"USDC"},"liquidity":{"usd":50000}}
+		]}`)
+	}})
+	a := newTestAgent(t, f.env())
+
+	response, err := a.processTask(context.Background(), session{}, "/price "+batchAddressA+","+batchAddressB+","+batchAddressC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/latest/dex/tokens/" + batchAddressA + "," + batchAddressB + "," + batchAddressC; !slices.Equal(paths, []string{want}) {
+		t.Errorf("Dexscreener requests = %v, want one call for all three", paths)
+	}
+	blocks := strings.Split(response, "\n\n---\n\n")
+	if len(blocks) != 3 {
+		t.Fatalf("response has %d blocks, want AAA, CCC and the missing list:\n%s", len(blocks), response)
+	}
+	if !strings.Contains(blocks[0], "AAA $1.25") || !strings.Contains(blocks[1], "CCC $0.75") {
+		t.Errorf("blocks are not mapped back to their addresses:\n%s", response)
+	}
+	if blocks[2] != "❌ **Could not find:** "+batchAddressB {
+		t.Errorf("missing section = %q, want %s", blocks[2], batchAddressB)
+	}
+}
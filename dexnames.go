@@ -0,0 +1,47 @@
+package main
+
+import "strings"
+
+// --- DEX and Chain Display Names ---
+
+// dexNames maps Dexscreener dexId values to their usual spelling. Unknown
+// IDs are shown with a capitalised first letter.
+var dexNames = map[string]string{
+	"uniswap":     "Uniswap",
+	"pancakeswap": "PancakeSwap",
+	"sushiswap":   "SushiSwap",
+	"quickswap":   "QuickSwap",
+	"traderjoe":   "Trader Joe",
+	"aerodrome":   "Aerodrome",
+	"velodrome":   "Velodrome",
+	"camelot":     "Camelot",
+	"curve":       "Curve",
+	"balancer":    "Balancer",
+	"raydium":     "Raydium",
+	"orca":        "Orca",
+	"meteora":     "Meteora",
+}
+
+// displayName looks id up in names, falling back to capitalising it.
+func displayName(names map[string]string, id string) string {
+	if name, ok := names[strings.ToLower(id)]; ok {
+		return name
+	}
+	if id == "" {
+		return ""
+	}
+	return strings.ToUpper(id[:1]) + id[1:]
+}
+
+// PairAttribution describes which DEX pool priced a DEX result, e.g.
+// "WBTC/USDC on Uniswap (Ethereum)", or "" for other providers.
+func (d MarketData) PairAttribution() string {
+	if d.Pair == "" {
+		return ""
+	}
+	attribution := d.Pair + " on " + displayName(dexNames, d.DexID)
+	if d.ChainID != "" {
+		attribution += " (" + chainDisplayName(d.ChainID) + ")"
+	}
+	return attribution
+}
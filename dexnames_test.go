@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPairAttribution(t *testing.T) {
+	tests := []struct {
+		data MarketData
+		want string
+	}{
+		{MarketData{Pair: "WBTC/USDC", DexID: "uniswap", ChainID: "ethereum"}, "WBTC/USDC on Uniswap (Ethereum)"},
+		{MarketData{Pair: "CAKE/WBNB", DexID: "pancakeswap", ChainID: "bsc"}, "CAKE/WBNB on PancakeSwap (BNB Chain)"},
+		// Unknown IDs are capitalised rather than dropped
+		{MarketData{Pair: "TKN/WETH", DexID: "newdex", ChainID: "newchain"}, "TKN/WETH on Newdex (Newchain)"},
+		{MarketData{Pair: "TKN/WETH", DexID: "uniswap"}, "TKN/WETH on Uniswap"},
+		{MarketData{DexID: "uniswap", ChainID: "ethereum"}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.data.PairAttribution(); got != tt.want {
+			t.Errorf("PairAttribution(%+v) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestDexResultShowsPairAttribution(t *testing.T) {
+	// testchain has no GoPlus coverage, so no risk scan is attempted
+	pairs := `{"pairs":[{"chainId":"testchain","dexId":"uniswap","priceUsd":"1.00",
+		"baseToken":{"address":"0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","symbol":"TKN"},"quoteToken":{"symbol":"USDC"},"liquidity":{"usd":20000}}]}`
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"dexscreener": body(pairs),
+		"cmc":         body(cmcQuote("BTC", 60000)),
+	})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	response, _ := a.processTask(ctx, session{}, "/price 0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	if want := "- **Priced via:** TKN/USDC on Uniswap (Testchain)"; !strings.Contains(response, want) {
+		t.Errorf("DEX result is missing %q:\n%s", want, response)
+	}
+	if response, _ := a.processTask(ctx, session{}, "/price btc"); strings.Contains(response, "Priced via") {
+		t.Errorf("a CMC result shows a pair attribution:\n%s", response)
+	}
+}
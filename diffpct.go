@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// changeSpread compares two 24h changes, returning the leader, the laggard
+// and the spread in percentage points (always >= 0).
+func changeSpread(first performanceRow, second performanceRow) (leader, laggard performanceRow, spread float64) {
+	if second.change > first.change {
+		first, second = second, first
+	}
+	return first, second, first.change - second.change
+}
+
+// getDiffPct handles `/diffpct <symbol> <symbol>`, a quick relative-strength
+// check of two coins' 24h changes. Both rows come from one markets call.
+func (a *PMOAgent) getDiffPct(ctx context.Context, args []string, _ map[string]string) (string, error) {
+	if len(args) != 2 {
+		return withUsage("Please provide exactly two coins to compare.", "/diffpct"), nil
+	}
+	// Compare CoinGecko IDs so aliases such as "btc bitcoin" count as the same coin
+	if getCoinID(args[0]) == getCoinID(args[1]) {
+		return withUsage("Please provide two different coins.", "/diffpct"), nil
+	}
+
+	markets, message, err := a.getMarketsForSymbols(ctx, args, "usd")
+	if markets == nil {
+		return message, err
+	}
+
+	rows := make([]performanceRow, 2)
+	var missing []string
+	for i, symbol := range args {
+		rows[i].symbol = strings.ToUpper(symbol)
+		if market, ok := markets[strings.ToLower(symbol)]; ok {
+			rows[i].change, rows[i].hasValue = market.changeFor("24h")
+		}
+		if !rows[i].hasValue {
+			missing = append(missing, rows[i].symbol)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Sprintf("No 24h change data for %s on CoinGecko, so the two can't be compared.", strings.Join(missing, " or ")), nil
+	}
+
+	leader, laggard, spread := changeSpread(rows[0], rows[1])
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString("⚖️ **24h Relative Strength**\n")
+	if a.changeRoundsToZero(spread) {
+		responseBuilder.WriteString(fmt.Sprintf("- **%s** and **%s** are moving in lockstep today\n", leader.symbol, laggard.symbol))
+	} else {
+		responseBuilder.WriteString(fmt.Sprintf("- **%s** is outperforming **%s** by %s today\n", leader.symbol, laggard.symbol, a.formatChange(spread)))
+	}
+	responseBuilder.WriteString(fmt.Sprintf("- %s: %s | %s: %s\n", leader.symbol, a.formatSignedChange(leader.change), laggard.symbol, a.formatSignedChange(laggard.change)))
+	if message != "" {
+		responseBuilder.WriteString("\n" + message + "\n")
+	}
+	responseBuilder.WriteString("\n*(Data provided by COINGECKO)*")
+
+	return responseBuilder.String(), nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestChangeSpread(t *testing.T) {
+	tests := []struct {
+		first, second float64
+		leader        string
+		spread        float64
+	}{
+		{1.2, 3.0, "ETH", 1.8},
+		{3.0, 1.2, "BTC", 1.8},
+		{-2.5, 1.5, "ETH", 4},
+		{-1, -4, "BTC", 3},
+	}
+	for _, tt := range tests {
+		leader, _, spread := changeSpread(
+			performanceRow{symbol: "BTC", change: tt.first, hasValue: true},
+			performanceRow{symbol: "ETH", change: tt.second, hasValue: true},
+		)
+		if leader.symbol != tt.leader || math.Abs(spread-tt.spread) > 1e-9 {
+			t.Errorf("changeSpread(%v, %v) = %s, %v; want %s, %v", tt.first, tt.second, leader.symbol, spread, tt.leader, tt.spread)
+		}
+	}
+}
+
+func TestDiffPctCommand(t *testing.T) {
+	tests := []struct {
+		markets, want string
+	}{
+		{`[{"id":"bitcoin","price_change_percentage_24h_in_currency":1.2},{"id":"ethereum","price_change_percentage_24h_in_currency":3.0}]`,
+			"- **ETH** is outperforming **BTC** by 1.80% today"},
+		{`[{"id":"bitcoin","price_change_percentage_24h_in_currency":1.2},{"id":"ethereum","price_change_percentage_24h_in_currency":null}]`,
+			"No 24h change data for ETH on CoinGecko"},
+	}
+	for _, tt := range tests {
+		f := newFakeProviders(t, map[string]http.HandlerFunc{"coingecko": body(tt.markets)})
+		a := newTestAgent(t, f.env())
+
+		response, err := a.processTask(context.Background(), session{}, "/diffpct btc eth")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(response, tt.want) {
+			t.Errorf("response missing %q:\n%s", tt.want, response)
+		}
+	}
+}
+
+func TestDiffPctRejectsAliasesOfOneCoin(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": body(`[{"id":"bitcoin","price_change_percentage_24h_in_currency":1.2}]`),
+	})
+	a := newTestAgent(t, f.env())
+
+	response, _ := a.processTask(context.Background(), session{}, "/diffpct btc bitcoin")
+	if !strings.HasPrefix(response, "Please provide two different coins.") {
+		t.Errorf("/diffpct btc bitcoin = %q, want the different-coins usage", response)
+	}
+	if n := f.count("coingecko"); n != 0 {
+		t.Errorf("aliases of one coin made %d CoinGecko calls", n)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	minEMAPeriod = 2
+	maxEMAPeriod = 200
+)
+
+// calculateEMA returns the exponential moving average of values over period,
+// seeded with the simple average of the first period values and then
+// smoothed with k = 2 / (period + 1) across the rest of the series.
+func calculateEMA(values []float64, period int) (float64, error) {
+	if period < 1 {
+		return 0, fmt.Errorf("period must be positive, got %d", period)
+	}
+	if len(values) < period {
+		return 0, fmt.Errorf("need at least %d data points, have %d", period, len(values))
+	}
+
+	sum := 0.0
+	for _, v := range values[:period] {
+		sum += v
+	}
+	ema := sum / float64(period)
+
+	k := 2 / float64(period+1)
+	for _, v := range values[period:] {
+		ema = v*k + ema*(1-k)
+	}
+	return ema, nil
+}
+
+// getEMA handles `/ema <symbol> <period>`, comparing the latest daily price
+// against its N-day EMA as a lightweight trend signal.
+func (a *PMOAgent) getEMA(ctx context.Context, args []string, _ map[string]string) (string, error) {
+	period, err := strconv.Atoi(args[1])
+	if err != nil || period < minEMAPeriod || period > maxEMAPeriod {
+		return withUsage(fmt.Sprintf("Please provide a period between %d and %d days.", minEMAPeriod, maxEMAPeriod), "/ema"), nil
+	}
+
+	// Fetch twice the period so the EMA has time to settle after the SMA seed
+	coinID := getCoinID(args[0])
+	chart, message, err := a.getMarketChart(ctx, coinID, period*2)
+	if chart == nil {
+		return message, err
+	}
+
+	points := chart.points()
+	prices := make([]float64, len(points))
+	for i, p := range points {
+		prices[i] = p.price
+	}
+
+	ema, err := calculateEMA(prices, period)
+	if err != nil {
+		return fmt.Sprintf("Not enough price history for a %d-day EMA of %s (%d data points available).", period, strings.ToUpper(args[0]), len(prices)), nil
+	}
+
+	current := prices[len(prices)-1]
+	signal := "🟢 above its EMA (bullish)"
+	if current < ema {
+		signal = "🔴 below its EMA (bearish)"
+	}
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString(fmt.Sprintf("📉 **%s %d-Day EMA**\n", strings.ToUpper(args[0]), period))
+	responseBuilder.WriteString(fmt.Sprintf("- **Current Price:** %s\n", formatPrice(current, "usd")))
+	responseBuilder.WriteString(fmt.Sprintf("- **EMA(%d):** %s\n", period, formatPrice(ema, "usd")))
+	responseBuilder.WriteString(fmt.Sprintf("- **Signal:** Price is %s\n", signal))
+	responseBuilder.WriteString("\n*(Data provided by COINGECKO)*")
+
+	return responseBuilder.String(), nil
+}
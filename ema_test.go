@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCalculateEMA(t *testing.T) {
+	tests := []struct {
+		values []float64
+		period int
+		want   float64
+	}{
+		// Exactly one period is just the SMA seed
+		{[]float64{2, 4, 6}, 3, 4},
+		// Seed SMA(1,2,3) = 2, then k = 0.5 walks it up by one per point
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 3, 9},
+		// k = 2/6 for period 5: seed 10, then 22*k + 10*(1-k) = 14
+		{[]float64{10, 10, 10, 10, 10, 22}, 5, 14},
+	}
+	for _, tt := range tests {
+		got, err := calculateEMA(tt.values, tt.period)
+		if err != nil {
+			t.Fatalf("calculateEMA(%v, %d): %v", tt.values, tt.period, err)
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("calculateEMA(%v, %d) = %v, want %v", tt.values, tt.period, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateEMAValidatesPeriod(t *testing.T) {
+	if _, err := calculateEMA([]float64{1, 2}, 3); err == nil {
+		t.Error("a period longer than the series was accepted")
+	}
+	if _, err := calculateEMA([]float64{1, 2}, 0); err == nil {
+		t.Error("a zero period was accepted")
+	}
+}
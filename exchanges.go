@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultExchangeCount = 10
+	maxExchangeCount     = 50
+)
+
+// --- CoinGecko Exchange Structs (For /exchanges) ---
+type CoinGeckoExchange struct {
+	ID                string  `json:"id"`
+	Name              string  `json:"name"`
+	Country           string  `json:"country"`
+	TrustScore        int     `json:"trust_score"`
+	TrustScoreRank    int     `json:"trust_score_rank"`
+	TradeVolume24hBTC float64 `json:"trade_volume_24h_btc"`
+}
+
+// getTopExchanges lists the top exchanges by 24h BTC-denominated trade volume.
+// An optional first argument changes how many are shown.
+func (a *PMOAgent) getTopExchanges(ctx context.Context, args []string, _ map[string]string) (string, error) {
+	count := defaultExchangeCount
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > maxExchangeCount {
+			return withUsage(fmt.Sprintf("Please provide a count between 1 and %d.", maxExchangeCount), "/exchanges"), nil
+		}
+		count = n
+	}
+
+	// CoinGecko orders this endpoint by trust rank, so fetch a full page and sort by volume ourselves
+	req, err := a.newCoinGeckoRequest(ctx, "/exchanges?per_page=100&page=1")
+	if err != nil {
+		slog.Error("Error creating CG exchanges request", "err", err)
+		return "Error creating HTTP request.", err
+	}
+
+	var exchanges []CoinGeckoExchange
+	status, err := a.fetchJSON(req, &exchanges)
+	if errors.Is(err, errResponseTooLarge) {
+		return "Error: CoinGecko response too large.", err
+	}
+	if status == 0 {
+		return "Error contacting CoinGecko API.", err
+	}
+	if status != http.StatusOK {
+		slog.Warn("CoinGecko exchanges API returned an error status", "status", status)
+		return fmt.Sprintf("Error: CoinGecko API returned status %d. Could not load exchanges.", status), nil
+	}
+	if err != nil {
+		return "Error processing CG API response.", err
+	}
+
+	if len(exchanges) == 0 {
+		return "CoinGecko returned no exchanges.", nil
+	}
+
+	sort.SliceStable(exchanges, func(i, j int) bool {
+		return exchanges[i].TradeVolume24hBTC > exchanges[j].TradeVolume24hBTC
+	})
+	if len(exchanges) > count {
+		exchanges = exchanges[:count]
+	}
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString(fmt.Sprintf("🏦 **Top %d Exchanges by 24h Volume**\n", len(exchanges)))
+	for i, ex := range exchanges {
+		responseBuilder.WriteString(fmt.Sprintf("%d. **%s** — %s BTC (Trust Score: %d/10)\n", i+1, ex.Name, formatQuantity(ex.TradeVolume24hBTC), ex.TrustScore))
+	}
+	responseBuilder.WriteString("\n*(Data provided by COINGECKO)*")
+
+	return responseBuilder.String(), nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Fear & Greed Index (/fear) ---
+
+const (
+	minFearDays = 1
+	maxFearDays = 90
+)
+
+// fearTrendThreshold is how many index points the period must move before
+// sentiment counts as improving or worsening rather than steady.
+const fearTrendThreshold = 5
+
+// sparkLevels renders values as a one-line bar chart, lowest to highest.
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// FearGreedResponse is alternative.me's /fng/ response. Values and
+// timestamps are strings, and entries are newest first.
+type FearGreedResponse struct {
+	Data []struct {
+		Value          string `json:"value"`
+		Classification string `json:"value_classification"`
+		Timestamp      string `json:"timestamp"`
+	} `json:"data"`
+}
+
+// fearReading is one parsed day of the index.
+type fearReading struct {
+	time           time.Time
+	value          int
+	classification string
+}
+
+// readings parses the response into chronological order, skipping entries
+// that aren't numbers.
+func (r FearGreedResponse) readings() []fearReading {
+	readings := make([]fearReading, 0, len(r.Data))
+	for i := len(r.Data) - 1; i >= 0; i-- {
+		d := r.Data[i]
+		value, err := strconv.Atoi(d.Value)
+		if err != nil {
+			continue
+		}
+		seconds, err := strconv.ParseInt(d.Timestamp, 10, 64)
+		if err != nil {
+			continue
+		}
+		readings = append(readings, fearReading{time: time.Unix(seconds, 0).UTC(), value: value, classification: d.Classification})
+	}
+	return readings
+}
+
+// sparkline draws index values (0-100) as block characters, so the same
+// height means the same sentiment whatever the period's range.
+func sparkline(readings []fearReading) string {
+	var b strings.Builder
+	for _, r := range readings {
+		level := r.value * len(sparkLevels) / 101
+		b.WriteRune(sparkLevels[max(0, min(level, len(sparkLevels)-1))])
+	}
+	return b.String()
+}
+
+// fearTrend summarises how sentiment moved between the first and last reading.
+func fearTrend(first, last fearReading) string {
+	switch delta := last.value - first.value; {
+	case delta >= fearTrendThreshold:
+		return fmt.Sprintf("sentiment improving (+%d)", delta)
+	case delta <= -fearTrendThreshold:
+		return fmt.Sprintf("sentiment worsening (%d)", delta)
+	default:
+		return "sentiment steady"
+	}
+}
+
+// getFearGreed fetches the last `days` readings of the Fear & Greed Index.
+// On failure it returns a human-readable message alongside the error (which may be nil).
+func (a *PMOAgent) getFearGreed(ctx context.Context, days int) (*FearGreedResponse, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/fng/?limit=%d", a.config.FearGreedBaseURL, days), nil)
+	if err != nil {
+		slog.Error("Error creating Fear & Greed request", "err", err)
+		return nil, "Error creating HTTP request.", err
+	}
+
+	var index FearGreedResponse
+	if message, err := a.fetchProviderJSON(req, &index, "Fear & Greed"); message != "" {
+		return nil, message, err
+	}
+	return &index, "", nil
+}
+
+// getFearIndex handles `/fear [days]`: today's Crypto Fear & Greed Index,
+// or with days the daily values over that period plus a sparkline and trend.
+func (a *PMOAgent) getFearIndex(ctx context.Context, args []string, _ map[string]string) (string, error) {
+	days := minFearDays
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < minFearDays || n > maxFearDays {
+			return withUsage(fmt.Sprintf("Please provide a number of days between %d and %d.", minFearDays, maxFearDays), "/fear"), nil
+		}
+		days = n
+	}
+
+	index, message, err := a.getFearGreed(ctx, days)
+	if index == nil {
+		return message, err
+	}
+	readings := index.readings()
+	if len(readings) == 0 {
+		return "The Fear & Greed API returned no readings.", nil
+	}
+	first, last := readings[0], readings[len(readings)-1]
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString("😨 **Crypto Fear & Greed Index**\n")
+	responseBuilder.WriteString(fmt.Sprintf("- **Today:** %d/100 (%s)\n", last.value, last.classification))
+	if len(readings) > 1 {
+		responseBuilder.WriteString(fmt.Sprintf("- **%s:** %d/100 (%s)\n", first.time.Format("2006-01-02"), first.value, first.classification))
+		responseBuilder.WriteString(fmt.Sprintf("- **Last %d Days:** `%s`\n", len(readings), sparkline(readings)))
+		responseBuilder.WriteString(fmt.Sprintf("- **Trend:** %s\n", fearTrend(first, last)))
+	}
+	responseBuilder.WriteString("\n*(Data provided by ALTERNATIVE.ME)*")
+
+	return responseBuilder.String(), nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fearHistory is three days of the index, newest first as alternative.me sends it.
+const fearHistory = `{"data":[
+	{"value":"70","value_classification":"Greed","timestamp":"1704240000"},
+	{"value":"n/a","value_classification":"","timestamp":"1704196800"},
+	{"value":"55","value_classification":"Neutral","timestamp":"1704153600"},
+	{"value":"40","value_classification":"Fear","timestamp":"1704067200"}]}`
+
+func TestFearReadingsAreChronological(t *testing.T) {
+	var index FearGreedResponse
+	if err := json.Unmarshal([]byte(fearHistory), &index); err != nil {
+		t.Fatal(err)
+	}
+
+	readings := index.readings()
+	if len(readings) != 3 {
+		t.Fatalf("got %d readings, want the unparseable one skipped: %v", len(readings), readings)
+	}
+	for i, want := range []int{40, 55, 70} {
+		if readings[i].value != want {
+			t.Errorf("reading %d = %d, want %d", i, readings[i].value, want)
+		}
+	}
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !readings[0].time.Equal(want) {
+		t.Errorf("first reading at %v, want %v", readings[0].time, want)
+	}
+	if got := sparkline(readings); got != "▄▅▆" {
+		t.Errorf("sparkline = %q, want ▄▅▆", got)
+	}
+	if got := sparkline([]fearReading{{value: 0}, {value: 100}}); got != "▁█" {
+		t.Errorf("sparkline of the extremes = %q, want ▁█", got)
+	}
+}
+
+func TestFearTrend(t *testing.T) {
+	tests := []struct {
+		first, last int
+		want        string
+	}{
+		{40, 70, "sentiment improving (+30)"},
+		{50, 45, "sentiment worsening (-5)"},
+		{50, 54, "sentiment steady"},
+	}
+	for _, tt := range tests {
+		if got := fearTrend(fearReading{value: tt.first}, fearReading{value: tt.last}); got != tt.want {
+			t.Errorf("fearTrend(%d → %d) = %q, want %q", tt.first, tt.last, got, tt.want)
+		}
+	}
+}
+
+func TestFearHistoryCommand(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"fng": func(w http.ResponseWriter, r *http.Request) {
+			if limit := r.URL.Query().Get("limit"); limit != "3" {
+				t.Errorf("limit = %q, want 3", limit)
+			}
+			respond(w, http.StatusOK, fearHistory)
+		},
+	})
+	env := f.env()
+	env["FEAR_GREED_BASE_URL"] = f.url + "/fng"
+	a := newTestAgent(t, env)
+	ctx := context.Background()
+
+	response, _ := a.processTask(ctx, session{}, "/fear 3")
+	for _, want := range []string{
+		"- **Today:** 70/100 (Greed)",
+		"- **2024-01-01:** 40/100 (Fear)",
+		"- **Last 3 Days:** `▄▅▆`",
+		"- **Trend:** sentiment improving (+30)",
+	} {
+		if !strings.Contains(response, want) {
+			t.Errorf("response is missing %q:\n%s", want, response)
+		}
+	}
+
+	for _, days := range []string{"0", "91", "week"} {
+		if response, _ := a.processTask(ctx, session{}, "/fear "+days); !strings.Contains(response, "between 1 and 90") {
+			t.Errorf("/fear %s = %q, want the range message", days, response)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"strings"
+)
+
+// --- Network Fee Estimates (/convert --fees) ---
+
+// nativeTransferGas is the gas used by a plain native-coin transfer on EVM chains.
+const nativeTransferGas = 21000
+
+// evmNativeChains maps EVM native coins to the Dexscreener chain ID whose
+// EVM_RPC_URLS node prices their gas. ETH uses mainnet, its costliest chain.
+var evmNativeChains = map[string]string{
+	"eth":   "ethereum",
+	"bnb":   "bsc",
+	"matic": "polygon",
+	"pol":   "polygon",
+	"avax":  "avalanche",
+	"ftm":   "fantom",
+}
+
+// withdrawalFees are typical exchange withdrawal fees in units of the coin
+// itself, using each coin's cheapest common network (e.g. TRC-20 for USDT).
+// They drift with exchange pricing but are fine for a rough net amount;
+// coins without an entry get no estimate.
+var withdrawalFees = map[string]float64{
+	"btc":   0.0002,
+	"eth":   0.001,
+	"bnb":   0.0005,
+	"sol":   0.01,
+	"xrp":   0.25,
+	"ada":   1,
+	"doge":  5,
+	"ltc":   0.001,
+	"trx":   1,
+	"dot":   0.1,
+	"avax":  0.01,
+	"matic": 0.1,
+	"usdt":  1,
+	"usdc":  1,
+	"dai":   1,
+}
+
+// withdrawalFee returns the typical withdrawal fee for a coin symbol.
+func withdrawalFee(symbol string) (float64, bool) {
+	fee, ok := withdrawalFees[strings.ToLower(symbol)]
+	return fee, ok
+}
+
+// gasFee estimates a native transfer's fee, in units of the coin itself,
+// from the current gas price on the coin's chain. It reports false for
+// non-EVM coins, chains without a configured RPC node, or a failed call.
+func (a *PMOAgent) gasFee(ctx context.Context, symbol string) (float64, bool) {
+	chain, ok := evmNativeChains[strings.ToLower(symbol)]
+	if !ok {
+		return 0, false
+	}
+	if _, configured := a.config.EVMRPCURLs[chain]; !configured {
+		return 0, false
+	}
+
+	result, message, err := a.callRPC(ctx, chain, "eth_gasPrice", []any{})
+	if message != "" {
+		slog.Warn("No gas price, falling back to the static fee", "chain", chain, "message", message, "err", err)
+		return 0, false
+	}
+	var hexPrice string
+	if err := json.Unmarshal(result, &hexPrice); err != nil {
+		slog.Warn("Unreadable gas price, falling back to the static fee", "chain", chain, "err", err)
+		return 0, false
+	}
+	return weiFee(hexPrice, nativeTransferGas)
+}
+
+// weiFee converts a hex gas price (in wei) and a gas amount to a fee in whole
+// coins. It reports false for a malformed price.
+func weiFee(hexPrice string, gas int64) (float64, bool) {
+	wei, ok := new(big.Int).SetString(strings.TrimPrefix(hexPrice, "0x"), 16)
+	if !ok || wei.Sign() < 0 {
+		return 0, false
+	}
+	fee, _ := new(big.Float).Quo(new(big.Float).SetInt(wei.Mul(wei, big.NewInt(gas))), big.NewFloat(1e18)).Float64()
+	return fee, true
+}
+
+// networkFee returns the fee estimate used by /convert --fees: the live gas
+// estimate for EVM native coins when an RPC node is configured, otherwise the
+// static withdrawal fee. live reports which one it is.
+func (a *PMOAgent) networkFee(ctx context.Context, symbol string) (fee float64, live, ok bool) {
+	if fee, ok := a.gasFee(ctx, symbol); ok {
+		return fee, true, true
+	}
+	fee, ok = withdrawalFee(symbol)
+	return fee, false, ok
+}
+
+// netAfterFee is the amount left after paying fee, floored at zero.
+func netAfterFee(amount, fee float64) float64 {
+	return max(amount-fee, 0)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNetAfterFee(t *testing.T) {
+	tests := []struct {
+		amount, fee, want float64
+	}{
+		{1, 0.001, 0.999},
+		{0.0005, 0.001, 0}, // the fee eats the whole amount
+		{10, 0, 10},
+	}
+	for _, tt := range tests {
+		if got := netAfterFee(tt.amount, tt.fee); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("netAfterFee(%v, %v) = %v, want %v", tt.amount, tt.fee, got, tt.want)
+		}
+	}
+}
+
+func TestWeiFee(t *testing.T) {
+	// 20 gwei * 21000 gas = 0.00042 ETH
+	fee, ok := weiFee("0x4a817c800", nativeTransferGas)
+	if !ok || math.Abs(fee-0.00042) > 1e-12 {
+		t.Errorf("weiFee = %v, %v; want 0.00042, true", fee, ok)
+	}
+	if _, ok := weiFee("0xzz", nativeTransferGas); ok {
+		t.Error("weiFee accepted a malformed price")
+	}
+}
+
+func TestNetworkFeeUsesLiveGasForEVMNatives(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var call struct {
+			Method string `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&call)
+		w.Header().Set("Content-Type", "application/json")
+		if call.Method != "eth_gasPrice" {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"message":"unexpected method"}}`))
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x4a817c800"}`))
+	}))
+	defer server.Close()
+	a := newTestAgent(t, map[string]string{"EVM_RPC_URLS": "ethereum=" + server.URL})
+
+	if fee, live, ok := a.networkFee(context.Background(), "ETH"); !ok || !live || math.Abs(fee-0.00042) > 1e-12 {
+		t.Errorf("networkFee(ETH) = %v, %v, %v; want 0.00042, live", fee, live, ok)
+	}
+	// No RPC node for BSC, so BNB falls back to the static table
+	if fee, live, ok := a.networkFee(context.Background(), "bnb"); !ok || live || fee != withdrawalFees["bnb"] {
+		t.Errorf("networkFee(bnb) = %v, %v, %v; want the static fee", fee, live, ok)
+	}
+	if _, _, ok := a.networkFee(context.Background(), "unknowncoin"); ok {
+		t.Error("networkFee returned an estimate for a coin without a known fee")
+	}
+}
+
+func TestConvertFeesShowFiatValue(t *testing.T) {
+	providers := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": body(`{"ethereum":{"usd":2000},"bitcoin":{"usd":40000}}`),
+	})
+	a := newTestAgent(t, providers.env())
+
+	tests := []struct {
+		input, want string
+	}{
+		// The static BTC fee is valued at the BTC price the conversion fetched
+		{"/convert 100 usd btc --fees", "- After ~0.0002 BTC ($8.00) withdrawal fee: **0.0023 BTC**"},
+		{"/convert 1 eth btc --fees", "- After ~0.0002 BTC ($8.00) withdrawal fee: **0.0498 BTC**"},
+	}
+	for _, tt := range tests {
+		response, err := a.processTask(context.Background(), session{}, tt.input)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.input, err)
+		}
+		if !strings.Contains(response, tt.want) {
+			t.Errorf("%s: response missing %q:\n%s", tt.input, tt.want, response)
+		}
+	}
+}
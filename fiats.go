@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// fiatBasket is the standard set of currencies /fiats prices a coin in.
+var fiatBasket = []string{"usd", "eur", "gbp", "jpy", "cny"}
+
+// fiatPrices returns 1 unit of the coin in every basket fiat, and the source
+// that priced it. CoinGecko covers the whole basket in one call; coins it
+// doesn't know fall back to a USD price from the provider chain, crossed into
+// the other fiats through CoinGecko's BTC rates.
+func (a *PMOAgent) fiatPrices(ctx context.Context, symbol string) (map[string]float64, string, error) {
+	coinID := getCoinID(symbol)
+	prices, err := a.getSimplePrices(ctx, []string{coinID}, fiatBasket)
+	if err == nil && len(prices[coinID]) == len(fiatBasket) {
+		return prices[coinID], "coingecko", nil
+	}
+	if err != nil {
+		slog.Error("CoinGecko fiat basket lookup failed", "id", coinID, "err", err)
+	}
+
+	result := a.lookupToken(ctx, symbol, map[string]string{"currency": "usd"})
+	if !result.found {
+		return nil, "", fmt.Errorf("no USD price for %s", symbol)
+	}
+	usdPrice, err := strconv.ParseFloat(parseRawOutput(result.raw)["price_value"], 64)
+	if err != nil || usdPrice <= 0 {
+		return nil, "", fmt.Errorf("no USD price for %s", symbol)
+	}
+
+	rates, err := a.getSimplePrices(ctx, []string{"bitcoin"}, fiatBasket)
+	if err != nil {
+		return nil, "", err
+	}
+	btc := rates["bitcoin"]
+	if btc["usd"] == 0 {
+		return nil, "", fmt.Errorf("no BTC/USD cross rate")
+	}
+
+	converted := make(map[string]float64, len(fiatBasket))
+	for _, fiat := range fiatBasket {
+		if rate, ok := btc[fiat]; ok {
+			converted[fiat] = usdPrice * rate / btc["usd"]
+		}
+	}
+	return converted, parseRawOutput(result.raw)["token_source"], nil
+}
+
+// getFiatPrices handles `/fiats <symbol>`, showing 1 unit of the coin in each
+// basket fiat as an aligned list.
+func (a *PMOAgent) getFiatPrices(ctx context.Context, args []string, _ map[string]string) (string, error) {
+	symbol := args[0]
+	prices, source, err := a.fiatPrices(ctx, symbol)
+	if err != nil {
+		slog.Error("Fiat basket failed", "symbol", symbol, "err", err)
+		return fmt.Sprintf("Could not price %s in fiat currencies. Please check the symbol.", strings.ToUpper(symbol)), nil
+	}
+
+	formatted := make([]string, len(fiatBasket))
+	width := 0
+	for i, fiat := range fiatBasket {
+		price, ok := prices[fiat]
+		if !ok {
+			formatted[i] = "n/a"
+		} else {
+			formatted[i] = formatPrice(price, fiat)
+		}
+		width = max(width, utf8.RuneCountInString(formatted[i]))
+	}
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString(fmt.Sprintf("💱 **1 %s in Major Fiats**\n```\n", strings.ToUpper(symbol)))
+	for i, fiat := range fiatBasket {
+		padding := strings.Repeat(" ", width-utf8.RuneCountInString(formatted[i]))
+		responseBuilder.WriteString(fmt.Sprintf("%s  %s%s\n", strings.ToUpper(fiat), padding, formatted[i]))
+	}
+	responseBuilder.WriteString("```\n")
+	if source == "coingecko" {
+		responseBuilder.WriteString("\n*(Data provided by COINGECKO)*")
+	} else {
+		responseBuilder.WriteString(fmt.Sprintf("\n*(Data provided by %s, converted via COINGECKO rates)*", strings.ToUpper(source)))
+	}
+
+	return responseBuilder.String(), nil
+}
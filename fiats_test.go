@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFiatsListsEveryBasketCurrency(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": body(`{"bitcoin":{"usd":60000,"eur":54000,"gbp":47000,"jpy":9000000,"cny":430000}}`),
+	})
+	a := newTestAgent(t, f.env())
+
+	response, err := a.processTask(context.Background(), session{}, "/fiats btc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := f.count("coingecko"); n != 1 {
+		t.Errorf("CoinGecko called %d times, want one call for the whole basket", n)
+	}
+	lines := fiatLines(response)
+	wants := []string{"USD", "$60,000.00", "EUR", "€54,000.00", "GBP", "£47,000.00", "JPY", "¥9,000,000.00", "CNY", "¥430,000.00"}
+	for i := 0; i < len(wants); i += 2 {
+		if !strings.Contains(response, wants[i]+"  ") || !strings.Contains(response, wants[i+1]) {
+			t.Errorf("response missing %s %s:\n%s", wants[i], wants[i+1], response)
+		}
+	}
+	for _, line := range lines[1:] {
+		if utf8.RuneCountInString(line) != utf8.RuneCountInString(lines[0]) {
+			t.Errorf("the list is not aligned:\n%s", response)
+			break
+		}
+	}
+}
+
+func TestFiatsCrossRatesForNonCoinGeckoCoins(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": body(cmcQuote("NEWCOIN", 10)),
+		"coingecko": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("ids") == "bitcoin" {
+				respond(w, http.StatusOK, `{"bitcoin":{"usd":60000,"eur":54000,"gbp":48000,"jpy":9000000,"cny":420000}}`)
+				return
+			}
+			respond(w, http.StatusOK, `{}`)
+		},
+	})
+	a := newTestAgent(t, f.env())
+
+	response, err := a.processTask(context.Background(), session{}, "/fiats newcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"$10.00", "€9.00", "£8.00", "¥1,500.00", "¥70.00", "converted via COINGECKO rates"} {
+		if !strings.Contains(response, want) {
+			t.Errorf("response missing %q:\n%s", want, response)
+		}
+	}
+}
+
+// fiatLines returns the lines of the /fiats code block.
+func fiatLines(response string) []string {
+	_, block, _ := strings.Cut(response, "```\n")
+	block, _, _ = strings.Cut(block, "```")
+	return strings.Split(strings.TrimSuffix(block, "\n"), "\n")
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// --- Response Formatters ---
+
+// Formatter renders one token's market data for an output target.
+type Formatter interface {
+	Format(data MarketData) string
+}
+
+// defaultFormat is used when neither --format nor OUTPUT_FORMAT is set.
+const defaultFormat = "markdown"
+
+// formatters is the registry of output formats selectable with --format=<name>
+// or OUTPUT_FORMAT. Adding an output target only takes an entry here.
+var formatters = map[string]Formatter{
+	"markdown": markdownFormatter{builtinMarketTemplate},
+	"csv":      csvFormatter{},
+	"json":     jsonFormatter{},
+}
+
+// formatNames lists the registered formats for error messages, e.g. "csv, json or markdown".
+func formatNames() string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// formatter picks the Formatter for a request: --format, then OUTPUT_FORMAT,
+// then markdown. Markdown uses the custom market template when one is set.
+func (a *PMOAgent) formatter(flags map[string]string) Formatter {
+	name := a.config.OutputFormat
+	if requested, ok := flags["format"]; ok {
+		name = strings.ToLower(requested)
+	}
+	if name == "" || name == defaultFormat {
+		if a.config.MarketTemplate != nil {
+			return markdownFormatter{a.config.MarketTemplate}
+		}
+		name = defaultFormat
+	}
+	if f, ok := formatters[name]; ok {
+		return f
+	}
+	return formatters[defaultFormat]
+}
+
+// formatOutput transforms the semicolon-separated response string into a
+// message using the formatter selected for the request.
+func (a *PMOAgent) formatOutput(rawOutput string, flags map[string]string) string {
+	data := parseMarketData(rawOutput)
+
+	// Nothing but the header would be shown, so say so plainly instead
+	if !data.HasData() {
+		if data.Source == "" {
+			return "⚠️ Data unavailable for this token."
+		}
+		return fmt.Sprintf("⚠️ Data unavailable for this token from %s.", strings.ToUpper(data.Source))
+	}
+
+	if a.wantsCompact(flags) {
+		data.compactFigures()
+	}
+	data.HideSource = a.hidesSource(flags)
+	data.ShowLogo = hasFlag(flags, "logo")
+	return a.formatter(flags).Format(data)
+}
+
+// hidesSource reports whether the "Data provided by" attribution should be
+// left out of price output: --nosource for this request, or HIDE_SOURCE.
+func (a *PMOAgent) hidesSource(flags map[string]string) bool {
+	return hasFlag(flags, "nosource") || a.config.HideSource
+}
+
+// wantsCompact reports whether large figures should be abbreviated: --compact
+// or --full for this request, otherwise COMPACT_NUMBERS.
+func (a *PMOAgent) wantsCompact(flags map[string]string) bool {
+	switch {
+	case hasFlag(flags, "full"):
+		return false
+	case hasFlag(flags, "compact"):
+		return true
+	}
+	return a.config.CompactNumbers
+}
+
+// csvColumns is the header row of the CSV format.
+var csvColumns = []string{"name", "source", "currency", "price", "change_24h", "market_cap", "volume_24h", "liquidity", "fdv", "contract_address"}
+
+// csvFormatter renders a header row and one data row. The price column is
+// the unrounded value so spreadsheets can use it directly.
+type csvFormatter struct{}
+
+// Format implements Formatter.
+func (csvFormatter) Format(data MarketData) string {
+	price := data.Price
+	if data.PriceValue != 0 {
+		price = strconv.FormatFloat(data.PriceValue, 'f', -1, 64)
+	}
+
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	writer.Write(csvColumns)
+	writer.Write([]string{data.Name, data.Source, data.Currency, price, data.Change24h, data.MarketCap, data.Volume24h, data.Liquidity, data.FDV, data.ContractAddress})
+	writer.Flush()
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+// jsonFormatter renders the same MarketData JSON as the HTTP API.
+type jsonFormatter struct{}
+
+// Format implements Formatter.
+func (jsonFormatter) Format(data MarketData) string {
+	encoded, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		slog.Error("Error encoding market data", "err", err)
+		return "Error processing market data."
+	}
+	return string(encoded)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFormatOutputWithOnlyTokenSource(t *testing.T) {
+	a := newTestAgent(t, nil)
+
+	if got, want := a.formatOutput("token_source:coingecko", map[string]string{}), "⚠️ Data unavailable for this token from COINGECKO."; got != want {
+		t.Errorf("formatOutput = %q, want %q", got, want)
+	}
+	if got, want := a.formatOutput("", map[string]string{}), "⚠️ Data unavailable for this token."; got != want {
+		t.Errorf("formatOutput of an empty response = %q, want %q", got, want)
+	}
+	// N/A supplies alone are not data either
+	if got := a.formatOutput("token_source:coingecko;circulating_supply:N/A", map[string]string{}); !strings.HasPrefix(got, "⚠️ Data unavailable") {
+		t.Errorf("formatOutput with only an N/A supply = %q, want the unavailable message", got)
+	}
+}
+
+func TestFormatOutputWithData(t *testing.T) {
+	a := newTestAgent(t, nil)
+
+	got := a.formatOutput("token_source:coingecko;current_price_usd:$1.00", map[string]string{})
+	if strings.Contains(got, "Data unavailable") || !strings.Contains(got, "$1.00") {
+		t.Errorf("formatOutput = %q, want the price rendered", got)
+	}
+}
+
+func TestCSVFormatter(t *testing.T) {
+	data := MarketData{
+		Name:       "Bitcoin, Inc",
+		Source:     "coingecko",
+		Currency:   "usd",
+		Price:      "$63,245.12",
+		PriceValue: 63245.1234,
+		Change24h:  "2.10%",
+		MarketCap:  "$1,200,000,000,000.00",
+	}
+	want := "name,source,currency,price,change_24h,market_cap,volume_24h,liquidity,fdv,contract_address\n" +
+		`"Bitcoin, Inc",coingecko,usd,63245.1234,2.10%,"$1,200,000,000,000.00",,,,`
+	if got := (csvFormatter{}).Format(data); got != want {
+		t.Errorf("csv =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatterSelection(t *testing.T) {
+	a := newTestAgent(t, map[string]string{"OUTPUT_FORMAT": "csv"})
+	raw := "token_source:cmc;name:Bitcoin;current_price_usd:$63,245.12;price_value:63245.12"
+
+	if got := a.formatOutput(raw, map[string]string{}); !strings.HasPrefix(got, "name,source,currency,price") {
+		t.Errorf("OUTPUT_FORMAT=csv rendered %q", got)
+	}
+	if got := a.formatOutput(raw, map[string]string{"format": "Markdown"}); !strings.HasPrefix(got, "💰 **Bitcoin Price & Market Overview**") {
+		t.Errorf("--format=Markdown rendered %q", got)
+	}
+	if got := a.formatOutput(raw, map[string]string{"format": "json"}); !strings.Contains(got, `"price_value": 63245.12`) {
+		t.Errorf("--format=json rendered %q", got)
+	}
+}
+
+func TestUnknownFormatIsRejected(t *testing.T) {
+	a := newTestAgent(t, nil)
+	response, _ := a.processTask(context.Background(), session{}, "/price btc --format=html")
+	if !strings.Contains(response, "Unknown format: html. Use --format=csv, json or markdown.") {
+		t.Errorf("response = %q, want the unknown format message", response)
+	}
+}
+
+func TestNoSourceFlagDropsFooter(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"cmc": body(cmcQuote("BTC", 60000))})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+	footer := "*(Data provided by COINMARKETCAP)*"
+
+	if response, _ := a.processTask(ctx, session{}, "/price btc"); !strings.Contains(response, footer) {
+		t.Errorf("footer missing by default:\n%s", response)
+	}
+	if response, _ := a.processTask(ctx, session{}, "/price btc --nosource"); strings.Contains(response, "Data provided by") || !strings.Contains(response, "$60,000.00") {
+		t.Errorf("--nosource response:\n%s", response)
+	}
+	// The table drops its Source column instead
+	if response, _ := a.processTask(ctx, session{}, "/price btc eth --table --nosource"); strings.Contains(response, "Source") || strings.Contains(response, "COINMARKETCAP") {
+		t.Errorf("--table --nosource still shows the source:\n%s", response)
+	}
+	// Machine formats keep the source as a field
+	if response, _ := a.processTask(ctx, session{}, "/price btc --nosource --format=json"); !strings.Contains(response, `"source": "coinmarketcap"`) {
+		t.Errorf("--format=json --nosource dropped the source field:\n%s", response)
+	}
+
+	env := f.env()
+	env["HIDE_SOURCE"] = "true"
+	a = newTestAgent(t, env)
+	if response, _ := a.processTask(ctx, session{}, "/price btc"); strings.Contains(response, "Data provided by") {
+		t.Errorf("HIDE_SOURCE=true still shows the footer:\n%s", response)
+	}
+}
+
+func TestLogoURLInOutput(t *testing.T) {
+	logo := "https://coin-images.coingecko.com/coins/images/1/large/bitcoin.png"
+	cg := strings.Replace(cgCoin("bitcoin", "btc", 60000), `"market_cap_rank":1,`, `"market_cap_rank":1,"image":{"large":"`+logo+`"},`, 1)
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": body(cg),
+		"cmc":       body(cmcQuote("BTC", 60000)),
+	})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	if response, _ := a.processTask(ctx, session{}, "/price btc --source=cg --format=json"); !strings.Contains(response, `"image": "`+logo+`"`) {
+		t.Errorf("JSON output is missing the logo URL:\n%s", response)
+	}
+	if response, _ := a.processTask(ctx, session{}, "/price btc --source=cg --logo"); !strings.Contains(response, "logo]("+logo+")") {
+		t.Errorf("--logo did not render the image:\n%s", response)
+	}
+	if response, _ := a.processTask(ctx, session{}, "/price btc --source=cg"); strings.Contains(response, logo) {
+		t.Errorf("markdown shows the logo without --logo:\n%s", response)
+	}
+	// CMC's quote has no image, so the field is left out
+	if response, _ := a.processTask(ctx, session{}, "/price btc --source=cmc --format=json"); strings.Contains(response, `"image"`) {
+		t.Errorf("CMC JSON output has an image field:\n%s", response)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Provider Health Summary ---
+
+// maxFailingSymbols caps how many symbols the periodic summary lists.
+const maxFailingSymbols = 5
+
+// providerHealth counts provider attempts and failures between summaries,
+// plus lifetime totals for /status. It is shared by all concurrent lookups,
+// so every access takes the lock.
+type providerHealth struct {
+	mu       sync.Mutex
+	attempts map[string]int
+	failures map[string]int
+	symbols  map[string]int // failed lookups per target, across providers
+
+	totalAttempts map[string]int // since startup; never reset
+	totalFailures map[string]int
+}
+
+func newProviderHealth() *providerHealth {
+	h := &providerHealth{
+		totalAttempts: make(map[string]int),
+		totalFailures: make(map[string]int),
+	}
+	h.reset()
+	return h
+}
+
+// reset clears the counters; callers must hold the lock (or own h exclusively).
+func (h *providerHealth) reset() {
+	h.attempts = make(map[string]int)
+	h.failures = make(map[string]int)
+	h.symbols = make(map[string]int)
+}
+
+// record notes one provider call for target.
+func (h *providerHealth) record(provider, target string, succeeded bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.attempts[provider]++
+	h.totalAttempts[provider]++
+	if !succeeded {
+		h.failures[provider]++
+		h.totalFailures[provider]++
+		h.symbols[strings.ToLower(target)]++
+	}
+}
+
+// totals returns the lifetime attempt and failure counts for provider.
+func (h *providerHealth) totals(provider string) (attempts, failures int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.totalAttempts[provider], h.totalFailures[provider]
+}
+
+// summary renders the counters gathered since the previous call and resets
+// them. It returns "" when no provider was called in the meantime, so quiet
+// periods don't fill the log.
+func (h *providerHealth) summary() string {
+	h.mu.Lock()
+	attempts, failures, symbols := h.attempts, h.failures, h.symbols
+	h.reset()
+	h.mu.Unlock()
+
+	if len(attempts) == 0 {
+		return ""
+	}
+
+	providers := make([]string, 0, len(attempts))
+	for provider := range attempts {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	rates := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		rates = append(rates, fmt.Sprintf("%s %d/%d failed (%.0f%%)", provider, failures[provider], attempts[provider],
+			float64(failures[provider])/float64(attempts[provider])*100))
+	}
+	line := "Provider health: " + strings.Join(rates, ", ")
+
+	if len(symbols) > 0 {
+		failing := make([]string, 0, len(symbols))
+		for symbol := range symbols {
+			failing = append(failing, symbol)
+		}
+		sort.Slice(failing, func(i, j int) bool {
+			if symbols[failing[i]] != symbols[failing[j]] {
+				return symbols[failing[i]] > symbols[failing[j]]
+			}
+			return failing[i] < failing[j]
+		})
+		if len(failing) > maxFailingSymbols {
+			failing = failing[:maxFailingSymbols]
+		}
+		for i, symbol := range failing {
+			failing[i] = fmt.Sprintf("%s (%d)", symbol, symbols[symbol])
+		}
+		line += "; top failing: " + strings.Join(failing, ", ")
+	}
+
+	return line
+}
+
+// logSummaries writes a summary every interval until the process exits.
+func (h *providerHealth) logSummaries(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if line := h.summary(); line != "" {
+			slog.Info(line)
+		}
+	}
+}
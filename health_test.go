@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestHealthSummaryAggregatesErrors(t *testing.T) {
+	h := newProviderHealth()
+	h.record("cmc", "BTC", true)
+	h.record("cmc", "fakecoin", false)
+	h.record("coingecko", "fakecoin", false)
+	h.record("coingecko", "pepe", false)
+	h.record("coingecko", "eth", true)
+
+	want := "Provider health: cmc 1/2 failed (50%), coingecko 2/3 failed (67%); top failing: fakecoin (2), pepe (1)"
+	if got := h.summary(); got != want {
+		t.Errorf("summary =\n%q\nwant\n%q", got, want)
+	}
+
+	// The summary resets the window but not the lifetime totals
+	if got := h.summary(); got != "" {
+		t.Errorf("summary after reset = %q, want empty", got)
+	}
+	if attempts, failures := h.totals("coingecko"); attempts != 3 || failures != 2 {
+		t.Errorf("totals = %d, %d; want 3, 2", attempts, failures)
+	}
+}
+
+func TestHealthSummaryCapsFailingSymbols(t *testing.T) {
+	h := newProviderHealth()
+	for i := range maxFailingSymbols + 3 {
+		h.record("cmc", fmt.Sprintf("coin%d", i), false)
+	}
+	want := "Provider health: cmc 8/8 failed (100%); top failing: coin0 (1), coin1 (1), coin2 (1), coin3 (1), coin4 (1)"
+	if got := h.summary(); got != want {
+		t.Errorf("summary =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestHealthRecordConcurrently(t *testing.T) {
+	h := newProviderHealth()
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				h.record("cmc", "btc", false)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if attempts, failures := h.totals("cmc"); attempts != 1000 || failures != 1000 {
+		t.Errorf("totals = %d, %d; want 1000, 1000", attempts, failures)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestAgent builds an agent from the environment, after applying env.
+// Every provider points at an unroutable address unless env overrides it,
+// so a test never reaches a real API by accident.
+func newTestAgent(t *testing.T, env map[string]string) *PMOAgent {
+	t.Helper()
+	for _, name := range []string{"CMC_BASE_URL", "COINGECKO_BASE_URL", "COINGECKO_PRO_BASE_URL", "DEXSCREENER_BASE_URL",
+		"BINANCE_BASE_URL", "GOPLUS_BASE_URL", "DEFILLAMA_BASE_URL", "DEFILLAMA_COINS_BASE_URL", "FEAR_GREED_BASE_URL"} {
+		t.Setenv(name, "http://127.0.0.1:1")
+	}
+	t.Setenv("CMC_API_KEY", "")
+	t.Setenv("COINGECKO_API_KEY", "")
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return NewPMOAgent(cfg)
+}
+
+// withCommand registers a command for the duration of the test.
+func withCommand(t *testing.T, name string, cmd command) {
+	t.Helper()
+	previous, existed := commands[name]
+	commands[name] = cmd
+	t.Cleanup(func() {
+		if existed {
+			commands[name] = previous
+		} else {
+			delete(commands, name)
+		}
+	})
+}
+
+// newJSONServer starts a test server that is closed when the test ends.
+func newJSONServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// respond writes a JSON body with the given status.
+func respond(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+}
+
+// cmcQuote is a CMC quotes/latest body for symbol priced in USD.
+func cmcQuote(symbol string, price float64) string {
+	return fmt.Sprintf(`{"status":{"error_code":0},"data":{%q:{"id":1,"name":%q,"symbol":%q,"cmc_rank":1,
+		"circulating_supply":19000000,"total_supply":21000000,
+		"quote":{"USD":{"price":%v,"volume_24h":1000000,"market_cap":%v,"percent_change_24h":2.5}}}}}`,
+		symbol, symbol, symbol, price, price*19000000)
+}
+
+// cgCoin is a CoinGecko /coins/{id} body priced in USD and EUR.
+func cgCoin(id, symbol string, usd float64) string {
+	return fmt.Sprintf(`{"id":%q,"symbol":%q,"name":%q,"market_cap_rank":1,"market_data":{
+		"current_price":{"usd":%v,"eur":%v},"price_change_percentage_24h":1.5,
+		"market_cap":{"usd":%v},"total_volume":{"usd":1000000},
+		"circulating_supply":19000000,"total_supply":21000000}}`,
+		id, symbol, id, usd, usd*0.9, usd*19000000)
+}
+
+// fakeProviders serves CMC, CoinGecko, Binance and Dexscreener from one test
+// server, each under its own path prefix, and counts the calls to each.
+// Providers without a handler answer 404.
+type fakeProviders struct {
+	url      string
+	handlers map[string]http.HandlerFunc
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newFakeProviders(t *testing.T, handlers map[string]http.HandlerFunc) *fakeProviders {
+	t.Helper()
+	f := &fakeProviders{handlers: handlers, calls: make(map[string]int)}
+	f.url = newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		name, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		f.mu.Lock()
+		f.calls[name]++
+		f.mu.Unlock()
+		handler, ok := f.handlers[name]
+		if !ok {
+			respond(w, http.StatusNotFound, `{"error":"not found"}`)
+			return
+		}
+		r.URL.Path = "/" + rest
+		handler(w, r)
+	}).URL
+	return f
+}
+
+// env points every provider at the fake and enables CMC.
+func (f *fakeProviders) env() map[string]string {
+	return map[string]string{
+		"CMC_API_KEY":          "test-key",
+		"CMC_BASE_URL":         f.url + "/cmc",
+		"COINGECKO_BASE_URL":   f.url + "/coingecko",
+		"BINANCE_BASE_URL":     f.url + "/binance",
+		"DEXSCREENER_BASE_URL": f.url + "/dexscreener",
+	}
+}
+
+// count returns how many requests a provider has received.
+func (f *fakeProviders) count(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[name]
+}
+
+// body returns a handler that always answers 200 with body.
+func body(b string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) { respond(w, http.StatusOK, b) }
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// --- Distance From Recent Highs ---
+
+// percentFrom returns how far price is from reference, as a signed
+// percentage (negative when below). It reports false for a missing reference.
+func percentFrom(price, reference float64) (float64, bool) {
+	if price <= 0 || reference <= 0 {
+		return 0, false
+	}
+	return (price - reference) / reference * 100, true
+}
+
+// fromWeekHighField returns the from_7d_high field for a CoinGecko coin:
+// the USD price against the highest point of its hourly 7-day USD sparkline,
+// or "" without a sparkline.
+func fromWeekHighField(priceUSD float64, sparklineUSD []float64) string {
+	high7d := 0.0
+	for _, p := range sparklineUSD {
+		high7d = max(high7d, p)
+	}
+	if distance, ok := percentFrom(priceUSD, high7d); ok {
+		return fmt.Sprintf(";from_7d_high:%.1f%%", min(distance, 0))
+	}
+	return ""
+}
+
+// fromATHField returns the from_ath field, comparing price and ath in the
+// display currency, or "" when the ATH is unknown.
+func fromATHField(price, ath float64) string {
+	if distance, ok := percentFrom(price, ath); ok {
+		return fmt.Sprintf(";from_ath:%.1f%%", min(distance, 0))
+	}
+	return ""
+}
+
+// dayRangeFields returns the high_24h, low_24h and range_position_24h fields
+// for providers that report the day's range, or "" when it is unknown. The
+// position is 0% at the low and 100% at the high; it is clamped because the
+// range and the last price are not always sampled at the same moment.
+func dayRangeFields(price, high, low float64, currency string) string {
+	if high <= 0 || low <= 0 || high < low {
+		return ""
+	}
+	fields := fmt.Sprintf(";high_24h:%s;low_24h:%s", formatPrice(high, currency), formatPrice(low, currency))
+	if high > low && price > 0 {
+		position := max(0, min((price-low)/(high-low), 1)) * 100
+		fields += fmt.Sprintf(";range_position_24h:%.0f%%", position)
+	}
+	return fields
+}
+
+// describeFromHigh renders a from_* field as e.g. "12.0% below 7-day high",
+// or "at 7-day high" when the price is at (or past) the reference.
+func describeFromHigh(distance, label string) string {
+	value, err := strconv.ParseFloat(strings.TrimSuffix(distance, "%"), 64)
+	if err != nil {
+		return ""
+	}
+	if value >= 0 {
+		return "at " + label
+	}
+	return fmt.Sprintf("%.1f%% below %s", -value, label)
+}
+
+// HighsSummary describes where the price sits relative to its recent highs,
+// e.g. "12.0% below 7-day high, 34.0% below ATH", or "" when neither is known.
+func (d MarketData) HighsSummary() string {
+	var parts []string
+	if d.From7dHigh != "" {
+		parts = append(parts, describeFromHigh(d.From7dHigh, "7-day high"))
+	}
+	if d.FromATH != "" {
+		parts = append(parts, describeFromHigh(d.FromATH, "ATH"))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFromWeekHighField(t *testing.T) {
+	tests := []struct {
+		name      string
+		priceUSD  float64
+		sparkline []float64
+		want      string
+	}{
+		{"below", 88, []float64{90, 100, 95}, ";from_7d_high:-12.0%"},
+		{"at the 7-day high", 100, []float64{90, 100}, ";from_7d_high:0.0%"},
+		{"no sparkline", 50, nil, ""},
+	}
+	for _, tt := range tests {
+		if got := fromWeekHighField(tt.priceUSD, tt.sparkline); got != tt.want {
+			t.Errorf("%s: fromWeekHighField = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFromATHField(t *testing.T) {
+	tests := []struct {
+		name       string
+		price, ath float64
+		want       string
+	}{
+		{"below", 66, 100, ";from_ath:-34.0%"},
+		{"past the ATH is shown as at it", 210, 200, ";from_ath:0.0%"},
+		{"unknown ATH", 50, 0, ""},
+	}
+	for _, tt := range tests {
+		if got := fromATHField(tt.price, tt.ath); got != tt.want {
+			t.Errorf("%s: fromATHField = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHighsSummary(t *testing.T) {
+	tests := []struct {
+		data MarketData
+		want string
+	}{
+		{MarketData{From7dHigh: "-12.0%", FromATH: "-34.0%"}, "12.0% below 7-day high, 34.0% below ATH"},
+		{MarketData{From7dHigh: "0.0%"}, "at 7-day high"},
+		{MarketData{FromATH: "-75.0%"}, "75.0% below ATH"},
+		{MarketData{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.data.HighsSummary(); got != tt.want {
+			t.Errorf("HighsSummary(%+v) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestPlainPriceShowsDistanceFromHighs(t *testing.T) {
+	cg := strings.Replace(cgCoin("bitcoin", "btc", 88), `"market_data":{`,
+		`"market_data":{"ath":{"usd":200},"sparkline_7d":{"price":[90,100,95]},`, 1)
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"coingecko": body(cg)})
+	a := newTestAgent(t, f.env())
+
+	response, _ := a.processTask(context.Background(), session{}, "/price btc --source=coingecko")
+	if want := "- **vs Highs:** currently 12.0% below 7-day high, 56.0% below ATH"; !strings.Contains(response, want) {
+		t.Errorf("response is missing %q:\n%s", want, response)
+	}
+	if n := f.count("coingecko"); n != 1 {
+		t.Errorf("CoinGecko called %d times, want the one /coins/{id} request", n)
+	}
+}
+
+func TestDayRangeFields(t *testing.T) {
+	tests := []struct {
+		price, high, low float64
+		want             string
+	}{
+		{60000, 62000, 58000, ";high_24h:$62,000.00;low_24h:$58,000.00;range_position_24h:50%"},
+		{61500, 62000, 58000, ";high_24h:$62,000.00;low_24h:$58,000.00;range_position_24h:88%"},
+		// A price that has moved past the range is clamped to its end
+		{63000, 62000, 58000, ";high_24h:$62,000.00;low_24h:$58,000.00;range_position_24h:100%"},
+		{1, 1, 1, ";high_24h:$1.00;low_24h:$1.00"},
+		{60000, 0, 58000, ""},
+		{60000, 58000, 62000, ""},
+	}
+	for _, tt := range tests {
+		if got := dayRangeFields(tt.price, tt.high, tt.low, "usd"); got != tt.want {
+			t.Errorf("dayRangeFields(%v, %v, %v) = %q, want %q", tt.price, tt.high, tt.low, got, tt.want)
+		}
+	}
+}
+
+func TestDayRangeRendersForProvidersThatHaveIt(t *testing.T) {
+	cg := strings.Replace(cgCoin("bitcoin", "btc", 60000), `"market_cap_rank":1,"market_data":{`,
+		`"market_cap_rank":1,"market_data":{"high_24h":{"usd":62000},"low_24h":{"usd":58000},`, 1)
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": body(cg),
+		"binance":   body(`{"symbol":"BTCUSDT","lastPrice":"60000","priceChangePercent":"1.0","highPrice":"61000","lowPrice":"59000","quoteVolume":"1000000"}`),
+		"cmc":       body(cmcQuote("BTC", 60000)),
+	})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	tests := map[string]string{
+		"coingecko": "- **24h Range:** $58,000.00 – $62,000.00 (price at 50% of the range)",
+		"binance":   "- **24h Range:** $59,000.00 – $61,000.00 (price at 50% of the range)",
+	}
+	for source, want := range tests {
+		if response, _ := a.processTask(ctx, session{}, "/price btc --source="+source); !strings.Contains(response, want) {
+			t.Errorf("%s response is missing %q:\n%s", source, want, response)
+		}
+	}
+	// CMC's quote has no 24h range, so the line is left out
+	if response, _ := a.processTask(ctx, session{}, "/price btc --source=cmc"); strings.Contains(response, "24h Range") {
+		t.Errorf("CMC response shows a 24h range:\n%s", response)
+	}
+}
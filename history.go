@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	minHistoryDays = 1
+	maxHistoryDays = 365
+)
+
+// historyCSV renders a price series as CSV with a header row, one row per
+// point: an RFC 3339 UTC timestamp and the unrounded USD price. caps, when
+// given, adds a market_cap column matched by timestamp (empty where unknown).
+func historyCSV(prices, caps []pricePoint) string {
+	header := []string{"timestamp", "price"}
+	capAt := make(map[int64]float64, len(caps))
+	for _, c := range caps {
+		capAt[c.time.UnixMilli()] = c.price
+	}
+	if caps != nil {
+		header = append(header, "market_cap")
+	}
+
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	writer.Write(header)
+	for _, p := range prices {
+		row := []string{p.time.Format(time.RFC3339), strconv.FormatFloat(p.price, 'f', -1, 64)}
+		if caps != nil {
+			marketCap := ""
+			if c, ok := capAt[p.time.UnixMilli()]; ok {
+				marketCap = strconv.FormatFloat(c, 'f', -1, 64)
+			}
+			row = append(row, marketCap)
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+// getHistory handles `/history <symbol> <days> [--mcap] [--csv]`, comparing
+// the price N days ago with today's. --mcap also reports how the market cap
+// moved, which says more than price for coins whose supply keeps changing.
+// --csv returns the whole series as CSV instead of a summary; like fetched
+// bodies, the export is held to MAX_RESPONSE_BYTES.
+func (a *PMOAgent) getHistory(ctx context.Context, args []string, flags map[string]string) (string, error) {
+	days, err := strconv.Atoi(args[1])
+	if err != nil || days < minHistoryDays || days > maxHistoryDays {
+		return withUsage(fmt.Sprintf("Please provide a number of days between %d and %d.", minHistoryDays, maxHistoryDays), "/history"), nil
+	}
+
+	coinID := getCoinID(args[0])
+	chart, message, err := a.getMarketChart(ctx, coinID, days)
+	if chart == nil {
+		return message, err
+	}
+
+	symbol := strings.ToUpper(args[0])
+	prices := chart.points()
+	if len(prices) < 2 {
+		return fmt.Sprintf("Not enough price history for %s over %d days.", symbol, days), nil
+	}
+	first, last := prices[0], prices[len(prices)-1]
+
+	if hasFlag(flags, "csv") {
+		var caps []pricePoint
+		if hasFlag(flags, "mcap") {
+			caps = chart.marketCapPoints()
+		}
+		export := historyCSV(prices, caps)
+		if int64(len(export)) > a.config.MaxResponseBytes {
+			return fmt.Sprintf("The %s CSV export over %d days is too large to send. Please request fewer days.", symbol, days), nil
+		}
+		return export, nil
+	}
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString(fmt.Sprintf("🕰 **%s over %d Days**\n", symbol, days))
+	responseBuilder.WriteString(fmt.Sprintf("- **Price on %s:** %s\n", first.time.Format("2006-01-02"), formatPrice(first.price, "usd")))
+	responseBuilder.WriteString(fmt.Sprintf("- **Price Now:** %s\n", formatPrice(last.price, "usd")))
+	if change, ok := percentFrom(last.price, first.price); ok {
+		responseBuilder.WriteString(fmt.Sprintf("- **Price Change:** %s\n", a.formatChange(change)))
+	}
+
+	if hasFlag(flags, "mcap") {
+		caps := chart.marketCapPoints()
+		if len(caps) < 2 {
+			responseBuilder.WriteString("- **Market Cap:** N/A (no market cap history)\n")
+		} else {
+			firstCap, lastCap := caps[0], caps[len(caps)-1]
+			responseBuilder.WriteString(fmt.Sprintf("- **Market Cap on %s:** %s\n", firstCap.time.Format("2006-01-02"), formatLargeCurrency(firstCap.price, "usd")))
+			responseBuilder.WriteString(fmt.Sprintf("- **Market Cap Now:** %s\n", formatLargeCurrency(lastCap.price, "usd")))
+			if change, ok := percentFrom(lastCap.price, firstCap.price); ok {
+				responseBuilder.WriteString(fmt.Sprintf("- **Market Cap Change:** %s\n", a.formatChange(change)))
+			}
+		}
+	}
+	responseBuilder.WriteString("\n*(Data provided by COINGECKO)*")
+
+	return responseBuilder.String(), nil
+}
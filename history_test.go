@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// historyChart is three days from 2024-01-01, with no market cap known on the first.
+const historyChart = `{
+	"prices":[[1704067200000,40000],[1704153600000,42000],[1704240000000,44000]],
+	"market_caps":[[1704067200000,0],[1704153600000,800000000000],[1704240000000,880000000000]]}`
+
+func TestMarketCapPoints(t *testing.T) {
+	var chart CoinGeckoMarketChart
+	if err := json.Unmarshal([]byte(historyChart), &chart); err != nil {
+		t.Fatal(err)
+	}
+
+	caps := chart.marketCapPoints()
+	if len(caps) != 2 {
+		t.Fatalf("got %d market cap points, want the zero cap skipped: %v", len(caps), caps)
+	}
+	if want := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC); !caps[0].time.Equal(want) || caps[0].price != 8e11 {
+		t.Errorf("first market cap point = %v, want 8e11 at %v", caps[0], want)
+	}
+	if caps[1].price != 8.8e11 {
+		t.Errorf("last market cap = %v, want 8.8e11", caps[1].price)
+	}
+	if got := len(chart.points()); got != 3 {
+		t.Errorf("got %d price points, want all 3", got)
+	}
+}
+
+func TestHistoryMarketCap(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"coingecko": body(historyChart)})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	response, _ := a.processTask(ctx, session{}, "/history btc 3 --mcap")
+	for _, want := range []string{"- **Market Cap on 2024-01-02:** $800.00B", "- **Market Cap Now:** $880.00B", "- **Market Cap Change:** 10.00%"} {
+		if !strings.Contains(response, want) {
+			t.Errorf("response is missing %q:\n%s", want, response)
+		}
+	}
+	if response, _ := a.processTask(ctx, session{}, "/history btc 3"); strings.Contains(response, "Market Cap") {
+		t.Errorf("market cap shown without --mcap:\n%s", response)
+	}
+
+	response, _ = a.processTask(ctx, session{}, "/history btc 3 --mcap --csv")
+	want := "timestamp,price,market_cap\n" +
+		"2024-01-01T00:00:00Z,40000,\n" +
+		"2024-01-02T00:00:00Z,42000,800000000000\n" +
+		"2024-01-03T00:00:00Z,44000,880000000000"
+	if response != want {
+		t.Errorf("CSV export = %q, want %q", response, want)
+	}
+}
+
+func TestHistoryCSVParsesBack(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"coingecko": body(historyChart)})
+	a := newTestAgent(t, f.env())
+
+	response, _ := a.processTask(context.Background(), session{}, "/history btc 3 --csv")
+	rows, err := csv.NewReader(strings.NewReader(response)).ReadAll()
+	if err != nil {
+		t.Fatalf("export is not valid CSV: %v\n%s", err, response)
+	}
+	want := [][]string{
+		{"timestamp", "price"},
+		{"2024-01-01T00:00:00Z", "40000"},
+		{"2024-01-02T00:00:00Z", "42000"},
+		{"2024-01-03T00:00:00Z", "44000"},
+	}
+	if fmt.Sprint(rows) != fmt.Sprint(want) {
+		t.Errorf("CSV rows = %v, want %v", rows, want)
+	}
+	for _, row := range rows[1:] {
+		if _, err := time.Parse(time.RFC3339, row[0]); err != nil {
+			t.Errorf("timestamp %q is not RFC 3339: %v", row[0], err)
+		}
+	}
+}
+
+func TestHistoryCSVRespectsResponseCap(t *testing.T) {
+	// Each CSV row is longer than its JSON point, so a body that fits the cap
+	// can still produce an export that doesn't.
+	points := make([]string, 20)
+	for i := range points {
+		points[i] = fmt.Sprintf("[%d,1]", int64(1704067200000)+int64(i)*86400000)
+	}
+	chart := `{"prices":[` + strings.Join(points, ",") + `]}`
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"coingecko": body(chart)})
+	env := f.env()
+	env["MAX_RESPONSE_BYTES"] = fmt.Sprint(len(chart) + 10)
+	a := newTestAgent(t, env)
+
+	response, _ := a.processTask(context.Background(), session{}, "/history btc 20 --csv")
+	if response != "The BTC CSV export over 20 days is too large to send. Please request fewer days." {
+		t.Errorf("oversized export response = %q", response)
+	}
+}
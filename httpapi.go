@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// --- HTTP API (HTTP_API_PORT) ---
+
+// apiError is the JSON body of every non-200 HTTP API response.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// apiHandler serves the REST API. Lookups go through the same provider chain
+// and cache as chat commands.
+func (a *PMOAgent) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /price", a.handleAPIPrice)
+	return mux
+}
+
+// handleAPIPrice serves `GET /price?symbol=btc&currency=usd`, returning the
+// MarketData JSON for a symbol or contract address. Clients share the
+// session throttle with chat rooms, keyed by their address.
+func (a *PMOAgent) handleAPIPrice(w http.ResponseWriter, r *http.Request) {
+	client := apiThrottleKey(r)
+	if ok, _ := a.throttle.allow(client, 1); !ok {
+		slog.Warn("Throttling API client", "client", client)
+		writeJSON(w, http.StatusTooManyRequests, apiError{"you're sending requests too fast"})
+		return
+	}
+
+	query := r.URL.Query()
+	symbol := strings.TrimSpace(query.Get("symbol"))
+	if symbol == "" {
+		writeJSON(w, http.StatusBadRequest, apiError{"missing symbol parameter"})
+		return
+	}
+
+	flags := make(map[string]string)
+	if currency := strings.ToLower(strings.TrimSpace(query.Get("currency"))); currency != "" {
+		if !isFiat(currency) {
+			writeJSON(w, http.StatusBadRequest, apiError{fmt.Sprintf("unsupported currency: %s", currency)})
+			return
+		}
+		flags["currency"] = currency
+	}
+
+	result := a.lookupToken(r.Context(), symbol, flags)
+	if !result.found {
+		// An error means the providers failed, not that the token is unknown
+		if result.err != nil {
+			slog.Error("API lookup failed", "symbol", symbol, "err", result.err)
+			writeJSON(w, http.StatusBadGateway, apiError{result.output})
+			return
+		}
+		writeJSON(w, http.StatusNotFound, apiError{result.output})
+		return
+	}
+	writeJSON(w, http.StatusOK, parseMarketData(result.raw))
+}
+
+// apiThrottleKey is the throttle session for an HTTP API request: the
+// client's IP, so opening new connections doesn't reset its budget.
+func apiThrottleKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "http:" + host
+}
+
+// writeJSON writes body as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("Error writing API response", "err", err)
+	}
+}
+
+// serveHTTPAPI runs the REST API on port until the process exits.
+func (a *PMOAgent) serveHTTPAPI(port int) {
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           a.apiHandler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		// Allow for a full provider failover plus retries before giving up on the write
+		WriteTimeout: 4*a.config.HTTPTimeout + 5*time.Second,
+	}
+	slog.Info("HTTP API listening", "port", port)
+	if err := server.ListenAndServe(); err != nil {
+		slog.Error("HTTP API stopped", "err", err)
+	}
+}
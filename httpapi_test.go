@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIPriceReturnsMarketData(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			convert := r.URL.Query().Get("convert")
+			respond(w, http.StatusOK, strings.Replace(cmcQuote("BTC", 60000), `"USD"`, `"`+convert+`"`, 1))
+		},
+	})
+	a := newTestAgent(t, f.env())
+	server := httptest.NewServer(a.apiHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/price?symbol=btc&currency=EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var data MarketData
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Source != "coinmarketcap" || data.Currency != "eur" || data.Price != "€60,000.00" || data.PriceValue != 60000 {
+		t.Errorf("MarketData = %+v", data)
+	}
+}
+
+func TestAPIPriceErrors(t *testing.T) {
+	f := newFakeProviders(t, nil)
+	a := newTestAgent(t, f.env())
+	handler := a.apiHandler()
+
+	tests := []struct {
+		target string
+		status int
+		error  string
+	}{
+		{"/price", http.StatusBadRequest, "missing symbol parameter"},
+		{"/price?symbol=btc&currency=doge", http.StatusBadRequest, "unsupported currency: doge"},
+		{"/price?symbol=fakecoin", http.StatusNotFound, "Could not find market data for fakecoin"},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", tt.target, nil))
+
+		var body apiError
+		json.Unmarshal(recorder.Body.Bytes(), &body)
+		if recorder.Code != tt.status || !strings.Contains(body.Error, tt.error) {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.target, recorder.Code, body.Error, tt.status, tt.error)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/price?symbol=btc", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /price = %d, want 405", recorder.Code)
+	}
+}
+
+func TestAPIPriceSeparatesOutagesFromUnknownTokens(t *testing.T) {
+	down := func(w http.ResponseWriter, r *http.Request) { respond(w, http.StatusServiceUnavailable, `{}`) }
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"cmc": down, "coingecko": down, "dexscreener": down})
+	env := f.env()
+	env["RETRY_BUDGET_PER_MINUTE"] = "0"
+	handler := newTestAgent(t, env).apiHandler()
+
+	tests := []struct {
+		target string
+		status int
+		error  string
+	}{
+		{"/price?symbol=btc", http.StatusBadGateway, "Could not reach any market data provider for btc"},
+		{"/price?symbol=0x6b175474e89094c44da98b954eedeac495271d0f", http.StatusBadGateway, "Error fetching DEX data."},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", tt.target, nil))
+
+		var body apiError
+		json.Unmarshal(recorder.Body.Bytes(), &body)
+		if recorder.Code != tt.status || !strings.Contains(body.Error, tt.error) {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.target, recorder.Code, body.Error, tt.status, tt.error)
+		}
+	}
+
+	// One provider answering "unknown" is enough for a 404
+	f = newFakeProviders(t, map[string]http.HandlerFunc{"cmc": down})
+	env = f.env()
+	env["RETRY_BUDGET_PER_MINUTE"] = "0"
+	recorder := httptest.NewRecorder()
+	newTestAgent(t, env).apiHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/price?symbol=fakecoin", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("unknown token with one provider down = %d, want 404", recorder.Code)
+	}
+}
+
+func TestAPIPriceThrottlesFlood(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) { respond(w, http.StatusOK, cmcQuote("BTC", 60000)) },
+	})
+	env := f.env()
+	env["SESSION_REQUESTS_PER_MINUTE"] = "3"
+	env["CACHE_TTL_SECONDS"] = "0"
+	a := newTestAgent(t, env)
+	handler := a.apiHandler()
+
+	get := func(remoteAddr string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/price?symbol=btc", nil)
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// A new source port is the same client
+	for i := range 3 {
+		if recorder := get(fmt.Sprintf("203.0.113.7:%d", 40000+i)); recorder.Code != http.StatusOK {
+			t.Fatalf("request %d within the limit = %d %s", i+1, recorder.Code, recorder.Body)
+		}
+	}
+	recorder := get("203.0.113.7:41000")
+	var body apiError
+	json.Unmarshal(recorder.Body.Bytes(), &body)
+	if recorder.Code != http.StatusTooManyRequests || body.Error != "you're sending requests too fast" {
+		t.Errorf("flooding request = %d %q, want 429", recorder.Code, body.Error)
+	}
+	if got := f.count("cmc"); got != 3 {
+		t.Errorf("upstream calls = %d, want 3 (throttled requests must not reach providers)", got)
+	}
+	if recorder := get("198.51.100.2:40000"); recorder.Code != http.StatusOK {
+		t.Errorf("another client was throttled: %d", recorder.Code)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// getTokenInfo handles `/info <symbol>`, combining CoinGecko's descriptive
+// metadata for a coin. Fields the coin doesn't have are simply omitted.
+func (a *PMOAgent) getTokenInfo(ctx context.Context, args []string, _ map[string]string) (string, error) {
+	if len(args) == 0 || strings.TrimSpace(args[0]) == "" {
+		return usageFor("/info"), nil
+	}
+
+	coinID := getCoinID(args[0])
+	path := fmt.Sprintf("/coins/%s?localization=false&tickers=false&market_data=false&community_data=false&developer_data=false&sparkline=false", coinID)
+
+	req, err := a.newCoinGeckoRequest(ctx, path)
+	if err != nil {
+		slog.Error("Error creating CG info request", "err", err)
+		return "Error creating HTTP request.", err
+	}
+
+	var coin CoinGeckoResponse
+	status, err := a.fetchJSON(req, &coin)
+	if errors.Is(err, errResponseTooLarge) {
+		return "Error: CoinGecko response too large.", err
+	}
+	if status == 0 {
+		return "Error contacting CoinGecko API.", err
+	}
+	if status != http.StatusOK {
+		slog.Warn("CoinGecko API returned an error status", "status", status, "id", coinID)
+		return fmt.Sprintf("Could not find token info for %s on CoinGecko.", args[0]), nil
+	}
+	if err != nil {
+		return "Error processing CG API response.", err
+	}
+	if coin.Error != "" || coin.ID == "" {
+		slog.Warn("CoinGecko returned an error body", "id", coinID, "error", coin.Error)
+		return fmt.Sprintf("Could not find token info for %s on CoinGecko.", args[0]), nil
+	}
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString(fmt.Sprintf("ℹ️ **%s (%s) Token Info**\n", coin.Name, strings.ToUpper(coin.Symbol)))
+
+	if categories := nonEmpty(coin.Categories); len(categories) > 0 {
+		responseBuilder.WriteString(fmt.Sprintf("- **Categories:** %s\n", strings.Join(categories, ", ")))
+	}
+
+	if homepages := nonEmpty(coin.Links.Homepage); len(homepages) > 0 {
+		responseBuilder.WriteString(fmt.Sprintf("- **Website:** %s\n", homepages[0]))
+	}
+
+	if coin.GenesisDate != "" {
+		responseBuilder.WriteString(fmt.Sprintf("- **Launch Date:** %s\n", coin.GenesisDate))
+	}
+
+	// Native coins report an empty platform entry, so only list real contracts
+	var chains []string
+	for chain, address := range coin.Platforms {
+		if chain != "" && address != "" {
+			chains = append(chains, chain)
+		}
+	}
+	if len(chains) > 0 {
+		sort.Strings(chains)
+		responseBuilder.WriteString("- **Contracts:**\n")
+		for _, chain := range chains {
+			responseBuilder.WriteString(fmt.Sprintf("  - %s: `%s`\n", chain, coin.Platforms[chain]))
+		}
+	}
+
+	responseBuilder.WriteString("\n*(Data provided by COINGECKO)*")
+	return responseBuilder.String(), nil
+}
+
+// nonEmpty returns the non-blank entries of values.
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
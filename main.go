@@ -3,20 +3,96 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"math"
+	"mime"
 	"net/http" // Needed for CMC URL encoding
 	"os"
+	"reflect"
+	"runtime/debug"
+	"slices"
 	"strconv" // Needed for Dexscreener price parsing
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
 
 	"github.com/TeneoProtocolAI/teneo-agent-sdk/pkg/agent"
-	"github.com/joho/godotenv"
+	"github.com/TeneoProtocolAI/teneo-agent-sdk/pkg/types"
 	"golang.org/x/text/message"
 )
 
 // Agent Handler Struct
-type PMOAgent struct{}
+type PMOAgent struct {
+	config       *AppConfig
+	client       *http.Client
+	retryBudget  *retryBudget
+	cache        *responseCache
+	marketsCache *responseCache // /coins/markets lists, JSON-encoded
+	protocols    protocolList   // DefiLlama's /protocols list, for /tvl
+	health       *providerHealth
+	notifiers    []Notifier
+	watches      *watchRegistry
+	throttle     *sessionThrottle
+	cexProviders []priceProvider
+	// sourceProviders are every enabled CEX provider, in or out of the
+	// failover chain, for --source to pick from
+	sourceProviders map[string]priceProvider
+	dexProvider     priceProvider
+
+	// coinGeckoPro is set once the API key turns out to be a Pro key
+	coinGeckoPro atomic.Bool
+
+	startedAt time.Time // for the /status uptime
+}
+
+// NewPMOAgent builds the agent handler from a loaded configuration.
+func NewPMOAgent(cfg *AppConfig) *PMOAgent {
+	a := &PMOAgent{
+		config: cfg,
+		client: &http.Client{
+			Timeout:       cfg.HTTPTimeout,
+			CheckRedirect: redirectPolicy(cfg.FollowRedirects),
+			Transport:     userAgentTransport{userAgent: cfg.UserAgent, next: http.DefaultTransport},
+		},
+		retryBudget:  newRetryBudget(cfg.RetryBudgetPerMinute),
+		cache:        newResponseCache(cfg.CacheTTL, cfg.CacheMaxStale),
+		marketsCache: newResponseCache(min(cfg.CacheTTL, marketsCacheTTL), cfg.CacheMaxStale), // CACHE_TTL=0 disables it too
+		health:       newProviderHealth(),
+		watches:      newWatchRegistry(),
+		throttle:     newSessionThrottle(cfg.SessionRequestsPerMinute, sessionThrottleWindow),
+		startedAt:    time.Now(),
+	}
+
+	a.notifiers = newNotifiers(cfg, a.client)
+
+	a.dexProvider = priceProvider{name: "dexscreener", lookup: a.getDexData}
+	available := map[string]priceProvider{
+		"cmc": {name: "cmc", lookup: a.getCMCData},
+		"coingecko": {name: "coingecko", lookup: func(ctx context.Context, target, currency string) (string, error) {
+			return a.getCoinGeckoData(ctx, getCoinID(target), currency)
+		}, exactLookup: a.getMappedCoinGeckoData},
+		"binance": {name: "binance", lookup: a.getBinanceData},
+	}
+	// CMC requires a key; operators who omit it simply run without that provider
+	if cfg.CMCAPIKey == "" {
+		slog.Warn("CMC_API_KEY not set, CoinMarketCap provider disabled")
+		delete(available, "cmc")
+	}
+	a.sourceProviders = available
+	for _, name := range cfg.ProviderOrder {
+		if provider, ok := available[name]; ok {
+			a.cexProviders = append(a.cexProviders, provider)
+		}
+	}
+
+	return a
+}
 
 // --- CoinGecko Maps (Needed for CG Symbol resolution) ---
 // This map helps convert simple symbols to CoinGecko's full ID string
@@ -33,23 +109,72 @@ var coinIDMap = map[string]string{
 	"uni":   "uniswap",
 	"matic": "polygon",
 	"ltc":   "litecoin",
+	"usdt":  "tether", // stablecoins priced by --vs
+	"usdc":  "usd-coin",
+	"dai":   "dai",
 	// Keep this list short, as we rely on CMC first
 }
 
 // --- CoinGecko Structs (For CG Failover) ---
 type CoinGeckoResponse struct {
-	ID         string `json:"id"`
-	Symbol     string `json:"symbol"`
-	Name       string `json:"name"`
+	ID          string    `json:"id"`
+	Symbol      string    `json:"symbol"`
+	Name        string    `json:"name"`
+	LastUpdated time.Time `json:"last_updated"`
+
+	MarketCapRank int `json:"market_cap_rank"`
+
+	// Error is set when CoinGecko answers 200 with {"error":"..."} (e.g. unknown IDs)
+	Error string `json:"error"`
+
+	// Descriptive metadata (populated when the coin endpoint is not trimmed, see /info)
+	Categories  []string          `json:"categories"`
+	Platforms   map[string]string `json:"platforms"`
+	GenesisDate string            `json:"genesis_date"`
+	Links       struct {
+		Homepage []string `json:"homepage"`
+	} `json:"links"`
+	Image struct {
+		Large string `json:"large"` // Logo URL, 250×250
+	} `json:"image"`
+
+	// Thinly traded coins can have null maps, or null values inside them, so
+	// amounts are optional rather than silently defaulting to 0
 	MarketData struct {
-		CurrentPrice             map[string]float64 `json:"current_price"`
-		PriceChangePercentage24h float64            `json:"price_change_percentage_24h"`
-		MarketCap                map[string]float64 `json:"market_cap"`
-		CirculatingSupply        float64            `json:"circulating_supply"`
-		TotalSupply              float64            `json:"total_supply"`
+		CurrentPrice             optionalAmounts `json:"current_price"`
+		PriceChangePercentage24h *float64        `json:"price_change_percentage_24h"`
+		PriceChange24hInCurrency optionalAmounts `json:"price_change_percentage_24h_in_currency"`
+		MarketCap                optionalAmounts `json:"market_cap"`
+		TotalVolume              optionalAmounts `json:"total_volume"`
+		High24h                  optionalAmounts `json:"high_24h"`
+		Low24h                   optionalAmounts `json:"low_24h"`
+		CirculatingSupply        float64         `json:"circulating_supply"`
+		TotalSupply              float64         `json:"total_supply"`
+		ATH                      optionalAmounts `json:"ath"`
+		Sparkline7d              struct {
+			Price []float64 `json:"price"` // Hourly USD prices over the last 7 days
+		} `json:"sparkline_7d"`
 	} `json:"market_data"`
 }
 
+// optionalAmounts is a per-currency CoinGecko figure where a missing key and
+// a null value both mean "unknown".
+type optionalAmounts map[string]*float64
+
+// lookup returns the amount for a currency, reporting false when unknown.
+func (m optionalAmounts) lookup(currency string) (float64, bool) {
+	if amount := m[currency]; amount != nil {
+		return *amount, true
+	}
+	return 0, false
+}
+
+// value returns the amount for a currency, or 0 when unknown.
+func (m optionalAmounts) value(currency string) float64 {
+	amount, _ := m.lookup(currency)
+	return amount
+}
+
 // --- CoinMarketCap (CMC) Structs (Primary CEX Lookup) ---
 type CMCResponse struct {
 	Status struct {
@@ -60,19 +185,39 @@ type CMCResponse struct {
 }
 
 type CMCData struct {
-	ID                int     `json:"id"`
-	Name              string  `json:"name"`
-	Symbol            string  `json:"symbol"`
-	CirculatingSupply float64 `json:"circulating_supply"`
-	TotalSupply       float64 `json:"total_supply"`
-	Quote             struct {
-		USD struct {
-			Price            float64 `json:"price"`
-			Volume24h        float64 `json:"volume_24h"`
-			MarketCap        float64 `json:"market_cap"`
-			PercentChange24h float64 `json:"percent_change_24h"`
-		} `json:"USD"`
-	} `json:"quote"`
+	ID                int       `json:"id"`
+	Name              string    `json:"name"`
+	Symbol            string    `json:"symbol"`
+	CirculatingSupply float64   `json:"circulating_supply"`
+	TotalSupply       float64   `json:"total_supply"`
+	CMCRank           int       `json:"cmc_rank"`
+	LastUpdated       time.Time `json:"last_updated"`
+	// Platform is the chain a token is issued on; it is null for native coins
+	Platform *CMCPlatform `json:"platform"`
+	// Quote is keyed by the requested convert currency, e.g. "USD" or "EUR"
+	Quote map[string]CMCQuote `json:"quote"`
+}
+
+type CMCPlatform struct {
+	Name         string `json:"name"`
+	TokenAddress string `json:"token_address"`
+}
+
+type CMCQuote struct {
+	Price            float64 `json:"price"`
+	Volume24h        float64 `json:"volume_24h"`
+	MarketCap        float64 `json:"market_cap"`
+	PercentChange24h float64 `json:"percent_change_24h"`
+}
+
+// --- Binance Structs (CEX Last Resort) ---
+type BinanceTicker struct {
+	Symbol             string `json:"symbol"`
+	LastPrice          string `json:"lastPrice"`
+	PriceChangePercent string `json:"priceChangePercent"`
+	HighPrice          string `json:"highPrice"`
+	LowPrice           string `json:"lowPrice"`
+	QuoteVolume        string `json:"quoteVolume"`
 }
 
 // --- Dexscreener Structs (DEX Lookup) ---
@@ -82,12 +227,29 @@ type DexscreenerResponse struct {
 
 type DexPair struct {
 	ChainID     string  `json:"chainId"`
+	DexID       string  `json:"dexId"` // e.g. "uniswap"
 	PairAddress string  `json:"pairAddress"`
 	BaseToken   Token   `json:"baseToken"`
 	QuoteToken  Token   `json:"quoteToken"`
 	PriceUsd    string  `json:"priceUsd"`
+	PriceNative string  `json:"priceNative"` // Base token price in quote token units
 	Volume      Volume  `json:"volume"`
 	FDV         float64 `json:"fdv"`
+
+	// Liquidity is omitted by Dexscreener for some pools
+	Liquidity *Liquidity `json:"liquidity"`
+}
+
+type Liquidity struct {
+	USD float64 `json:"usd"`
+}
+
+// liquidityUSD returns the pool's USD liquidity, or 0 when unknown.
+func (p DexPair) liquidityUSD() float64 {
+	if p.Liquidity == nil {
+		return 0
+	}
+	return p.Liquidity.USD
 }
 
 type Token struct {
@@ -103,19 +265,381 @@ type Volume struct {
 	M5  float64 `json:"m5"`
 }
 
+// --- Shared HTTP Fetching ---
+
+var (
+	errResponseTooLarge      = errors.New("response too large")
+	errUnexpectedContentType = errors.New("unexpected content type")
+	errUnexpectedRedirect    = errors.New("unexpected redirect")
+	errTruncatedBody         = errors.New("truncated response body")
+	errUpstreamStatus        = errors.New("upstream error status")
+)
+
+// statusError returns errUpstreamStatus for rate limits and server errors, so
+// callers can tell a provider outage from a token the provider doesn't know.
+// Any other status returns nil.
+func statusError(status int) error {
+	if status == http.StatusTooManyRequests || status >= http.StatusInternalServerError {
+		return fmt.Errorf("%w %d", errUpstreamStatus, status)
+	}
+	return nil
+}
+
+// maxRedirects bounds redirect chains when FOLLOW_REDIRECTS is enabled.
+const maxRedirects = 3
+
+// redirectPolicy returns the http.Client CheckRedirect hook for the configured
+// policy. By default redirects are not followed: the 3xx response is handed
+// back to fetchJSON, which reports it as errUnexpectedRedirect.
+func redirectPolicy(follow bool) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if !follow {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("%w: stopped after %d redirects", errUnexpectedRedirect, maxRedirects)
+		}
+		return nil
+	}
+}
+
+// userAgentTransport identifies the agent on every outbound request, since
+// some providers throttle or block clients without a User-Agent.
+type userAgentTransport struct {
+	userAgent string
+	next      http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper. A User-Agent already set on the
+// request is kept.
+func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.userAgent == "" || req.Header.Get("User-Agent") != "" {
+		return t.next.RoundTrip(req)
+	}
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.next.RoundTrip(req)
+}
+
+// isJSONContentType accepts application/json, text/json and +json media types.
+// A missing header is tolerated since some gateways strip it.
+func isJSONContentType(header string) bool {
+	if header == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// fetchJSON sends the request and decodes the JSON body into target.
+// The body is read through an io.LimitReader so a misbehaving provider cannot
+// exhaust memory. Non-200 bodies are decoded on a best-effort basis so callers
+// can still inspect provider error payloads; the status code is always returned.
+// Transient failures are retried with backoff while the global retry budget allows.
+func (a *PMOAgent) fetchJSON(req *http.Request, target interface{}) (int, error) {
+	for attempt := 0; ; attempt++ {
+		status, err := a.fetchJSONOnce(req, target)
+
+		// A Pro key is rejected by the demo API; try the Pro tier and remember it if accepted
+		if proReq := a.coinGeckoProRetry(req, status); proReq != nil {
+			req = proReq
+			// Drop whatever the rejected demo error body decoded into target
+			if v := reflect.ValueOf(target); v.Kind() == reflect.Pointer && !v.IsNil() {
+				v.Elem().SetZero()
+			}
+			status, err = a.fetchJSONOnce(req, target)
+			if status != 0 && status != http.StatusUnauthorized && status != http.StatusForbidden && !a.coinGeckoPro.Swap(true) {
+				slog.Info("CoinGecko Pro API key detected, using the Pro API from now on")
+			}
+		}
+
+		retryable := isRetryable(err, status)
+		// A truncated body is usually a dropped connection, worth exactly one more try
+		if errors.Is(err, errTruncatedBody) {
+			retryable = attempt == 0
+		}
+		if attempt >= maxFetchRetries || !retryable {
+			return status, err
+		}
+		if !a.retryBudget.allow() {
+			slog.Warn("Retry budget exhausted, not retrying", "host", req.URL.Host, "status", status)
+			return status, err
+		}
+		slog.Warn("Retrying request", "host", req.URL.Host, "status", status, "attempt", attempt+1, "err", err)
+		if !waitForRetry(req.Context(), attempt) {
+			return status, err
+		}
+	}
+}
+
+// responseLimit is the body size cap for req: DEFILLAMA_MAX_RESPONSE_BYTES
+// for the DefiLlama API, MAX_RESPONSE_BYTES for everything else.
+func (a *PMOAgent) responseLimit(req *http.Request) int64 {
+	if strings.HasPrefix(req.URL.String(), a.config.DefiLlamaBaseURL+"/") {
+		return a.config.DefiLlamaMaxResponseBytes
+	}
+	return a.config.MaxResponseBytes
+}
+
+// fetchProviderJSON fetches req into target and turns the outcome into the
+// user-facing message used for a failed provider call: too large, no response,
+// an error status or an undecodable body. The message is "" on success. Only
+// GETs are retried, since a POST body can't be replayed.
+func (a *PMOAgent) fetchProviderJSON(req *http.Request, target interface{}, providerName string) (string, error) {
+	fetch := a.fetchJSON
+	if req.Method != http.MethodGet {
+		fetch = a.fetchJSONOnce
+	}
+
+	status, err := fetch(req, target)
+	if errors.Is(err, errResponseTooLarge) {
+		return fmt.Sprintf("Error: %s response too large.", providerName), err
+	}
+	if status == 0 {
+		return fmt.Sprintf("Error contacting %s API.", providerName), err
+	}
+	if status != http.StatusOK {
+		slog.Warn("Provider returned an error status", "provider", providerName, "status", status, "path", req.URL.Path)
+		return fmt.Sprintf("Error: %s API returned status %d.", providerName, status), nil
+	}
+	if err != nil {
+		return fmt.Sprintf("Error processing %s response.", providerName), err
+	}
+	return "", nil
+}
+
+// fetchJSONOnce performs a single attempt of fetchJSON.
+func (a *PMOAgent) fetchJSONOnce(req *http.Request, target interface{}) (int, error) {
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		slog.Warn("Redirect not followed", "host", req.URL.Host, "location", resp.Header.Get("Location"))
+		return resp.StatusCode, fmt.Errorf("%w: %s returned status %d", errUnexpectedRedirect, req.URL.Host, resp.StatusCode)
+	}
+
+	// An HTML error page served with a 200 would otherwise surface as a confusing decode error
+	if contentType := resp.Header.Get("Content-Type"); resp.StatusCode == http.StatusOK && !isJSONContentType(contentType) {
+		slog.Warn("Non-JSON response", "host", req.URL.Host, "content_type", contentType)
+		return resp.StatusCode, fmt.Errorf("%w: %s returned %q", errUnexpectedContentType, req.URL.Host, contentType)
+	}
+
+	// Read one byte past the cap so we can tell "exactly at the limit" from "over it"
+	maxBytes := a.responseLimit(req)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return resp.StatusCode, fmt.Errorf("%w: %s closed the connection mid-body", errTruncatedBody, req.URL.Host)
+	}
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if int64(len(body)) > maxBytes {
+		slog.Warn("Response too large", "host", req.URL.Host, "max_bytes", maxBytes)
+		return resp.StatusCode, fmt.Errorf("%w: %s returned more than %d bytes", errResponseTooLarge, req.URL.Host, maxBytes)
+	}
+
+	if err := json.Unmarshal(body, target); err != nil && resp.StatusCode == http.StatusOK {
+		// JSON that simply stops is a cut-off transfer; anything else is a real schema mismatch
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) && syntaxErr.Offset == int64(len(body)) {
+			return resp.StatusCode, fmt.Errorf("%w: %s: %v", errTruncatedBody, req.URL.Host, err)
+		}
+		return resp.StatusCode, err
+	}
+
+	return resp.StatusCode, nil
+}
+
 // --- Helper Functions ---
 
+// maxPriceDecimals caps how far formatPrice will expand tiny prices.
+const maxPriceDecimals = 18
+
 func getCoinID(input string) string {
 	lowerInput := strings.ToLower(input)
 	if id, ok := coinIDMap[lowerInput]; ok {
 		return id
 	}
-	return lowerInput
+	// Quoted names like "shiba inu" map onto CoinGecko's hyphenated IDs
+	return strings.Join(strings.Fields(lowerInput), "-")
 }
 
-func formatCurrency(amount float64) string {
+// normalizeInput drops invisible formatting characters (zero-width spaces,
+// byte order marks, direction marks) that text copied from chat clients often
+// carries, so they can't end up glued to a command, symbol or flag. Tabs,
+// non-breaking spaces and runs of spaces are already plain whitespace to
+// tokenizeInput and batchLines.
+func normalizeInput(input string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, input)
+}
+
+// tokenizeInput splits the raw task on whitespace like strings.Fields, but keeps
+// double-quoted sections together so `/price "shiba inu"` yields one argument.
+// An unterminated quote simply runs to the end of the input.
+func tokenizeInput(input string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+	hasToken := false
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasToken = true
+		case unicode.IsSpace(r) && !inQuotes:
+			if hasToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				hasToken = false
+			}
+		default:
+			current.WriteRune(r)
+			hasToken = true
+		}
+	}
+	if hasToken {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens
+}
+
+// currencySymbols maps fiat codes to their customary symbol. Codes without a
+// widely recognised symbol are rendered with the ISO code appended instead.
+var currencySymbols = map[string]string{
+	"usd": "$",
+	"eur": "€",
+	"gbp": "£",
+	"jpy": "¥",
+	"cny": "¥",
+	"krw": "₩",
+	"inr": "₹",
+	"rub": "₽",
+	"try": "₺",
+	"brl": "R$",
+	"cad": "C$",
+	"aud": "A$",
+	"hkd": "HK$",
+	"sgd": "S$",
+	"mxn": "MX$",
+}
+
+// withCurrency decorates an already formatted number with the currency's
+// symbol (e.g. "€1,234.56"), or its ISO code for exotic currencies
+// (e.g. "1,234.56 CHF"). An empty currency means USD.
+func withCurrency(number string, currency string) string {
+	currency = strings.ToLower(currency)
+	if currency == "" {
+		currency = "usd"
+	}
+
+	symbol, ok := currencySymbols[currency]
+	if !ok {
+		return number + " " + strings.ToUpper(currency)
+	}
+	if strings.HasPrefix(number, "-") {
+		return "-" + symbol + number[1:]
+	}
+	return symbol + number
+}
+
+func formatCurrency(amount float64, currency string) string {
 	p := message.NewPrinter(message.MatchLanguage("en"))
-	return p.Sprintf("$%.2f", amount)
+	return withCurrency(p.Sprintf("%.2f", amount), currency)
+}
+
+// largeNumberUnits are the suffixes used by formatLargeNumber, smallest first.
+var largeNumberUnits = []struct {
+	suffix string
+	size   float64
+}{{"K", 1e3}, {"M", 1e6}, {"B", 1e9}, {"T", 1e12}}
+
+// formatLargeNumber abbreviates a number with a K/M/B/T suffix and two
+// decimals, e.g. 1234567890 -> "1.23B". Values below 1,000 keep the usual
+// two-decimal formatting. A value that would round up to 1000 of one unit is
+// shown in the next one, so 999,999,999 is "1.00B" rather than "1000.00M".
+func formatLargeNumber(value float64) string {
+	unit := -1
+	for i, u := range largeNumberUnits {
+		if math.Abs(value) >= u.size {
+			unit = i
+		}
+	}
+	if unit < 0 {
+		return message.NewPrinter(message.MatchLanguage("en")).Sprintf("%.2f", value)
+	}
+
+	scaled := value / largeNumberUnits[unit].size
+	if math.Abs(math.Round(scaled*100)/100) >= 1000 && unit < len(largeNumberUnits)-1 {
+		unit++
+		scaled = value / largeNumberUnits[unit].size
+	}
+	return strconv.FormatFloat(scaled, 'f', 2, 64) + largeNumberUnits[unit].suffix
+}
+
+// formatLargeCurrency is formatCurrency with a K/M/B/T suffix, e.g. "$1.23T".
+func formatLargeCurrency(amount float64, currency string) string {
+	return withCurrency(formatLargeNumber(amount), currency)
+}
+
+// formatPrice formats a per-unit price, keeping four significant digits for
+// sub-unit values so tiny prices (e.g. "1.2e-9" from Dexscreener) are not
+// flattened to $0.00 by the fixed two-decimal currency format.
+func formatPrice(amount float64, currency string) string {
+	if amount == 0 || math.Abs(amount) >= 1 {
+		return formatCurrency(amount, currency)
+	}
+
+	decimals := int(-math.Floor(math.Log10(math.Abs(amount)))) + 3
+	if decimals > maxPriceDecimals {
+		decimals = maxPriceDecimals
+	}
+
+	formatted := strconv.FormatFloat(amount, 'f', decimals, 64)
+	formatted = strings.TrimRight(formatted, "0")
+	// Keep at least cents precision, e.g. $0.50 rather than $0.5
+	if dot := strings.IndexByte(formatted, '.'); len(formatted)-dot-1 < 2 {
+		formatted += strings.Repeat("0", 2-(len(formatted)-dot-1))
+	}
+
+	return withCurrency(formatted, currency)
+}
+
+// formatChange formats a percentage change with CHANGE_DECIMALS decimals.
+// Values that round to zero are shown unsigned rather than as "-0.00%".
+func (a *PMOAgent) formatChange(percent float64) string {
+	if a.changeRoundsToZero(percent) {
+		percent = 0
+	}
+	return fmt.Sprintf("%.*f%%", a.config.ChangeDecimals, percent)
+}
+
+// formatSignedChange is formatChange with a leading "+" on gains, for lists
+// that show moves side by side (e.g. "+2.10%" next to "-1.30%").
+func (a *PMOAgent) formatSignedChange(percent float64) string {
+	formatted := a.formatChange(percent)
+	if a.changeRoundsToZero(percent) || percent < 0 {
+		return formatted
+	}
+	return "+" + formatted
+}
+
+// changeRoundsToZero reports whether a change is too small to show at CHANGE_DECIMALS.
+func (a *PMOAgent) changeRoundsToZero(percent float64) bool {
+	return math.Abs(percent) < 0.5*math.Pow10(-a.config.ChangeDecimals)
 }
 
 func formatQuantity(quantity float64) string {
@@ -126,331 +650,1362 @@ func formatQuantity(quantity float64) string {
 	return p.Sprintf("%.0f", quantity)
 }
 
-// --- NEW Helper Function ---
+// priceValueField returns the unrounded price as a price_value field, for
+// consumers such as --raw that need the number rather than the display string.
+func priceValueField(price float64) string {
+	return ";price_value:" + strconv.FormatFloat(price, 'f', -1, 64)
+}
 
-// formatOutput transforms the semicolon-separated response string into a readable message.
-func formatOutput(rawOutput string) string {
-	parts := make(map[string]string)
+// stalenessFields returns the last_updated field (and stale_for when the
+// timestamp is older than the configured threshold) to append to a provider
+// response. Providers without a timestamp pass the zero time and get nothing.
+func (a *PMOAgent) stalenessFields(lastUpdated time.Time) string {
+	if lastUpdated.IsZero() {
+		return ""
+	}
 
-	// 1. Parse the semicolon string into a map
-	pairs := strings.Split(rawOutput, ";")
-	for _, pair := range pairs {
+	fields := ";last_updated:" + lastUpdated.UTC().Format(time.RFC3339)
+	if age := time.Since(lastUpdated); a.config.StaleThreshold > 0 && age > a.config.StaleThreshold {
+		fields += ";stale_for:" + formatAge(age)
+	}
+	return fields
+}
+
+// formatAge renders a duration coarsely for humans, e.g. "25m" or "3h 5m".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
+
+// truncateAddress shortens a long contract address to its first 6 and last 4
+// characters (e.g. 0xC02a…6Cc2), which is enough to eyeball it against a
+// block explorer without flooding the message.
+func truncateAddress(address string) string {
+	if len(address) <= 12 {
+		return address
+	}
+	return address[:6] + "…" + address[len(address)-4:]
+}
+
+// parseRawOutput splits a semicolon-separated provider response into its key:value fields.
+func parseRawOutput(rawOutput string) map[string]string {
+	parts := make(map[string]string)
+	for _, pair := range strings.Split(rawOutput, ";") {
 		kv := strings.SplitN(pair, ":", 2)
 		if len(kv) == 2 {
 			parts[kv[0]] = kv[1]
 		}
 	}
+	return parts
+}
 
-	// 2. Identify the source and get key data points
-	source := parts["token_source"]
-	price := parts["current_price_usd"]
-	change := parts["24h_change"]
-
-	// The CMC response contains the full name, which is ideal
-	tokenName := parts["name"]
-	if tokenName == "" {
-		tokenName = "Token" // Fallback if name is missing
+// responseCurrency returns the currency a raw provider response is quoted in.
+func responseCurrency(rawOutput string) string {
+	if currency := parseRawOutput(rawOutput)["currency"]; currency != "" {
+		return currency
 	}
+	return "usd"
+}
 
-	// 3. Start building the human-readable response
-	var responseBuilder strings.Builder
-
-	// Add the source and token name
-	responseBuilder.WriteString(fmt.Sprintf("💰 **%s Price & Market Overview**\n", tokenName))
+// parseDisplayNumber recovers the numeric value from a formatted amount such
+// as "$63,245.12", "€0.0012", "1,234.50 CHF" or "-2.10%". Our formatters never
+// emit exponents, so everything except digits, '.' and '-' is discarded.
+func parseDisplayNumber(display string) (float64, bool) {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) || r == '.' || r == '-' {
+			return r
+		}
+		return -1
+	}, display)
+	if cleaned == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
 
-	// Add current price
-	responseBuilder.WriteString(fmt.Sprintf("- **Price (USD):** %s\n", price))
+// --- API Logic Functions ---
 
-	// Add 24-hour change with proper color emoji
-	changeFloat, err := strconv.ParseFloat(strings.TrimSuffix(change, "%"), 64)
-	if err == nil {
-		if changeFloat >= 0 {
-			responseBuilder.WriteString(fmt.Sprintf("- **24h Change:** **🟢 +%s**\n", change))
-		} else {
-			responseBuilder.WriteString(fmt.Sprintf("- **24h Change:** **🔴 %s**\n", change))
-		}
-	} else if change != "" {
-		// Fallback for unparseable change, just print the raw string
-		responseBuilder.WriteString(fmt.Sprintf("- **24h Change:** %s\n", change))
+// newCoinGeckoRequest builds a GET request for a CoinGecko API path (including
+// any query string), attaching the demo API key when one is configured.
+func (a *PMOAgent) newCoinGeckoRequest(ctx context.Context, path string) (*http.Request, error) {
+	if a.coinGeckoPro.Load() {
+		return a.newCoinGeckoProRequest(ctx, path)
 	}
 
-	// Add Market Cap (available from CEX APIs)
-	if marketCap, ok := parts["market_cap_usd"]; ok && marketCap != "" {
-		responseBuilder.WriteString(fmt.Sprintf("- **Market Cap:** %s\n", marketCap))
+	req, err := http.NewRequestWithContext(ctx, "GET", a.config.CoinGeckoBaseURL+path, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add Volume (available from Dexscreener)
-	if volume, ok := parts["volume_24h"]; ok && volume != "" {
-		responseBuilder.WriteString(fmt.Sprintf("- **24h Volume:** %s\n", volume))
+	if a.config.CoinGeckoAPIKey != "" {
+		req.Header.Set("x-cg-demo-api-key", a.config.CoinGeckoAPIKey)
 	}
 
-	// Add FDV (available from Dexscreener)
-	if fdv, ok := parts["fdv"]; ok && fdv != "" {
-		responseBuilder.WriteString(fmt.Sprintf("- **Fully Diluted Value (FDV):** %s\n", fdv))
+	return req, nil
+}
+
+// newCoinGeckoProRequest builds the same request against the Pro API.
+func (a *PMOAgent) newCoinGeckoProRequest(ctx context.Context, path string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.config.CoinGeckoProBaseURL+path, nil)
+	if err != nil {
+		return nil, err
 	}
+	req.Header.Set("x-cg-pro-api-key", a.config.CoinGeckoAPIKey)
+	return req, nil
+}
 
-	// Add Circulating Supply
-	if supply, ok := parts["circulating_supply"]; ok && supply != "N/A" && supply != "" {
-		responseBuilder.WriteString(fmt.Sprintf("- **Circulating Supply:** %s\n", supply))
+// coinGeckoProRetry returns a Pro API copy of req when the demo API rejected
+// its key (401/403), which is what happens when a Pro key is configured. It
+// returns nil for every other request or outcome.
+func (a *PMOAgent) coinGeckoProRetry(req *http.Request, status int) *http.Request {
+	if status != http.StatusUnauthorized && status != http.StatusForbidden {
+		return nil
+	}
+	if a.config.CoinGeckoAPIKey == "" || req.Header.Get("x-cg-demo-api-key") == "" {
+		return nil
+	}
+	path, ok := strings.CutPrefix(req.URL.String(), a.config.CoinGeckoBaseURL)
+	if !ok {
+		return nil
 	}
 
-	// Add Source Footer
-	responseBuilder.WriteString(fmt.Sprintf("\n*(Data provided by %s)*", strings.ToUpper(source)))
+	proReq, err := a.newCoinGeckoProRequest(req.Context(), path)
+	if err != nil {
+		slog.Error("Error creating CG Pro request", "err", err)
+		return nil
+	}
+	slog.Info("CoinGecko demo API rejected the key, trying the Pro API", "status", status)
+	return proReq
+}
 
-	return responseBuilder.String()
+// 1. CoinGecko API (Failover)
+// currency is a lower-case fiat code such as "usd" or "eur". An unknown ID
+// (usually a wrong guess from getCoinID) is retried once via CoinGecko search.
+func (a *PMOAgent) getCoinGeckoData(ctx context.Context, coinID string, currency string) (string, error) {
+	return a.coinGeckoDataForID(ctx, coinID, currency, true)
 }
 
-// --- API Logic Functions ---
+// getMappedCoinGeckoData prices a symbol whose CoinGecko ID is in coinIDMap,
+// without the search fallback. Other symbols are reported as unresolved
+// without a request.
+func (a *PMOAgent) getMappedCoinGeckoData(ctx context.Context, symbol string, currency string) (string, error) {
+	coinID, ok := coinIDMap[strings.ToLower(symbol)]
+	if !ok {
+		return fmt.Sprintf("Error: no known CoinGecko ID for %s.", symbol), nil
+	}
+	return a.coinGeckoDataForID(ctx, coinID, currency, false)
+}
 
-// 1. CoinGecko API (Failover)
-func getCoinGeckoData(coinID string) (string, error) {
-	url := fmt.Sprintf("https://api.coingecko.com/api/v3/coins/%s?localization=false&tickers=false&market_data=true&community_data=false&developer_data=false&sparkline=false", coinID)
-	apiKey := os.Getenv("COINGECKO_API_KEY")
+func (a *PMOAgent) coinGeckoDataForID(ctx context.Context, coinID string, currency string, searchOnMiss bool) (string, error) {
+	path := fmt.Sprintf("/coins/%s?localization=false&tickers=false&market_data=true&community_data=false&developer_data=false&sparkline=true", coinID)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := a.newCoinGeckoRequest(ctx, path)
 	if err != nil {
-		log.Printf("Error creating CG request: %v", err)
+		slog.Error("Error creating CG request", "err", err)
 		return "Error creating HTTP request.", err
 	}
 
-	if apiKey != "" {
-		req.Header.Set("x-cg-demo-api-key", apiKey)
-	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	var cryptoData CoinGeckoResponse
+	status, err := a.fetchJSON(req, &cryptoData)
 
-	if err != nil {
+	if errors.Is(err, errResponseTooLarge) {
+		return "Error: CoinGecko response too large.", err
+	}
+	if status == 0 {
 		return "Error contacting CoinGecko API.", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("CoinGecko API returned status: %d for ID: %s", resp.StatusCode, coinID)
+	if status == http.StatusNotFound && searchOnMiss {
+		if id, ok := a.searchCoinGeckoID(ctx, coinID); ok && id != coinID {
+			slog.Debug("CoinGecko ID not found, retrying with search match", "id", coinID, "match", id)
+			return a.coinGeckoDataForID(ctx, id, currency, false)
+		}
+	}
+
+	if status != http.StatusOK {
+		slog.Warn("CoinGecko API returned an error status", "status", status, "id", coinID)
 		// Return a specific failure message that ProcessTask can check
-		return fmt.Sprintf("Error: CoinGecko API returned status %d. Could not find data for %s.", resp.StatusCode, coinID), nil
+		return fmt.Sprintf("Error: CoinGecko API returned status %d. Could not find data for %s.", status, coinID), statusError(status)
 	}
 
-	var cryptoData CoinGeckoResponse
-	if err := json.NewDecoder(resp.Body).Decode(&cryptoData); err != nil {
+	if err != nil {
 		return "Error processing CG API response.", err
 	}
+	if cryptoData.Error != "" || cryptoData.ID == "" {
+		slog.Warn("CoinGecko returned an error body", "id", coinID, "error", cryptoData.Error)
+		return fmt.Sprintf("Error: CoinGecko could not find data for %s.", coinID), nil
+	}
 
-	// Format all data points
-	priceUSD := formatCurrency(cryptoData.MarketData.CurrentPrice["usd"])
-	priceEUR := formatCurrency(cryptoData.MarketData.CurrentPrice["eur"])
-	change24h := fmt.Sprintf("%.2f%%", cryptoData.MarketData.PriceChangePercentage24h)
-	marketCap := formatCurrency(cryptoData.MarketData.MarketCap["usd"])
-	circulatingSupply := formatQuantity(cryptoData.MarketData.CirculatingSupply)
-	totalSupply := formatQuantity(cryptoData.MarketData.TotalSupply)
+	// Some obscure coins are only priced in a few fiats (e.g. KRW), so check
+	// the key is present instead of formatting a missing price as $0.00
+	displayCurrency, ok := pricedCurrency(cryptoData.MarketData.CurrentPrice, currency)
+	if !ok {
+		slog.Warn("CoinGecko has no fiat price", "id", coinID)
+		return fmt.Sprintf("CoinGecko price unavailable in %s for %s.", strings.ToUpper(currency), coinID), nil
+	}
+	priceNote := ""
+	if displayCurrency != currency {
+		priceNote = fmt.Sprintf(";price_note:%s price unavailable, showing %s", strings.ToUpper(currency), strings.ToUpper(displayCurrency))
+		currency = displayCurrency
+	}
 
-	// Build the final response string
-	responseString := fmt.Sprintf(
-		"token_source:coingecko;current_price_usd:%s;current_price_eur:%s;24h_change:%s;market_cap_usd:%s;circulating_supply:%s;total_supply:%s",
-		priceUSD,
-		priceEUR,
-		change24h,
-		marketCap,
-		circulatingSupply,
-		totalSupply,
-	)
+	// Format all data points. Market cap and 24h change are left out, rather
+	// than shown as $0.00 and 0.00%, when CoinGecko doesn't know them
+	marketData := cryptoData.MarketData
+	circulatingSupply := formatQuantity(marketData.CirculatingSupply)
+	totalSupply := formatQuantity(marketData.TotalSupply)
+	price := marketData.CurrentPrice.value(currency)
+	extras := ";coin_id:" + cryptoData.ID + fromWeekHighField(marketData.CurrentPrice.value("usd"), marketData.Sparkline7d.Price) +
+		fromATHField(price, marketData.ATH.value(currency))
+	if cryptoData.MarketCapRank > 0 {
+		extras += fmt.Sprintf(";rank:%d", cryptoData.MarketCapRank)
+	}
+	if volume, ok := marketData.TotalVolume.lookup(currency); ok {
+		extras += ";volume_24h:" + formatCurrency(volume, currency)
+	}
+	extras += dayRangeFields(price, marketData.High24h.value(currency), marketData.Low24h.value(currency), currency)
+
+	change, hasChange := marketData.PriceChange24hInCurrency.lookup(currency)
+	if currency == "usd" && marketData.PriceChangePercentage24h != nil {
+		change, hasChange = *marketData.PriceChangePercentage24h, true
+	}
+	if hasChange {
+		extras += ";24h_change:" + a.formatChange(change)
+	}
+	if marketCap, ok := marketData.MarketCap.lookup(currency); ok {
+		extras += fmt.Sprintf(";market_cap_%s:%s", currency, formatCurrency(marketCap, currency))
+	}
+	if logo := cryptoData.Image.Large; strings.HasPrefix(logo, "https://") {
+		// A ';' would split the raw field, so it is percent-encoded
+		extras += ";image:" + strings.ReplaceAll(logo, ";", "%3B")
+	}
 
-	return responseString, nil
+	var responseString string
+	if currency == "usd" {
+		responseString = "token_source:coingecko;current_price_usd:" + formatPrice(price, "usd")
+		if priceEUR, ok := marketData.CurrentPrice.lookup("eur"); ok {
+			responseString += ";current_price_eur:" + formatPrice(priceEUR, "eur")
+		}
+	} else {
+		// Non-USD requests use the currency-specific price, cap and change
+		responseString = fmt.Sprintf("token_source:coingecko;currency:%s;current_price_%s:%s", currency, currency, formatPrice(price, currency))
+	}
+	responseString += fmt.Sprintf(";circulating_supply:%s;total_supply:%s", circulatingSupply, totalSupply)
+	if cryptoData.Symbol != "" {
+		responseString += ";symbol:" + strings.ToUpper(strings.ReplaceAll(cryptoData.Symbol, ";", ""))
+	}
+
+	return responseString + priceNote + priceValueField(price) + extras + a.stalenessFields(cryptoData.LastUpdated), nil
+}
+
+// fallbackPriceCurrencies are tried, in order, when a coin lacks the requested currency.
+var fallbackPriceCurrencies = []string{"usd", "eur", "gbp", "jpy", "krw", "cny"}
+
+// pricedCurrency returns the requested currency if prices contains it, otherwise
+// the first fallback fiat that is present. It reports false if none are.
+func pricedCurrency(prices optionalAmounts, requested string) (string, bool) {
+	if _, ok := prices.lookup(requested); ok {
+		return requested, true
+	}
+	for _, fallback := range fallbackPriceCurrencies {
+		if _, ok := prices.lookup(fallback); ok {
+			return fallback, true
+		}
+	}
+	return "", false
 }
 
 // 2. CoinMarketCap API (Primary CEX Lookup)
-func getCMCData(symbol string) (string, error) {
-	url := "https://pro-api.coinmarketcap.com/v1/cryptocurrency/quotes/latest"
+// currency is a lower-case fiat code such as "usd" or "eur".
+func (a *PMOAgent) getCMCData(ctx context.Context, symbol string, currency string) (string, error) {
+	url := a.config.CMCBaseURL + "/v1/cryptocurrency/quotes/latest"
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		log.Printf("Error creating CMC request: %v", err)
+		slog.Error("Error creating CMC request", "err", err)
 		return "Error creating HTTP request.", err
 	}
 
+	// cmc:<id> targets one listing exactly; the response is keyed by the ID
 	q := req.URL.Query()
-	q.Add("symbol", strings.ToUpper(symbol))
-	q.Add("convert", "USD")
+	dataKey := strings.ToUpper(symbol)
+	if id, isID := cmcIDTarget(symbol); isID {
+		if !validCMCID(id) {
+			return fmt.Sprintf("Invalid CoinMarketCap ID: %s. Use cmc:<number>, e.g. cmc:1 for Bitcoin.", id), nil
+		}
+		q.Add("id", id)
+		dataKey = id
+	} else {
+		q.Add("symbol", dataKey)
+	}
+	q.Add("convert", strings.ToUpper(currency))
 	req.URL.RawQuery = q.Encode()
 
-	apiKey := os.Getenv("CMC_API_KEY")
-	if apiKey == "" {
-		return "Error: CMC_API_KEY not found in .env file.", nil
-	}
-	req.Header.Set("X-CMC_PRO_API_KEY", apiKey)
+	req.Header.Set("X-CMC_PRO_API_KEY", a.config.CMCAPIKey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	var cryptoData CMCResponse
+	status, err := a.fetchJSON(req, &cryptoData)
 
-	if err != nil {
+	if errors.Is(err, errResponseTooLarge) {
+		return "Error: CoinMarketCap response too large.", err
+	}
+	if status == 0 {
 		return "Error contacting CoinMarketCap API.", err
 	}
-	defer resp.Body.Close()
 
-	var cryptoData CMCResponse
-	if err := json.NewDecoder(resp.Body).Decode(&cryptoData); err != nil {
+	// Rate limits and outages are failover conditions, not "symbol not found";
+	// CMC's 429 body doesn't follow the usual status schema, so don't decode it
+	switch {
+	case status == http.StatusTooManyRequests:
+		slog.Warn("CMC rate limit hit", "symbol", symbol)
+		return "Error: CoinMarketCap rate limit reached. Try again shortly.", statusError(status)
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		slog.Error("CMC rejected the API key", "status", status)
+		return fmt.Sprintf("Error: CoinMarketCap rejected the API key (status %d).", status), nil
+	case status >= http.StatusInternalServerError:
+		slog.Warn("CMC API returned an error status", "status", status, "symbol", symbol)
+		return fmt.Sprintf("Error: CoinMarketCap API returned status %d.", status), statusError(status)
+	}
+
+	if err != nil {
 		return "Error processing CMC API response.", err
 	}
 
 	// Check for API errors (e.g., Symbol not found)
 	if cryptoData.Status.ErrorCode != 0 {
-		log.Printf("CMC API Error: %s for symbol: %s", cryptoData.Status.ErrorMessage, symbol)
+		slog.Warn("CMC API error", "error", cryptoData.Status.ErrorMessage, "symbol", symbol)
 		// Return a specific failure message that ProcessTask can check
 		return fmt.Sprintf("CMC could not find market data for symbol: %s. Error: %s", symbol, cryptoData.Status.ErrorMessage), nil
 	}
 
-	data, ok := cryptoData.Data[strings.ToUpper(symbol)]
+	data, ok := cryptoData.Data[dataKey]
 	if !ok {
 		// Return a specific failure message that ProcessTask can check
 		return fmt.Sprintf("CMC could not find market data for symbol: %s. Try another symbol.", symbol), nil
 	}
 
+	quote, ok := data.Quote[strings.ToUpper(currency)]
+	if !ok {
+		return fmt.Sprintf("CMC could not find market data for symbol: %s in %s.", symbol, strings.ToUpper(currency)), nil
+	}
+
 	// Format all data points
-	priceUSD := formatCurrency(data.Quote.USD.Price)
-	change24h := fmt.Sprintf("%.2f%%", data.Quote.USD.PercentChange24h)
-	marketCap := formatCurrency(data.Quote.USD.MarketCap)
+	price := formatPrice(quote.Price, currency)
+	change24h := a.formatChange(quote.PercentChange24h)
+	marketCap := formatCurrency(quote.MarketCap, currency)
 	circulatingSupply := formatQuantity(data.CirculatingSupply)
 	totalSupply := formatQuantity(data.TotalSupply)
 
 	// Build the final response string
 	responseString := fmt.Sprintf(
-		"token_source:coinmarketcap;current_price_usd:%s;24h_change:%s;market_cap_usd:%s;circulating_supply:%s;total_supply:%s",
-		priceUSD,
+		"token_source:coinmarketcap;currency:%s;current_price_%s:%s;24h_change:%s;market_cap_%s:%s;circulating_supply:%s;total_supply:%s",
+		currency,
+		currency,
+		price,
 		change24h,
+		currency,
 		marketCap,
 		circulatingSupply,
 		totalSupply,
 	)
 
-	return responseString, nil
+	if data.CMCRank > 0 {
+		responseString += fmt.Sprintf(";rank:%d", data.CMCRank)
+	}
+	if quote.Volume24h > 0 {
+		responseString += ";volume_24h:" + formatCurrency(quote.Volume24h, currency)
+	}
+	// Tokens also report their contract so users can cross-check on-chain
+	if data.Platform != nil && data.Platform.TokenAddress != "" {
+		responseString += fmt.Sprintf(";contract_address:%s;contract_chain:%s", data.Platform.TokenAddress, data.Platform.Name)
+	}
+
+	if data.Name != "" {
+		responseString += ";name:" + strings.ReplaceAll(data.Name, ";", "")
+	}
+	if data.Symbol != "" {
+		responseString += ";symbol:" + strings.ReplaceAll(data.Symbol, ";", "")
+	}
+
+	return responseString + priceValueField(quote.Price) + a.stalenessFields(data.LastUpdated), nil
+}
+
+// cmcIDTarget reports whether target is a cmc:<id> lookup, returning the ID.
+func cmcIDTarget(target string) (string, bool) {
+	if len(target) < len("cmc:") || !strings.EqualFold(target[:len("cmc:")], "cmc:") {
+		return "", false
+	}
+	return target[len("cmc:"):], true
+}
+
+// validCMCID reports whether id is a positive integer, as CMC IDs are.
+func validCMCID(id string) bool {
+	value, err := strconv.Atoi(id)
+	return err == nil && value > 0 && strconv.Itoa(value) == id
 }
 
 // 3. Dexscreener API (DEX Lookup)
-func getDexData(tokenAddress string) (string, error) {
-	url := fmt.Sprintf("https://api.dexscreener.com/latest/dex/tokens/%s", tokenAddress)
+// Dexscreener only quotes USD, so the currency argument is accepted for
+// interface compatibility with the other providers and otherwise ignored.
+func (a *PMOAgent) getDexData(ctx context.Context, tokenAddress string, _ string) (string, error) {
+	return a.getDexDataWithFilters(ctx, tokenAddress, a.config.MinLiquidityUSD, "")
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		log.Printf("Error creating Dexscreener request: %v", err)
-		return "", err
+// getDexDataWithFilters looks up a token's most relevant DEX pair, skipping
+// pools with less than minLiquidity USD of liquidity (0 disables) and, when
+// chain is set, pools on any other chain.
+func (a *PMOAgent) getDexDataWithFilters(ctx context.Context, tokenAddress string, minLiquidity float64, chain string) (string, error) {
+	pairs, message, err := a.fetchDexPairs(ctx, tokenAddress)
+	if message != "" {
+		return message, err
 	}
+	pairs, message = filterPairsByChain(pairs, chain)
+	if message != "" {
+		return message, nil
+	}
+	return dexPairResponse(pairs, minLiquidity, a.config.TrustedQuoteTokens), nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+// filterPairsByChain keeps the pairs on chain (a Dexscreener chain ID; empty
+// keeps everything). When the token only trades elsewhere it returns a
+// message listing the chains it does have liquidity on instead.
+func filterPairsByChain(pairs []DexPair, chain string) ([]DexPair, string) {
+	if chain == "" || len(pairs) == 0 {
+		return pairs, ""
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Dexscreener API returned status: %d for address: %s", resp.StatusCode, tokenAddress)
-		return fmt.Sprintf("Dexscreener Error: API returned status %d.", resp.StatusCode), nil
+	var matching []DexPair
+	var elsewhere []string
+	for _, pair := range pairs {
+		if strings.EqualFold(pair.ChainID, chain) {
+			matching = append(matching, pair)
+		} else if pair.liquidityUSD() > 0 && !slices.Contains(elsewhere, pair.ChainID) {
+			elsewhere = append(elsewhere, pair.ChainID)
+		}
+	}
+	if len(matching) > 0 {
+		return matching, ""
+	}
+	if len(elsewhere) == 0 {
+		return nil, fmt.Sprintf("Dexscreener found no pairs for that token on %s.", strings.ToLower(chain))
+	}
+	return nil, fmt.Sprintf("Dexscreener found no pairs for that token on %s, but it is available on: %s.", strings.ToLower(chain), strings.Join(elsewhere, ", "))
+}
+
+// fetchDexPairs queries the tokens endpoint, which accepts one address or a
+// comma-separated list. On failure it returns a human-readable message
+// alongside the error (which may be nil).
+func (a *PMOAgent) fetchDexPairs(ctx context.Context, addresses string) ([]DexPair, string, error) {
+	url := fmt.Sprintf("%s/latest/dex/tokens/%s", a.config.DexscreenerBaseURL, addresses)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		slog.Error("Error creating Dexscreener request", "err", err)
+		return nil, "Error creating HTTP request.", err
 	}
 
 	var dexData DexscreenerResponse
-	if err := json.NewDecoder(resp.Body).Decode(&dexData); err != nil {
-		return "Error processing Dexscreener response.", err
+	status, err := a.fetchJSON(req, &dexData)
+	if errors.Is(err, errResponseTooLarge) {
+		return nil, "Error: Dexscreener response too large.", err
+	}
+	if status == 0 {
+		return nil, "Error contacting Dexscreener API.", err
 	}
 
-	if len(dexData.Pairs) == 0 {
-		return "Dexscreener found no pairs for that token address.", nil
+	if status != http.StatusOK {
+		slog.Warn("Dexscreener API returned an error status", "status", status, "address", addresses)
+		return nil, fmt.Sprintf("Dexscreener Error: API returned status %d.", status), statusError(status)
 	}
 
-	pair := dexData.Pairs[0]
+	if err != nil {
+		return nil, "Error processing Dexscreener response.", err
+	}
+
+	return dexData.Pairs, "", nil
+}
+
+// selectDexPair picks the pair used for pricing among those at least
+// minLiquidity deep: the one quoted in the earliest trusted quote token, or
+// failing that the first one, since Dexscreener lists the most relevant pair
+// first. It returns nil when no pair is deep enough.
+func selectDexPair(pairs []DexPair, minLiquidity float64, trustedQuotes []string) *DexPair {
+	var first, best *DexPair
+	bestRank := len(trustedQuotes)
+	for i := range pairs {
+		if pairs[i].liquidityUSD() < minLiquidity {
+			continue
+		}
+		if first == nil {
+			first = &pairs[i]
+		}
+		for rank, symbol := range trustedQuotes[:bestRank] {
+			if strings.EqualFold(pairs[i].QuoteToken.Symbol, symbol) {
+				best, bestRank = &pairs[i], rank
+				break
+			}
+		}
+	}
+	if best != nil {
+		return best
+	}
+	return first
+}
+
+// dexPairResponse builds the raw response for one token from its pairs, or
+// a failure message when none qualify.
+func dexPairResponse(pairs []DexPair, minLiquidity float64, trustedQuotes []string) string {
+	if len(pairs) == 0 {
+		return "Dexscreener found no pairs for that token address."
+	}
+
+	pair := selectDexPair(pairs, minLiquidity, trustedQuotes)
+	if pair == nil {
+		return fmt.Sprintf("Dexscreener found no pools that meet the minimum liquidity threshold (%s).", formatCurrency(minLiquidity, "usd"))
+	}
 
 	price, _ := strconv.ParseFloat(pair.PriceUsd, 64)
 
 	responseString := fmt.Sprintf(
-		"token_source:dexscreener;chain_id:%s;current_price_usd:%s;volume_24h:%s;fdv:%s;base_token:%s",
+		"token_source:dexscreener;chain_id:%s;current_price_usd:%s;volume_24h:%s;fdv:%s;base_token:%s;contract_address:%s",
 		pair.ChainID,
-		formatCurrency(price),
-		formatCurrency(pair.Volume.H24),
-		formatCurrency(pair.FDV),
+		formatPrice(price, "usd"),
+		formatCurrency(pair.Volume.H24, "usd"),
+		formatCurrency(pair.FDV, "usd"),
 		pair.BaseToken.Symbol,
+		pair.BaseToken.Address,
+	)
+	responseString += priceValueField(price)
+	if pair.DexID != "" && pair.QuoteToken.Symbol != "" {
+		responseString += fmt.Sprintf(";dex_id:%s;pair:%s/%s", pair.DexID, pair.BaseToken.Symbol, pair.QuoteToken.Symbol)
+	}
+	if pair.Liquidity != nil {
+		responseString += ";liquidity_usd:" + formatCurrency(pair.Liquidity.USD, "usd")
+	}
+
+	// Both sides of the pair, so --quote can show the quote token's view
+	if priceInQuote, err := strconv.ParseFloat(pair.PriceNative, 64); err == nil && priceInQuote > 0 && pair.QuoteToken.Symbol != "" {
+		responseString += fmt.Sprintf(";quote_token:%s;price_in_quote:%s;quote_in_base:%s;quote_price_usd:%s",
+			pair.QuoteToken.Symbol,
+			formatPrice(priceInQuote, pair.QuoteToken.Symbol),
+			formatPrice(1/priceInQuote, pair.BaseToken.Symbol),
+			formatPrice(price/priceInQuote, "usd"),
+		)
+	}
+
+	return responseString
+}
+
+// 4. Binance API (CEX Last Resort)
+func (a *PMOAgent) getBinanceData(ctx context.Context, symbol string, currency string) (string, error) {
+	// USD requests use the USDT market, which we treat as USD for display;
+	// other fiats use Binance's direct fiat pairs where they exist (e.g. BTCEUR)
+	quoteAsset := "USDT"
+	if currency != "usd" {
+		quoteAsset = strings.ToUpper(currency)
+	}
+	pairSymbol := strings.ToUpper(symbol) + quoteAsset
+	url := fmt.Sprintf("%s/api/v3/ticker/24hr?symbol=%s", a.config.BinanceBaseURL, pairSymbol)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		slog.Error("Error creating Binance request", "err", err)
+		return "Error creating HTTP request.", err
+	}
+
+	var ticker BinanceTicker
+	status, err := a.fetchJSON(req, &ticker)
+	if errors.Is(err, errResponseTooLarge) {
+		return "Error: Binance response too large.", err
+	}
+	if status == 0 {
+		return "Error contacting Binance API.", err
+	}
+
+	if status != http.StatusOK {
+		slog.Warn("Binance API returned an error status", "status", status, "pair", pairSymbol)
+		return fmt.Sprintf("Binance could not find a %s market for symbol: %s.", quoteAsset, symbol), statusError(status)
+	}
+
+	if err != nil {
+		return "Error processing Binance API response.", err
+	}
+
+	price, _ := strconv.ParseFloat(ticker.LastPrice, 64)
+	change, _ := strconv.ParseFloat(ticker.PriceChangePercent, 64)
+	volume, _ := strconv.ParseFloat(ticker.QuoteVolume, 64)
+	high, _ := strconv.ParseFloat(ticker.HighPrice, 64)
+	low, _ := strconv.ParseFloat(ticker.LowPrice, 64)
+
+	responseString := fmt.Sprintf(
+		"token_source:binance;symbol:%s;currency:%s;current_price_%s:%s;24h_change:%s;volume_24h:%s",
+		strings.ToUpper(symbol),
+		currency,
+		currency,
+		formatPrice(price, currency),
+		a.formatChange(change),
+		formatCurrency(volume, currency),
 	)
 
-	return responseString, nil
+	return responseString + priceValueField(price) + dayRangeFields(price, high, low, currency), nil
+}
+
+// --- Provider Chain ---
+
+// priceProvider is one named source that can resolve a symbol or address
+// in a lower-case fiat currency. exactLookup, when set, prices only targets
+// the provider resolves without guessing (no search fallback) and is what the
+// cross-check uses; providers that never guess leave it nil.
+type priceProvider struct {
+	name        string
+	lookup      func(ctx context.Context, target string, currency string) (string, error)
+	exactLookup func(ctx context.Context, target string, currency string) (string, error)
+}
+
+// sourceAliases maps the accepted --source values onto provider names.
+var sourceAliases = map[string]string{
+	"cmc":           "cmc",
+	"coinmarketcap": "cmc",
+	"coingecko":     "coingecko",
+	"cg":            "coingecko",
+	"dexscreener":   "dexscreener",
+	"dex":           "dexscreener",
+	"binance":       "binance",
+}
+
+// findProvider returns the provider registered under a canonical name.
+func (a *PMOAgent) findProvider(name string) (priceProvider, bool) {
+	if name == a.dexProvider.name {
+		return a.dexProvider, true
+	}
+	p, ok := a.sourceProviders[name]
+	return p, ok
+}
+
+// providerDisplayNames are the user-facing names of the CEX providers.
+var providerDisplayNames = map[string]string{
+	"cmc":       "CoinMarketCap",
+	"coingecko": "CoinGecko",
+	"binance":   "Binance",
+}
+
+// chainDescription names the CEX failover chain for messages, e.g.
+// "CoinMarketCap or CoinGecko".
+func (a *PMOAgent) chainDescription() string {
+	names := make([]string, 0, len(a.cexProviders))
+	for _, p := range a.cexProviders {
+		names = append(names, providerDisplayNames[p.name])
+	}
+	switch len(names) {
+	case 0:
+		return "any provider"
+	case 1:
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// providerSucceeded reports whether a provider returned usable market data.
+// Every successful provider response starts with its token_source field;
+// anything else is a human-readable failure message.
+func providerSucceeded(response string, err error) bool {
+	return err == nil && strings.HasPrefix(response, "token_source:")
+}
+
+// evmAddressLength is "0x" plus 40 hex digits.
+const evmAddressLength = 42
+
+// isContractAddress reports whether the input is a well-formed EVM contract
+// address: exactly "0x" followed by 40 hex digits (either case).
+func isContractAddress(input string) bool {
+	if len(input) != evmAddressLength || !strings.HasPrefix(input, "0x") {
+		return false
+	}
+	for _, r := range input[2:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// looksLikeAddress reports whether a malformed input was probably meant as a
+// contract address, so failures can explain the expected format.
+func looksLikeAddress(input string) bool {
+	return strings.HasPrefix(input, "0x") && len(input) >= 30 && !isContractAddress(input)
+}
+
+// extractCurrencyShorthand recognises the natural-language `<symbols> in <fiat>`
+// form (e.g. `/price btc in eur`), recording the fiat as the currency flag and
+// returning the remaining arguments. If "in" isn't followed by a supported
+// fiat the arguments are returned untouched and treated as symbols.
+func extractCurrencyShorthand(args []string, flags map[string]string) []string {
+	if len(args) < 3 {
+		return args
+	}
+	in, currency := args[len(args)-2], args[len(args)-1]
+	if !strings.EqualFold(in, "in") || !isFiat(currency) {
+		return args
+	}
+	flags["currency"] = strings.ToLower(currency)
+	return args[:len(args)-2]
+}
+
+// parseMinLiquidity parses a --minliq value: a USD amount of 0 or more.
+func parseMinLiquidity(raw string) (float64, bool) {
+	if raw == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimPrefix(raw, "$"), ",", ""), 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, false
+	}
+	return value, true
+}
+
+// targetPunctuation is what users commonly paste around symbols in chat, e.g.
+// "btc," or "(eth)". None of it can appear in a ticker or an 0x address.
+const targetPunctuation = ",.;:!?()[]{}<>\"'`“”‘’"
+
+// trimTargets strips surrounding punctuation from each lookup target,
+// dropping any that were nothing but punctuation.
+func trimTargets(args []string) []string {
+	trimmed := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg = strings.Trim(arg, targetPunctuation); arg != "" {
+			trimmed = append(trimmed, arg)
+		}
+	}
+	return trimmed
+}
+
+// fillerTargets are words typed around a symbol ("/price price", "/market of")
+// that are never worth a lookup on their own. The list stays short so that
+// real tickers are not blocked.
+var fillerTargets = map[string]bool{"price": true, "market": true, "token": true, "coin": true, "of": true, "for": true}
+
+// onlyFillerTargets reports whether every target is a filler word or repeats
+// a command keyword, i.e. the user never actually named a token.
+func onlyFillerTargets(args []string) bool {
+	for _, arg := range args {
+		word := strings.ToLower(arg)
+		if _, isCommand := commands[resolveCommand("/"+word)]; !isCommand && !fillerTargets[word] {
+			return false
+		}
+	}
+	return len(args) > 0
+}
+
+// smartDashes are what chat clients and word processors turn a typed "--"
+// into; a leading one is read as the flag prefix.
+var smartDashes = []string{"—", "–"}
+
+// parseFlags separates `--name=value` and `--name` flags from positional arguments.
+// Flag names are lower-cased; bare flags map to an empty value.
+func parseFlags(args []string) ([]string, map[string]string) {
+	var positional []string
+	flags := make(map[string]string)
+
+	for _, arg := range args {
+		for _, dash := range smartDashes {
+			if rest, ok := strings.CutPrefix(arg, dash); ok && rest != "" && !strings.HasPrefix(rest, "-") {
+				arg = "--" + rest
+				break
+			}
+		}
+		if !strings.HasPrefix(arg, "--") || len(arg) == 2 {
+			positional = append(positional, arg)
+			continue
+		}
+		name, value, _ := strings.Cut(arg[2:], "=")
+		flags[strings.ToLower(name)] = value
+	}
+
+	return positional, flags
+}
+
+// --- Ticker Ambiguity Check ---
+
+// ambiguityFactor is how far apart two providers' prices must be before we
+// assume they resolved the ticker to different coins.
+const ambiguityFactor = 100
+
+// crossCheck asks the first remaining provider that can resolve the target and
+// compares its price with the primary response. It returns an
+// ambiguity_warning field to append when the two disagree wildly, else "".
+// Providers are asked through exactLookup where they have one: a guessed or
+// searched ID is exactly the kind of match that may be a different coin, and
+// the extra search requests would make every lookup cost more.
+func (a *PMOAgent) crossCheck(ctx context.Context, target, currency, primary string, others []priceProvider) string {
+	primaryPrice, ok := parseDisplayNumber(parseRawOutput(primary)["current_price_"+responseCurrency(primary)])
+	if !ok || primaryPrice <= 0 {
+		return ""
+	}
+
+	for _, provider := range others {
+		lookup := provider.lookup
+		if provider.exactLookup != nil {
+			lookup = provider.exactLookup
+		}
+		response, err := lookup(ctx, target, currency)
+		if !providerSucceeded(response, err) {
+			continue
+		}
+		otherPrice, ok := parseDisplayNumber(parseRawOutput(response)["current_price_"+responseCurrency(response)])
+		if !ok || otherPrice <= 0 {
+			return ""
+		}
+
+		if math.Max(primaryPrice, otherPrice)/math.Min(primaryPrice, otherPrice) > ambiguityFactor {
+			slog.Info("Ticker ambiguity", "target", target, "primary", primary, "alternative", response)
+			return fmt.Sprintf(";ambiguity_warning:%s and %s prices differ by more than %dx",
+				strings.ToUpper(parseRawOutput(primary)["token_source"]), strings.ToUpper(provider.name), ambiguityFactor)
+		}
+		return ""
+	}
+
+	return ""
 }
 
 // --- Agent Handler (The Core Logic) ---
 
+// maxTokensPerRequest caps how many symbols a single /price call may look up.
+const maxTokensPerRequest = 10
+
+// session identifies where a task came from, for per-room state such as
+// /watch alerts. Direct ProcessTask calls have an empty room and no sender.
+type session struct {
+	room   string
+	sender types.MessageSender
+}
+
 // ProcessTask uses the correct Teneo SDK signature and orchestrates the API calls.
 func (a *PMOAgent) ProcessTask(ctx context.Context, input string) (string, error) {
-	log.Printf("Processing task: %s", input)
+	return a.processTask(ctx, session{}, input)
+}
 
-	// 1. Command and Input Parsing
-	parts := strings.Fields(input)
-	if len(parts) < 2 {
-		return "Please specify a command (/price or /market) and a token symbol or contract address.", nil
+// ProcessTaskWithStreaming is what the SDK calls when it is implemented, since
+// it also passes the room. The answer is still sent as a single message; the
+// room and sender let /watch alerts reach the room that set them up.
+func (a *PMOAgent) ProcessTaskWithStreaming(ctx context.Context, input string, room string, sender types.MessageSender) error {
+	result, err := a.processTask(ctx, session{room: room, sender: sender}, input)
+	if err != nil {
+		return err
 	}
+	return sender.SendMessage(result)
+}
 
-	command := strings.ToLower(parts[0])
-	if command != "/price" && command != "/market" {
-		return fmt.Sprintf("Unknown command: %s. Use /price or /market.", command), nil
+func (a *PMOAgent) processTask(ctx context.Context, s session, input string) (response string, err error) {
+	// A bug in one handler must not take the whole agent down with it. The
+	// panic is logged here and the error cleared so the apology is delivered.
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			slog.Error("Panic while processing task", "input", input, "room", s.room, "panic", recovered, "stack", string(debug.Stack()))
+			response, err = internalErrorMessage, nil
+		}
+	}()
+
+	// The SDK always passes a context, but direct callers (e.g. tests) may not
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	slog.Debug("Processing task", "input", input)
 
-	lookupTarget := parts[1]
-	cleanInput := strings.ToLower(strings.TrimSpace(lookupTarget))
+	// Don't start provider calls for a task the SDK has already given up on
+	if err = ctx.Err(); err != nil {
+		return "Request cancelled.", err
+	}
 
-	// 2. Try DEX (Contract Address Lookup)
-	if strings.HasPrefix(cleanInput, "0x") && len(cleanInput) >= 40 {
-		log.Printf("Attempting Dexscreener lookup for address: %s", cleanInput)
-		dexResponse, err := getDexData(cleanInput)
-		if err != nil {
-			return "Error fetching DEX data.", err
+	// /batch keeps its line structure, so it is split before tokenizing. Each
+	// of its sub-commands counts against the room's throttle
+	input = normalizeInput(input)
+	lines, isBatch := batchLines(input)
+	cost := 1
+	if isBatch && len(lines) <= maxBatchCommands {
+		cost = max(len(lines), 1)
+	}
+	throttleKey := s.room
+	if throttleKey == "" {
+		throttleKey = directThrottleKey
+	}
+	if ok, remaining := a.throttle.allow(throttleKey, cost); !ok {
+		slog.Warn("Throttling room", "room", throttleKey, "cost", cost, "remaining", remaining)
+		if cost > 1 && remaining > 0 {
+			return fmt.Sprintf("This batch has %d commands but you can only send %d more right now. Please send fewer commands or slow down.", cost, remaining), nil
 		}
-		// --- FORMATTING CHANGE HERE ---
-		return formatOutput(dexResponse), nil
+		return "You're sending requests too fast, please slow down.", nil
 	}
 
-	// 3. Try CEX Primary (CoinMarketCap)
-	log.Printf("Attempting CoinMarketCap lookup for symbol: %s", lookupTarget)
-	cmcResponse, cmcErr := getCMCData(lookupTarget)
+	if isBatch {
+		return a.runBatch(ctx, s, lines)
+	}
+	return a.dispatch(ctx, s, tokenizeInput(input))
+}
+
+// marketCommand handles /market, which is /price with --details implied so
+// contract lookups carry CoinGecko's supply, rank and ATH alongside DEX data,
+// and CoinGecko results add the low-trust volume flag.
+func (a *PMOAgent) marketCommand(ctx context.Context, args []string, flags map[string]string) (string, error) {
+	flags["details"] = ""
+	return a.priceCommand(ctx, args, flags)
+}
+
+// priceCommand handles /price and /market lookups for one or more targets.
+func (a *PMOAgent) priceCommand(ctx context.Context, args []string, flags map[string]string) (string, error) {
+	const cmdName = "/price"
+
+	args = extractCurrencyShorthand(trimTargets(args), flags)
+	if len(args) == 0 {
+		return usageFor(cmdName), nil
+	}
+	if onlyFillerTargets(args) {
+		return withUsage(fmt.Sprintf("%q is not a token symbol.", strings.Join(args, " ")), cmdName), nil
+	}
+	if currency, ok := flags["currency"]; ok && !isFiat(currency) {
+		return withUsage(fmt.Sprintf("Unsupported currency: %s.", strings.ToUpper(currency)), cmdName), nil
+	}
+	if len(args) > maxTokensPerRequest {
+		return withUsage(fmt.Sprintf("Please look up at most %d tokens at a time.", maxTokensPerRequest), cmdName), nil
+	}
+
+	if rawMinLiq, ok := flags["minliq"]; ok {
+		if _, valid := parseMinLiquidity(rawMinLiq); !valid {
+			return withUsage(fmt.Sprintf("Invalid --minliq: %s. Please provide a USD amount of 0 or more.", rawMinLiq), cmdName), nil
+		}
+	}
+
+	if rawSource, ok := flags["source"]; ok {
+		if _, known := sourceAliases[strings.ToLower(rawSource)]; !known {
+			return withUsage(fmt.Sprintf("Unknown source: %s. Use --source=coingecko, cmc, dexscreener or binance.", rawSource), cmdName), nil
+		}
+	}
+
+	if rawVs, ok := flags["vs"]; ok {
+		if !stablecoins[strings.ToLower(rawVs)] {
+			return withUsage(fmt.Sprintf("Unsupported --vs: %s. Use --vs=usdt, usdc or dai.", rawVs), cmdName), nil
+		}
+		// The stablecoin price is derived from the USD price
+		if currency, ok := flags["currency"]; ok && currency != "usd" {
+			return withUsage(fmt.Sprintf("--vs=%s prices against USD and can't be combined with %s.", strings.ToLower(rawVs), strings.ToUpper(currency)), cmdName), nil
+		}
+		flags["currency"] = "usd"
+	}
+
+	if rawFormat, ok := flags["format"]; ok {
+		if _, known := formatters[strings.ToLower(rawFormat)]; !known {
+			return withUsage(fmt.Sprintf("Unknown format: %s. Use --format=%s.", rawFormat, formatNames()), cmdName), nil
+		}
+	}
+
+	// 1. A comma-separated address list is resolved with a single Dexscreener call
+	if addresses, ok := addressList(args); ok {
+		if _, raw := flags["raw"]; raw {
+			return "error: --raw supports a single token", fmt.Errorf("--raw with %d addresses", len(addresses))
+		}
+		if len(addresses) > maxTokensPerRequest {
+			return withUsage(fmt.Sprintf("Please look up at most %d tokens at a time.", maxTokensPerRequest), cmdName), nil
+		}
+		return a.lookupDexBatch(ctx, addresses, flags)
+	}
+
+	// 2. --raw returns just the number, for scripts using the agent as a price oracle
+	if _, raw := flags["raw"]; raw {
+		if len(args) != 1 {
+			return "error: --raw supports a single token", fmt.Errorf("--raw with %d tokens", len(args))
+		}
+		return a.rawPrice(ctx, args[0], flags)
+	}
+
+	// 3. Single lookups keep the provider's own message on failure
+	if len(args) == 1 {
+		result := a.lookupToken(ctx, args[0], flags)
+		return result.output, result.err
+	}
+
+	// 4. Multi-token lookups report successes and failures separately
+	return a.lookupTokens(ctx, args, flags), nil
+}
+
+// rawPrice resolves one target and returns its price as a bare decimal such
+// as "63245.12". Failures return a short plain-text message and an error, so
+// callers never mistake them for a price.
+func (a *PMOAgent) rawPrice(ctx context.Context, target string, flags map[string]string) (string, error) {
+	result := a.lookupToken(ctx, target, flags)
+	if !result.found {
+		if result.err != nil {
+			slog.Error("Raw lookup failed", "target", target, "err", result.err)
+		}
+		return "error: no price found for " + target, fmt.Errorf("no price found for %s", target)
+	}
 
-	// Check if CMC succeeded (no fatal error AND found data)
-	if cmcErr == nil && !strings.Contains(cmcResponse, "CMC could not find market data") {
-		// --- FORMATTING CHANGE HERE ---
-		return formatOutput(cmcResponse), nil
+	price, err := strconv.ParseFloat(parseRawOutput(result.raw)["price_value"], 64)
+	if err != nil || price <= 0 {
+		return "error: no price found for " + target, fmt.Errorf("%s response has no price", target)
 	}
+	return strconv.FormatFloat(price, 'f', -1, 64), nil
+}
 
-	// 4. Try CEX Failover (CoinGecko)
-	log.Printf("CMC failed. Falling back to CoinGecko for symbol: %s", lookupTarget)
-	coinID := getCoinID(lookupTarget)
-	cgResponse, cgErr := getCoinGeckoData(coinID)
+// tokenResult is the outcome of resolving a single lookup target.
+type tokenResult struct {
+	target string
+	raw    string // Semicolon-separated provider response, set on success
+	output string // Formatted market overview on success, failure message otherwise
+	found  bool
+	err    error
+}
 
-	// Check if CoinGecko succeeded (no fatal error AND found data)
-	if cgErr == nil && !strings.Contains(cgResponse, "Could not find data for") {
-		// --- FORMATTING CHANGE HERE ---
-		return formatOutput(cgResponse), nil
+// lookupTokens resolves several targets concurrently and aggregates the
+// formatted results in the order the targets were requested, listing any
+// targets that could not be resolved.
+func (a *PMOAgent) lookupTokens(ctx context.Context, targets []string, flags map[string]string) string {
+	// Each worker writes only its own slot, so completion order doesn't matter
+	results := make([]tokenResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		safeGo("lookup for "+target, func() {
+			defer wg.Done()
+			// A panicking lookup leaves the target reported as not found
+			results[i] = tokenResult{target: target}
+			results[i] = a.lookupToken(ctx, target, flags)
+		})
 	}
+	wg.Wait()
 
-	// 5. Final Failure
-	return fmt.Sprintf("Could not find market data for %s on CoinMarketCap or CoinGecko. Please ensure the symbol is correct or use a contract address for DEX listings.", lookupTarget), nil
+	return a.renderResults(results, flags)
+}
+
+// renderResults aggregates several lookup results into one response: a block
+// (or table row) per success, followed by the targets that could not be found.
+func (a *PMOAgent) renderResults(results []tokenResult, flags map[string]string) string {
+	var found []tokenResult
+	var missing []string
+	for _, result := range results {
+		if result.found {
+			found = append(found, result)
+			continue
+		}
+		if result.err != nil {
+			slog.Error("Lookup failed", "target", result.target, "err", result.err)
+		}
+		missing = append(missing, result.target)
+	}
+
+	var responseBuilder strings.Builder
+	if _, table := flags["table"]; table && len(found) > 0 {
+		responseBuilder.WriteString(renderTable(found, a.hidesSource(flags)))
+	} else {
+		blocks := make([]string, len(found))
+		for i, result := range found {
+			blocks[i] = result.output
+		}
+		responseBuilder.WriteString(strings.Join(blocks, "\n\n---\n\n"))
+	}
+	if len(missing) > 0 {
+		if len(found) > 0 {
+			responseBuilder.WriteString("\n\n---\n\n")
+		}
+		responseBuilder.WriteString(fmt.Sprintf("❌ **Could not find:** %s", strings.Join(missing, ", ")))
+	}
+
+	return responseBuilder.String()
+}
+
+// lookupToken resolves one symbol or contract address, serving repeat
+// lookups from the response cache.
+func (a *PMOAgent) lookupToken(ctx context.Context, lookupTarget string, flags map[string]string) tokenResult {
+	lookupTarget = strings.TrimSpace(lookupTarget)
+	result := tokenResult{target: lookupTarget}
+	if lookupTarget == "" {
+		result.output = "Please specify a token symbol or contract address after the command."
+		return result
+	}
+	currency := a.requestCurrency(flags)
+
+	// --fresh (or --nocache) skips the cache read but still refreshes the entry;
+	// the upstream call goes through the normal fetch path and its retry budget
+	var trace *lookupTrace
+	if _, debug := flags["debug"]; debug {
+		trace = &lookupTrace{}
+	}
+
+	key := a.lookupCacheKey(lookupTarget, flags)
+	if !wantsFresh(flags) {
+		if cached, ok := a.cache.get(key); ok {
+			slog.Debug("Cache hit", "key", key)
+			if trace != nil {
+				trace.cacheHit = true
+			}
+			result.raw = a.presentationView(ctx, cached, flags)
+			result.output, result.found = a.formatOutput(result.raw, flags)+trace.render(), true
+			return result
+		}
+	}
+
+	fetchedAt := time.Now()
+	response, found, err := a.resolveToken(ctx, lookupTarget, currency, flags, trace)
+	if !found {
+		// During an outage an expired entry beats no answer at all
+		if cached, age, ok := a.cache.getStale(key); ok && a.config.ServeStaleOnError {
+			slog.Warn("All providers failed, serving cached data", "key", key, "age", formatAge(age), "err", err)
+			// The --vs peg lookup goes through lookupToken too, so it can be served stale as well
+			result.raw = a.presentationView(ctx, cached+";cached_for:"+formatAge(age), flags)
+			result.output, result.found = a.formatOutput(result.raw, flags)+trace.render(), true
+			return result
+		}
+		result.output, result.err = response+trace.render(), err
+		return result
+	}
+
+	a.cache.set(key, response, fetchedAt)
+	result.raw = a.presentationView(ctx, response, flags)
+	result.output, result.found = a.formatOutput(result.raw, flags)+trace.render(), true
+	return result
+}
+
+// presentationView applies the per-request views (--quote, --scaled, --vs)
+// to a provider response, which is why they are never part of the cache.
+func (a *PMOAgent) presentationView(ctx context.Context, response string, flags map[string]string) string {
+	return a.withStablecoinView(ctx, withScaledView(withPairView(response, flags), flags), flags)
+}
+
+// withPairView marks a DEX response for the quote-side view when --quote is
+// set. It only changes presentation, so it is applied after the cache.
+func withPairView(response string, flags map[string]string) string {
+	if _, quote := flags["quote"]; quote && strings.Contains(response, ";quote_token:") {
+		return response + ";pair_view:quote"
+	}
+	return response
+}
+
+// requestCurrency returns the lower-case quote currency for a lookup: the
+// per-request currency when given, otherwise the configured DEFAULT_FIAT.
+func (a *PMOAgent) requestCurrency(flags map[string]string) string {
+	if requested, ok := flags["currency"]; ok {
+		return strings.ToLower(requested)
+	}
+	return a.defaultFiat()
+}
+
+// defaultFiat returns the configured DEFAULT_FIAT, or USD.
+func (a *PMOAgent) defaultFiat() string {
+	if a.config.DefaultFiat != "" {
+		return a.config.DefaultFiat
+	}
+	return "usd"
+}
+
+// lookupCacheKey is the cache key for one target with the request's options.
+// Every flag that changes the raw response belongs here, normalised so that
+// equivalent spellings (--minliq=1000 and --minliq=1e3) share an entry;
+// presentation-only flags such as --quote, --scaled and --vs do not.
+func (a *PMOAgent) lookupCacheKey(target string, flags map[string]string) string {
+	minLiquidity := ""
+	if value, ok := parseMinLiquidity(flags["minliq"]); ok {
+		minLiquidity = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return cacheKey(target, a.requestCurrency(flags), sourceAliases[strings.ToLower(flags["source"])],
+		"minliq="+minLiquidity, "chain="+strings.ToLower(flags["chain"]), "details="+strconv.FormatBool(hasFlag(flags, "details")))
+}
+
+// hasFlag reports whether a flag was given, with or without a value.
+func hasFlag(flags map[string]string, name string) bool {
+	_, ok := flags[name]
+	return ok
+}
+
+// wantsFresh reports whether the request asked to bypass cached data.
+func wantsFresh(flags map[string]string) bool {
+	_, fresh := flags["fresh"]
+	_, nocache := flags["nocache"]
+	return fresh || nocache
+}
+
+// dexFields returns the fields appended to every successful DEX lookup, however
+// it was routed: the wrapped-native note (WETH, WBNB... are easily mistaken
+// for the native asset), the GoPlus risk scan and, with --details,
+// CoinGecko's data for the contract.
+func (a *PMOAgent) dexFields(ctx context.Context, dexResponse string, flags map[string]string) string {
+	fields := a.wrappedNativeFields(ctx, dexResponse) + a.riskFields(ctx, dexResponse)
+	if hasFlag(flags, "details") {
+		fields += a.contractDetailFields(ctx, dexResponse)
+	}
+	return fields
+}
+
+// resolveToken queries the providers for one target. On success it returns the
+// raw provider response; otherwise a human-readable failure message.
+func (a *PMOAgent) resolveToken(ctx context.Context, lookupTarget, currency string, flags map[string]string, trace *lookupTrace) (string, bool, error) {
+	cleanInput := strings.ToLower(lookupTarget)
+
+	// --minliq overrides MIN_LIQUIDITY_USD and --chain restricts the pools
+	// for this request's DEX lookup
+	dexProvider := a.dexProvider
+	minLiquidity, customLiquidity := parseMinLiquidity(flags["minliq"])
+	if !customLiquidity {
+		minLiquidity = a.config.MinLiquidityUSD
+	}
+	if customLiquidity || flags["chain"] != "" {
+		dexProvider.lookup = func(ctx context.Context, target, _ string) (string, error) {
+			return a.getDexDataWithFilters(ctx, target, minLiquidity, flags["chain"])
+		}
+	}
+
+	// cmc:<id> targets only mean something to CoinMarketCap, so they are
+	// forced onto it like --source=cmc
+	rawSource, forced := flags["source"]
+	if _, isCMCID := cmcIDTarget(cleanInput); isCMCID {
+		if forced && sourceAliases[strings.ToLower(rawSource)] != "cmc" {
+			return "cmc:<id> targets can only be looked up on CoinMarketCap.", false, nil
+		}
+		rawSource, forced = "cmc", true
+	}
+
+	// 1. Forced Provider (--source bypasses the failover chain entirely)
+	if forced {
+		sourceName := sourceAliases[strings.ToLower(rawSource)]
+		if sourceName == a.dexProvider.name && !isContractAddress(cleanInput) {
+			return "Dexscreener lookups require a token contract address.", false, nil
+		}
+
+		provider, enabled := a.findProvider(sourceName)
+		if !enabled {
+			return fmt.Sprintf("The %s provider is disabled on this agent.", sourceName), false, nil
+		}
+		target := lookupTarget
+		if sourceName == a.dexProvider.name {
+			provider, target = dexProvider, cleanInput
+		}
+		slog.Debug("Forcing provider lookup", "provider", provider.name, "target", target)
+		start := time.Now()
+		response, err := provider.lookup(ctx, target, currency)
+		succeeded := providerSucceeded(response, err)
+		trace.record(provider.name, start, succeeded)
+		a.health.record(provider.name, target, succeeded)
+		switch {
+		case succeeded && provider.name == a.dexProvider.name:
+			response += a.dexFields(ctx, response, flags)
+		case succeeded && hasFlag(flags, "details"):
+			response += a.coinGeckoDetailFields(ctx, response)
+		}
+		// No fallback: surface the provider's own failure to help isolate it
+		return response, succeeded, err
+	}
+
+	// 2. Try DEX (Contract Address Lookup)
+	if isContractAddress(cleanInput) {
+		slog.Debug("Attempting provider lookup", "provider", "dexscreener", "address", cleanInput)
+		start := time.Now()
+		dexResponse, err := dexProvider.lookup(ctx, cleanInput, currency)
+		trace.record(a.dexProvider.name, start, providerSucceeded(dexResponse, err))
+		a.health.record(a.dexProvider.name, cleanInput, providerSucceeded(dexResponse, err))
+		if err != nil {
+			return "Error fetching DEX data.", false, err
+		}
+		if !providerSucceeded(dexResponse, nil) {
+			return dexResponse, false, nil
+		}
+		return dexResponse + a.dexFields(ctx, dexResponse, flags), true, nil
+	}
+
+	// 3. Walk the CEX failover chain (CoinMarketCap -> CoinGecko by default)
+	var providerErrs []error
+	for i, provider := range a.cexProviders {
+		// Once the task is cancelled every remaining provider would fail too
+		if err := ctx.Err(); err != nil {
+			return "Request cancelled.", false, err
+		}
+		slog.Debug("Attempting provider lookup", "provider", provider.name, "symbol", lookupTarget)
+		start := time.Now()
+		response, err := provider.lookup(ctx, lookupTarget, currency)
+		trace.record(provider.name, start, providerSucceeded(response, err))
+		a.health.record(provider.name, lookupTarget, providerSucceeded(response, err))
+		if providerSucceeded(response, err) {
+			if a.config.CrossCheckPrices {
+				response += a.crossCheck(ctx, lookupTarget, currency, response, a.cexProviders[i+1:])
+			}
+			if hasFlag(flags, "details") {
+				response += a.coinGeckoDetailFields(ctx, response)
+			}
+			return response, true, nil
+		}
+		if err != nil {
+			providerErrs = append(providerErrs, err)
+		}
+		slog.Debug("Provider failed, trying next provider", "provider", provider.name, "symbol", lookupTarget)
+	}
+
+	// 4. Final Failure. When every provider errored, none of them actually
+	// looked for the token, so don't claim it doesn't exist
+	if len(providerErrs) > 0 && len(providerErrs) == len(a.cexProviders) {
+		return fmt.Sprintf("Could not reach any market data provider for %s. Please try again shortly.", lookupTarget), false, errors.Join(providerErrs...)
+	}
+	if looksLikeAddress(cleanInput) {
+		return fmt.Sprintf("%s is not a valid contract address (expected 0x followed by 40 hex characters), and no token uses it as a symbol.", lookupTarget), false, nil
+	}
+	return fmt.Sprintf("Could not find market data for %s on %s. Please ensure the symbol is correct or use a contract address for DEX listings.", lookupTarget, a.chainDescription()), false, nil
 }
 
 // --- Main Function ---
 
+// version is reported to Teneo and by /status; release builds set it with
+// -ldflags "-X main.version=<version>".
+var version = "1.0.0"
+
 func main() {
-	godotenv.Load()
+	if err := loadDotEnv(".env"); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	appConfig, err := LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	// Everything logs through slog at its own level; any remaining plain log
+	// calls are routed through this handler at info level
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: appConfig.LogLevel})))
 
 	config := agent.DefaultConfig()
 	config.Name = "Price and Market Overview"
-	config.Description = "Fetches comprehensive crypto market data from CoinMarketCap (Primary CEX), CoinGecko (CEX Failover), and Dexscreener (DEX)."
+	config.Version = version
+	config.Description = "Fetches comprehensive crypto market data from CoinMarketCap (Primary CEX), CoinGecko (CEX Failover), Binance (on request) and Dexscreener (DEX)."
 	config.Capabilities = []string{"fetch real-time cryptocurrency price and market data using multiple apis"}
 
-	config.PrivateKey = os.Getenv("PRIVATE_KEY")
-	config.NFTTokenID = os.Getenv("NFT_TOKEN_ID")
-	config.OwnerAddress = os.Getenv("OWNER_ADDRESS")
+	config.PrivateKey = appConfig.PrivateKey
+	config.NFTTokenID = appConfig.NFTTokenID
+	config.OwnerAddress = appConfig.OwnerAddress
+
+	handler := NewPMOAgent(appConfig)
+	if appConfig.HealthSummaryInterval > 0 {
+		safeGo("provider health summaries", func() { handler.health.logSummaries(appConfig.HealthSummaryInterval) })
+	}
+	if appConfig.HTTPAPIPort > 0 {
+		safeGo("http api", func() { handler.serveHTTPAPI(appConfig.HTTPAPIPort) })
+	}
+	if appConfig.WatchInterval > 0 {
+		safeGo("watch loop", func() { handler.runWatches(appConfig.WatchInterval) })
+	}
 
 	enhancedAgent, err := agent.NewEnhancedAgent(&agent.EnhancedAgentConfig{
 		Config:       config,
-		AgentHandler: &PMOAgent{},
+		AgentHandler: handler,
 	})
 
 	if err != nil {
 		log.Fatalf("Failed to initialize enhanced agent: %v", err)
 	}
 
-	log.Println("Starting Price and Market Overview Agent...")
+	slog.Info("Starting Price and Market Overview Agent...")
 	enhancedAgent.Run()
 }
@@ -0,0 +1,1338 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testContextKey struct{}
+
+func TestProcessTaskNilContext(t *testing.T) {
+	a := newTestAgent(t, nil)
+	var got context.Context
+	withCommand(t, "/ctx", command{run: func(_ *PMOAgent, ctx context.Context, _ []string, _ map[string]string) (string, error) {
+		got = ctx
+		return "ok", nil
+	}})
+
+	var ctx context.Context // what a direct caller that never set one passes
+	response, err := a.processTask(ctx, session{}, "/ctx")
+	if err != nil || response != "ok" {
+		t.Fatalf("processTask = %q, %v; want ok, nil", response, err)
+	}
+	if got == nil {
+		t.Error("the handler got a nil context")
+	}
+}
+
+func TestProcessTaskCancelledContext(t *testing.T) {
+	a := newTestAgent(t, nil)
+	ran := false
+	withCommand(t, "/ctx", command{run: func(*PMOAgent, context.Context, []string, map[string]string) (string, error) {
+		ran = true
+		return "ok", nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	response, err := a.processTask(ctx, session{}, "/ctx")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if response != "Request cancelled." {
+		t.Errorf("response = %q, want the cancellation message", response)
+	}
+	if ran {
+		t.Error("the handler ran for a cancelled task")
+	}
+}
+
+func TestProcessTaskPassesContextToHandler(t *testing.T) {
+	a := newTestAgent(t, nil)
+	var got any
+	withCommand(t, "/ctx", command{run: func(_ *PMOAgent, ctx context.Context, _ []string, _ map[string]string) (string, error) {
+		got = ctx.Value(testContextKey{})
+		return "ok", nil
+	}})
+
+	ctx := context.WithValue(context.Background(), testContextKey{}, "task")
+	if _, err := a.processTask(ctx, session{}, "/ctx"); err != nil {
+		t.Fatalf("processTask: %v", err)
+	}
+	if got != "task" {
+		t.Errorf("handler context value = %v, want the task's context", got)
+	}
+}
+
+func TestFetchJSONStopsRetryingWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		cancel()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	a := newTestAgent(t, nil)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var target map[string]any
+	a.fetchJSON(req, &target)
+	if n := hits.Load(); n != 1 {
+		t.Errorf("server hit %d times, want 1 (no retries after cancellation)", n)
+	}
+}
+
+func TestFetchJSONRejectsOversizedBody(t *testing.T) {
+	server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, `{"padding":"`+strings.Repeat("x", 200)+`"}`)
+	})
+	a := newTestAgent(t, map[string]string{"MAX_RESPONSE_BYTES": "100"})
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	var target map[string]any
+	status, err := a.fetchJSON(req, &target)
+	if !errors.Is(err, errResponseTooLarge) {
+		t.Fatalf("err = %v, want errResponseTooLarge", err)
+	}
+	if status != http.StatusOK {
+		t.Errorf("status = %d, want 200", status)
+	}
+	if len(target) != 0 {
+		t.Error("an oversized body was decoded")
+	}
+}
+
+func TestFetchJSONAcceptsBodyAtLimit(t *testing.T) {
+	body := `{"a":"` + strings.Repeat("x", 92) + `"}` // exactly 100 bytes
+	server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, body)
+	})
+	a := newTestAgent(t, map[string]string{"MAX_RESPONSE_BYTES": "100"})
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	var target map[string]string
+	if _, err := a.fetchJSON(req, &target); err != nil {
+		t.Fatalf("fetchJSON: %v", err)
+	}
+	if len(target["a"]) != 92 {
+		t.Error("a body exactly at the limit was not decoded")
+	}
+}
+
+func TestCoinGeckoReportsOversizedResponse(t *testing.T) {
+	server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, `{"id":"bitcoin","padding":"`+strings.Repeat("x", 200)+`"}`)
+	})
+	a := newTestAgent(t, map[string]string{"COINGECKO_BASE_URL": server.URL, "MAX_RESPONSE_BYTES": "100"})
+
+	response, err := a.getCoinGeckoData(context.Background(), "bitcoin", "usd")
+	if !errors.Is(err, errResponseTooLarge) || response != "Error: CoinGecko response too large." {
+		t.Errorf("getCoinGeckoData = %q, %v; want the too-large message", response, err)
+	}
+}
+
+func TestTokenizeInput(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"/price btc", []string{"/price", "btc"}},
+		{"/price btc eth sol", []string{"/price", "btc", "eth", "sol"}},
+		{`/price "shiba inu"`, []string{"/price", "shiba inu"}},
+		{`/price "shiba inu" btc`, []string{"/price", "shiba inu", "btc"}},
+		{`/price "shiba inu`, []string{"/price", "shiba inu"}}, // unterminated quote runs to the end
+		{`/price ""`, []string{"/price", ""}},
+		{"  /price   btc  ", []string{"/price", "btc"}},
+		{"/price\tbtc\t--format=json", []string{"/price", "btc", "--format=json"}},
+		{"/price \t btc\u00a0eth\r\n", []string{"/price", "btc", "eth"}},
+		{"/price\t\"shiba\tinu\"", []string{"/price", "shiba\tinu"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := tokenizeInput(tt.input); !slices.Equal(got, tt.want) {
+			t.Errorf("tokenizeInput(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestCommandsTolerateTabsAndExtraSpaces(t *testing.T) {
+	var gotArgs []string
+	var gotFlags map[string]string
+	withCommand(t, "/echo", command{run: func(_ *PMOAgent, _ context.Context, args []string, flags map[string]string) (string, error) {
+		gotArgs, gotFlags = args, flags
+		return "ok", nil
+	}})
+	a := newTestAgent(t, nil)
+
+	for _, input := range []string{
+		"/echo btc eth --format=json --fresh",
+		"/echo\tbtc\teth\t--format=json\t--fresh",
+		"   /echo    btc   eth  --format=json    --fresh   ",
+		"\t/echo \t btc\t\teth \t--format=json --fresh\t",
+	} {
+		gotArgs, gotFlags = nil, nil
+		if response, _ := a.processTask(context.Background(), session{}, input); response != "ok" {
+			t.Errorf("%q: command not detected, response = %q", input, response)
+			continue
+		}
+		if !slices.Equal(gotArgs, []string{"btc", "eth"}) {
+			t.Errorf("%q: args = %q, want [btc eth]", input, gotArgs)
+		}
+		if len(gotFlags) != 2 || gotFlags["format"] != "json" || !hasFlag(gotFlags, "fresh") {
+			t.Errorf("%q: flags = %v, want format=json and fresh", input, gotFlags)
+		}
+	}
+}
+
+func TestGetCoinIDHyphenatesQuotedNames(t *testing.T) {
+	tests := map[string]string{
+		"btc":       "bitcoin",
+		"shiba inu": "shiba-inu",
+		"Shiba Inu": "shiba-inu",
+		"pepe":      "pepe",
+	}
+	for input, want := range tests {
+		if got := getCoinID(input); got != want {
+			t.Errorf("getCoinID(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestSourceFlagQueriesOnlyThatProvider(t *testing.T) {
+	binanceTicker := `{"symbol":"BTCUSDT","lastPrice":"60000","priceChangePercent":"1.0","quoteVolume":"1000","highPrice":"61000","lowPrice":"59000"}`
+	tests := []struct {
+		source, want string
+	}{
+		{"coingecko", "coingecko"},
+		{"cg", "coingecko"},
+		{"cmc", "cmc"},
+		{"binance", "binance"},
+	}
+	for _, tt := range tests {
+		f := newFakeProviders(t, map[string]http.HandlerFunc{
+			"cmc":       body(cmcQuote("BTC", 60000)),
+			"coingecko": body(cgCoin("bitcoin", "btc", 60000)),
+			"binance":   body(binanceTicker),
+		})
+		a := newTestAgent(t, f.env())
+
+		response, err := a.priceCommand(context.Background(), []string{"btc"}, map[string]string{"source": tt.source})
+		if err != nil || !strings.Contains(response, "60,000") {
+			t.Errorf("--source=%s: response = %q, %v", tt.source, response, err)
+		}
+		for _, name := range []string{"cmc", "coingecko", "binance", "dexscreener"} {
+			want := 0
+			if name == tt.want {
+				want = 1
+			}
+			if got := f.count(name); got != want {
+				t.Errorf("--source=%s: %s called %d times, want %d", tt.source, name, got, want)
+			}
+		}
+	}
+}
+
+func TestSourceFlagDoesNotFallBack(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": body(cmcQuote("BTC", 60000)),
+	})
+	a := newTestAgent(t, f.env())
+
+	response, _ := a.priceCommand(context.Background(), []string{"btc"}, map[string]string{"source": "coingecko"})
+	if !strings.Contains(response, "CoinGecko") || strings.Contains(response, "60,000") {
+		t.Errorf("response = %q, want CoinGecko's own failure", response)
+	}
+	if f.count("cmc") != 0 {
+		t.Error("a forced lookup fell back to CMC")
+	}
+}
+
+func TestSourceFlagRejectsUnknownProvider(t *testing.T) {
+	a := newTestAgent(t, nil)
+	response, _ := a.priceCommand(context.Background(), []string{"btc"}, map[string]string{"source": "kraken"})
+	if !strings.Contains(response, "Unknown source: kraken") {
+		t.Errorf("response = %q, want the unknown source message", response)
+	}
+}
+
+func TestDefaultChainSkipsBinance(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{})
+	a := newTestAgent(t, f.env())
+
+	a.priceCommand(context.Background(), []string{"btc"}, map[string]string{})
+	if f.count("cmc") == 0 || f.count("coingecko") == 0 {
+		t.Error("the default chain did not try CMC and CoinGecko")
+	}
+	if f.count("binance") != 0 {
+		t.Error("the default chain queried Binance")
+	}
+}
+
+func TestDexPairResponseKeepsScientificNotationPrice(t *testing.T) {
+	pairs := []DexPair{{
+		ChainID:    "ethereum",
+		PriceUsd:   "1.2e-9",
+		BaseToken:  Token{Address: "0xabc", Symbol: "TINY"},
+		QuoteToken: Token{Symbol: "WETH"},
+		Liquidity:  &Liquidity{USD: 50000},
+	}}
+
+	parts := parseRawOutput(dexPairResponse(pairs, 0, nil))
+	if got, want := parts["current_price_usd"], "$0.0000000012"; got != want {
+		t.Errorf("current_price_usd = %q, want %q", got, want)
+	}
+	if got, want := parts["price_value"], "0.0000000012"; got != want {
+		t.Errorf("price_value = %q, want %q", got, want)
+	}
+}
+
+func TestFormatPrice(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   string
+	}{
+		{63245, "$63,245.00"},
+		{1, "$1.00"},
+		{0.5, "$0.50"},
+		{0.012345, "$0.01235"},
+		{1.2e-9, "$0.0000000012"},
+		{0, "$0.00"},
+	}
+	for _, tt := range tests {
+		if got := formatPrice(tt.amount, "usd"); got != tt.want {
+			t.Errorf("formatPrice(%v) = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestPriceReportsPartialSuccess(t *testing.T) {
+	prices := map[string]float64{"BTC": 60000, "ETH": 3000}
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			symbol := r.URL.Query().Get("symbol")
+			if price, ok := prices[symbol]; ok {
+				respond(w, http.StatusOK, cmcQuote(symbol, price))
+				return
+			}
+			respond(w, http.StatusOK, `{"status":{"error_code":0},"data":{}}`)
+		},
+	})
+	a := newTestAgent(t, f.env())
+
+	response, err := a.processTask(context.Background(), session{}, "/price btc eth fakecoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, missing, ok := strings.Cut(response, "❌ **Could not find:**")
+	if !ok {
+		t.Fatalf("response has no Could not find section:\n%s", response)
+	}
+	for _, want := range []string{"60,000", "3,000"} {
+		if !strings.Contains(found, want) {
+			t.Errorf("successes missing %s:\n%s", want, response)
+		}
+	}
+	if strings.TrimSpace(missing) != "fakecoin" {
+		t.Errorf("Could not find section = %q, want fakecoin", missing)
+	}
+}
+
+func TestExtractCurrencyShorthand(t *testing.T) {
+	tests := []struct {
+		args     []string
+		want     []string
+		currency string
+	}{
+		{[]string{"btc", "in", "eur"}, []string{"btc"}, "eur"},
+		{[]string{"btc", "eth", "in", "GBP"}, []string{"btc", "eth"}, "gbp"},
+		{[]string{"btc", "eth"}, []string{"btc", "eth"}, ""},
+		// "in" followed by something that isn't a fiat is just another symbol
+		{[]string{"btc", "in", "eth"}, []string{"btc", "in", "eth"}, ""},
+		{[]string{"in", "eur"}, []string{"in", "eur"}, ""},
+	}
+	for _, tt := range tests {
+		flags := map[string]string{}
+		got := extractCurrencyShorthand(tt.args, flags)
+		if !slices.Equal(got, tt.want) || flags["currency"] != tt.currency {
+			t.Errorf("extractCurrencyShorthand(%q) = %q, currency %q; want %q, %q", tt.args, got, flags["currency"], tt.want, tt.currency)
+		}
+	}
+}
+
+func TestPriceCurrencyShorthand(t *testing.T) {
+	var converts []string
+	var mu sync.Mutex
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			converts = append(converts, r.URL.Query().Get("convert"))
+			mu.Unlock()
+			respond(w, http.StatusOK, cmcQuote(r.URL.Query().Get("symbol"), 100))
+		},
+	})
+	a := newTestAgent(t, f.env())
+
+	if _, err := a.processTask(context.Background(), session{}, "/price btc in eur"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(converts, []string{"EUR"}) {
+		t.Errorf("/price btc in eur asked CMC for %q, want one EUR lookup", converts)
+	}
+
+	converts = nil
+	response, err := a.processTask(context.Background(), session{}, "/price btc eth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(converts, []string{"USD", "USD"}) {
+		t.Errorf("/price btc eth asked CMC for %q, want two USD lookups", converts)
+	}
+	if strings.Contains(response, "Could not find") {
+		t.Errorf("/price btc eth reported a missing symbol:\n%s", response)
+	}
+}
+
+func TestFetchJSONRejectsHTMLPage(t *testing.T) {
+	server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html><body>Service unavailable</body></html>"))
+	})
+	a := newTestAgent(t, nil)
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	var target map[string]any
+	status, err := a.fetchJSON(req, &target)
+	if !errors.Is(err, errUnexpectedContentType) {
+		t.Fatalf("err = %v, want errUnexpectedContentType", err)
+	}
+	if status != http.StatusOK {
+		t.Errorf("status = %d, want 200", status)
+	}
+}
+
+func TestFetchJSONRedirects(t *testing.T) {
+	server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		respond(w, http.StatusOK, `{"moved":true}`)
+	})
+
+	// Redirects are rejected by default
+	a := newTestAgent(t, nil)
+	req, _ := http.NewRequest("GET", server.URL+"/old", nil)
+	var target map[string]bool
+	status, err := a.fetchJSON(req, &target)
+	if !errors.Is(err, errUnexpectedRedirect) || status != http.StatusFound {
+		t.Errorf("fetchJSON = %d, %v; want 302, errUnexpectedRedirect", status, err)
+	}
+
+	// FOLLOW_REDIRECTS=true follows them to the final JSON
+	a = newTestAgent(t, map[string]string{"FOLLOW_REDIRECTS": "true"})
+	req, _ = http.NewRequest("GET", server.URL+"/old", nil)
+	target = nil
+	if _, err := a.fetchJSON(req, &target); err != nil || !target["moved"] {
+		t.Errorf("fetchJSON with FOLLOW_REDIRECTS = %v, %v; want the redirected body", target, err)
+	}
+}
+
+func TestFetchJSONSetsUserAgent(t *testing.T) {
+	var got atomic.Value
+	server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		got.Store(r.Header.Get("User-Agent"))
+		respond(w, http.StatusOK, `{}`)
+	})
+	userAgent := func(a *PMOAgent, header string) string {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		if header != "" {
+			req.Header.Set("User-Agent", header)
+		}
+		var target map[string]any
+		if _, err := a.fetchJSON(req, &target); err != nil {
+			t.Fatalf("fetchJSON: %v", err)
+		}
+		return got.Load().(string)
+	}
+
+	a := newTestAgent(t, nil)
+	if ua := userAgent(a, ""); ua != "teneo-crypto-agent/"+version {
+		t.Errorf("User-Agent = %q, want the default", ua)
+	}
+	if ua := userAgent(a, "provider-specific/1"); ua != "provider-specific/1" {
+		t.Errorf("User-Agent = %q, want the request's own header kept", ua)
+	}
+
+	a = newTestAgent(t, map[string]string{"USER_AGENT": "my-deployment/2"})
+	if ua := userAgent(a, ""); ua != "my-deployment/2" {
+		t.Errorf("User-Agent = %q, want the USER_AGENT override", ua)
+	}
+}
+
+func TestPriceWarnsWhenDataIsStale(t *testing.T) {
+	tests := []struct {
+		age  time.Duration
+		want bool
+	}{
+		{2 * time.Hour, true},
+		{time.Minute, false},
+	}
+	for _, tt := range tests {
+		updated := time.Now().Add(-tt.age).UTC().Format(time.RFC3339)
+		coin := strings.Replace(cgCoin("bitcoin", "btc", 60000), "{", `{"last_updated":"`+updated+`",`, 1)
+		f := newFakeProviders(t, map[string]http.HandlerFunc{"coingecko": body(coin)})
+		a := newTestAgent(t, f.env())
+
+		response, err := a.processTask(context.Background(), session{}, "/price btc --source=cg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(response, "⚠️ Data may be stale (updated 2h"); got != tt.want {
+			t.Errorf("data %s old: stale warning shown = %v, want %v:\n%s", tt.age, got, tt.want, response)
+		}
+	}
+}
+
+func TestStalenessFieldsWithoutTimestamp(t *testing.T) {
+	a := newTestAgent(t, nil)
+	if fields := a.stalenessFields(time.Time{}); fields != "" {
+		t.Errorf("stalenessFields(zero) = %q, want nothing for providers without a timestamp", fields)
+	}
+}
+
+func TestFormatCurrency(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		currency string
+		want     string
+	}{
+		{1234.5, "usd", "$1,234.50"},
+		{1234.5, "", "$1,234.50"},
+		{1234.5, "EUR", "€1,234.50"},
+		{1234.5, "gbp", "£1,234.50"},
+		{-12.3, "gbp", "-£12.30"},
+		// No common symbol, so the ISO code follows the number
+		{1234.5, "chf", "1,234.50 CHF"},
+	}
+	for _, tt := range tests {
+		if got := formatCurrency(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("formatCurrency(%v, %q) = %q, want %q", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestFormatLargeCurrency(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		currency string
+		want     string
+	}{
+		{999.99, "usd", "$999.99"},
+		{1234, "usd", "$1.23K"},
+		{890e6, "usd", "$890.00M"},
+		{45.6e9, "usd", "$45.60B"},
+		{1.234e12, "usd", "$1.23T"},
+		{5678e12, "usd", "$5678.00T"},
+		// Rounding up to 1000 of a unit moves to the next unit
+		{999_999, "usd", "$1.00M"},
+		{999_999_999, "usd", "$1.00B"},
+		{999_994_999, "usd", "$999.99M"},
+		{-2.5e9, "usd", "-$2.50B"},
+		{1.5e9, "chf", "1.50B CHF"},
+	}
+	for _, tt := range tests {
+		if got := formatLargeCurrency(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("formatLargeCurrency(%v, %q) = %q, want %q", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestCompactFlag(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"cmc": body(cmcQuote("BTC", 60000))})
+	ctx := context.Background()
+
+	a := newTestAgent(t, f.env())
+	if response, _ := a.processTask(ctx, session{}, "/market btc --compact"); !strings.Contains(response, "$1.14T") ||
+		!strings.Contains(response, "$60,000.00") {
+		t.Errorf("--compact should abbreviate the market cap but not the price:\n%s", response)
+	}
+	if response, _ := a.processTask(ctx, session{}, "/market btc"); !strings.Contains(response, "$1,140,000,000,000.00") {
+		t.Errorf("the market cap should be in full by default:\n%s", response)
+	}
+
+	env := f.env()
+	env["COMPACT_NUMBERS"] = "true"
+	a = newTestAgent(t, env)
+	if response, _ := a.processTask(ctx, session{}, "/market btc --full"); !strings.Contains(response, "$1,140,000,000,000.00") {
+		t.Errorf("--full should override COMPACT_NUMBERS:\n%s", response)
+	}
+}
+
+func TestCoinGeckoCoinWithoutUSDPrice(t *testing.T) {
+	coin := func(prices string) string {
+		return `{"id":"obscure","symbol":"obs","name":"Obscure","market_data":{"current_price":` + prices + `}}`
+	}
+	tests := []struct {
+		name, prices, want string
+	}{
+		{"krw only", `{"krw":1500}`, "₩1,500"},
+		{"no fiat at all", `{}`, "CoinGecko price unavailable in USD for obscure."},
+	}
+	for _, tt := range tests {
+		f := newFakeProviders(t, map[string]http.HandlerFunc{"coingecko": body(coin(tt.prices))})
+		a := newTestAgent(t, f.env())
+
+		response, err := a.processTask(context.Background(), session{}, "/price obscure --source=cg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(response, tt.want) || strings.Contains(response, "$0.00") {
+			t.Errorf("%s: response = %q, want %q and no $0.00", tt.name, response, tt.want)
+		}
+	}
+}
+
+func TestCoinGeckoCoinWithNullMarketCap(t *testing.T) {
+	coin := `{"id":"newcoin","symbol":"new","name":"New Coin","market_data":{
+		"current_price":{"usd":2.5},"market_cap":null,"total_volume":{"usd":null},
+		"price_change_percentage_24h":null,"circulating_supply":null}}`
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"coingecko": body(coin)})
+	a := newTestAgent(t, f.env())
+
+	response, _ := a.processTask(context.Background(), session{}, "/price newcoin --source=cg")
+	if !strings.Contains(response, "$2.50") {
+		t.Errorf("the price is missing:\n%s", response)
+	}
+	for _, absent := range []string{"$0.00", "Market Cap", "24h Volume", "24h Change", "Circulating Supply"} {
+		if strings.Contains(response, absent) {
+			t.Errorf("a null figure was rendered as %q:\n%s", absent, response)
+		}
+	}
+}
+
+func TestCrossCheckFlagsTickerAmbiguity(t *testing.T) {
+	tests := []struct {
+		name       string
+		crossCheck string // PRICE_CROSS_CHECK; empty leaves the default
+		cgPrice    float64
+		ambiguous  bool
+		cgCalls    int
+	}{
+		{"different coins", "", 0.05, true, 1},
+		{"same coin", "", 60100, false, 1},
+		{"opted in", "true", 0.05, true, 1},
+		{"opted out", "false", 0.05, false, 0},
+	}
+	for _, tt := range tests {
+		f := newFakeProviders(t, map[string]http.HandlerFunc{
+			"cmc":       body(cmcQuote("BTC", 60000)),
+			"coingecko": body(cgCoin("bitcoin", "btc", tt.cgPrice)),
+		})
+		env := f.env()
+		if tt.crossCheck != "" {
+			env["PRICE_CROSS_CHECK"] = tt.crossCheck
+		}
+		a := newTestAgent(t, env)
+
+		response, err := a.processTask(context.Background(), session{}, "/price btc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(response, "⚠️ Ticker ambiguity — specify a contract address or ID"); got != tt.ambiguous {
+			t.Errorf("%s: ambiguity warning shown = %v, want %v:\n%s", tt.name, got, tt.ambiguous, response)
+		}
+		if f.count("coingecko") != tt.cgCalls {
+			t.Errorf("%s: CoinGecko called %d times, want %d", tt.name, f.count("coingecko"), tt.cgCalls)
+		}
+	}
+}
+
+func TestCrossCheckSkipsUnmappedCoinGeckoIDs(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": body(cmcQuote("PENGU", 0.02)),
+		"coingecko": func(w http.ResponseWriter, r *http.Request) {
+			respond(w, http.StatusOK, `{"coins":[{"id":"pudgy-penguins","name":"Pudgy Penguins","symbol":"PENGU"}]}`)
+		},
+	})
+	a := newTestAgent(t, f.env())
+
+	response, err := a.processTask(context.Background(), session{}, "/price pengu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(response, "$0.02") || strings.Contains(response, "Ticker ambiguity") {
+		t.Errorf("response = %q, want the CMC price without a warning", response)
+	}
+	// Neither a guessed ID nor a search match is worth a cross-check request
+	if n := f.count("coingecko"); n != 0 {
+		t.Errorf("CoinGecko called %d times, want no cross-check for an unmapped symbol", n)
+	}
+}
+
+func TestFreshFlagBypassesCache(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"cmc": body(cmcQuote("BTC", 60000))})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	a.processTask(ctx, session{}, "/price btc")
+	a.processTask(ctx, session{}, "/price btc")
+	if n := f.count("cmc"); n != 1 {
+		t.Fatalf("CMC called %d times, want the repeat served from cache", n)
+	}
+
+	for _, flag := range []string{"--fresh", "--nocache"} {
+		before := f.count("cmc")
+		if response, _ := a.processTask(ctx, session{}, "/price btc "+flag); !strings.Contains(response, "60,000") {
+			t.Errorf("%s: response = %q", flag, response)
+		}
+		if f.count("cmc") != before+1 {
+			t.Errorf("%s did not trigger an upstream call", flag)
+		}
+	}
+
+	// The fresh result was written back, so a plain lookup is still cached
+	before := f.count("cmc")
+	a.processTask(ctx, session{}, "/price btc")
+	if f.count("cmc") != before {
+		t.Error("the lookup after --fresh missed the cache")
+	}
+}
+
+func TestDexResultShowsContractAddress(t *testing.T) {
+	address := "0x6982508145454Ce325dDbE47a25d4ec3d2311933"
+	pairs := []DexPair{{
+		ChainID:    "ethereum",
+		PriceUsd:   "0.000001",
+		BaseToken:  Token{Address: address, Symbol: "PEPE"},
+		QuoteToken: Token{Symbol: "WETH"},
+		Liquidity:  &Liquidity{USD: 50000},
+	}}
+	a := newTestAgent(t, nil)
+
+	raw := dexPairResponse(pairs, 0, nil)
+	if got := parseRawOutput(raw)["contract_address"]; got != address {
+		t.Errorf("contract_address = %q, want %q", got, address)
+	}
+	if output := a.formatOutput(raw, map[string]string{}); !strings.Contains(output, "- **Contract:** `0x6982…1933`") {
+		t.Errorf("output has no truncated contract line:\n%s", output)
+	}
+}
+
+func TestTruncateAddress(t *testing.T) {
+	if got := truncateAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"); got != "0xC02a…6Cc2" {
+		t.Errorf("truncateAddress = %q", got)
+	}
+	if got := truncateAddress("0xabc"); got != "0xabc" {
+		t.Errorf("truncateAddress of a short address = %q, want it unchanged", got)
+	}
+}
+
+func TestCMCPlatformShowsContract(t *testing.T) {
+	token := `{"status":{"error_code":0},"data":{"LINK":{"id":1975,"name":"Chainlink","symbol":"LINK","cmc_rank":15,
+		"platform":{"id":1027,"name":"Ethereum","symbol":"ETH","token_address":"0x514910771AF9Ca656af840dff83E8264EcF986CA"},
+		"quote":{"USD":{"price":15,"volume_24h":1000000,"market_cap":9000000000,"percent_change_24h":1.2}}}}}`
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("symbol") == "LINK" {
+				respond(w, http.StatusOK, token)
+				return
+			}
+			// Native coins have "platform": null
+			respond(w, http.StatusOK, strings.Replace(cmcQuote("BTC", 60000), `"cmc_rank":1,`, `"cmc_rank":1,"platform":null,`, 1))
+		},
+	})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	if response, _ := a.processTask(ctx, session{}, "/price link"); !strings.Contains(response, "- **Contract:** `0x5149…86CA` on Ethereum") {
+		t.Errorf("CMC token is missing its contract line:\n%s", response)
+	}
+	if response, _ := a.processTask(ctx, session{}, "/price btc"); strings.Contains(response, "Contract") {
+		t.Errorf("a native coin shows a contract line:\n%s", response)
+	}
+}
+
+func TestCMCIDLookup(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("id") != "1" || r.URL.Query().Has("symbol") {
+				t.Errorf("CMC query = %q, want id=1 and no symbol", r.URL.RawQuery)
+			}
+			// ID lookups are keyed by the ID rather than the symbol
+			respond(w, http.StatusOK, strings.Replace(cmcQuote("BTC", 60000), `"BTC":{"id":1,"name":"BTC"`, `"1":{"id":1,"name":"Bitcoin"`, 1))
+		},
+	})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	if response, _ := a.processTask(ctx, session{}, "/price cmc:1"); !strings.Contains(response, "Bitcoin") || !strings.Contains(response, "$60,000.00") {
+		t.Errorf("cmc:1 did not resolve to Bitcoin:\n%s", response)
+	}
+
+	before := f.count("cmc")
+	for input, want := range map[string]string{
+		"/price cmc:abc":           "Invalid CoinMarketCap ID: abc",
+		"/price cmc:1 --source=cg": "cmc:<id> targets can only be looked up on CoinMarketCap.",
+	} {
+		if response, _ := a.processTask(ctx, session{}, input); !strings.Contains(response, want) {
+			t.Errorf("%s: response = %q, want %q", input, response, want)
+		}
+	}
+	if f.count("cmc") != before {
+		t.Error("an invalid cmc:<id> lookup reached CoinMarketCap")
+	}
+}
+
+func TestCMCProviderDisabledWithoutKey(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"cmc": body(cmcQuote("BTC", 60000))})
+	env := f.env()
+	env["CMC_API_KEY"] = ""
+	a := newTestAgent(t, env)
+
+	for _, provider := range a.cexProviders {
+		if provider.name == "cmc" {
+			t.Error("the CMC provider is in the chain without a key")
+		}
+	}
+	if _, ok := a.sourceProviders["cmc"]; ok {
+		t.Error("the CMC provider is available to --source without a key")
+	}
+
+	a.processTask(context.Background(), session{}, "/price btc")
+	if f.count("cmc") != 0 {
+		t.Error("CMC was called without a key")
+	}
+}
+
+func TestCoinGeckoErrorBodyWithStatus200(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"coingecko": body(`{"error":"coin not found"}`)})
+	a := newTestAgent(t, f.env())
+
+	response, err := a.getCoinGeckoData(context.Background(), "notacoin", "usd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != "Error: CoinGecko could not find data for notacoin." {
+		t.Errorf("getCoinGeckoData = %q, want the not-found message", response)
+	}
+
+	response, _ = a.processTask(context.Background(), session{}, "/price notacoin")
+	if !strings.Contains(response, "Could not find") || strings.Contains(response, "$0.00") {
+		t.Errorf("/price = %q, want a not-found result rather than $0.00", response)
+	}
+}
+
+func TestTrimTargets(t *testing.T) {
+	address := "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"
+	got := trimTargets([]string{"btc,", "(eth)", `"sol"`, address, "(" + address + ").", "cmc:1", ",,"})
+	want := []string{"btc", "eth", "sol", address, address, "cmc:1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("trimTargets = %q, want %q", got, want)
+	}
+}
+
+func TestPriceTrimsPastedPunctuation(t *testing.T) {
+	var symbols []string
+	var mu sync.Mutex
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			symbol := r.URL.Query().Get("symbol")
+			mu.Lock()
+			symbols = append(symbols, symbol)
+			mu.Unlock()
+			respond(w, http.StatusOK, cmcQuote(symbol, 100))
+		},
+	})
+	a := newTestAgent(t, f.env())
+
+	response, err := a.processTask(context.Background(), session{}, "/price btc, (eth)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(symbols)
+	if !slices.Equal(symbols, []string{"BTC", "ETH"}) {
+		t.Errorf("CMC was asked for %q, want BTC and ETH", symbols)
+	}
+	if strings.Contains(response, "Could not find") {
+		t.Errorf("response reports a missing symbol:\n%s", response)
+	}
+}
+
+func TestDexMinimumLiquidity(t *testing.T) {
+	// testchain has no GoPlus coverage, so no risk scan is attempted
+	pairs := `{"pairs":[
+		{"chainId":"testchain","priceUsd":"1.10","baseToken":{"address":"0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","symbol":"TKN"},"quoteToken":{"symbol":"WETH"},"liquidity":{"usd":500}},
+		{"chainId":"testchain","priceUsd":"1.00","baseToken":{"address":"0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","symbol":"TKN"},"quoteToken":{"symbol":"USDC"},"liquidity":{"usd":20000}}
+	]}`
+	address := "0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	tests := []struct {
+		name, minConfig, flag, want string
+	}{
+		{"no threshold uses the first pair", "0", "", "$1.10"},
+		{"configured threshold skips the micro-pool", "1000", "", "$1.00"},
+		{"--minliq overrides the config", "0", " --minliq=1000", "$1.00"},
+		{"nothing qualifies", "50000", "", "no pools that meet the minimum liquidity threshold ($50,000.00)"},
+	}
+	for _, tt := range tests {
+		f := newFakeProviders(t, map[string]http.HandlerFunc{"dexscreener": body(pairs)})
+		env := f.env()
+		env["MIN_LIQUIDITY_USD"] = tt.minConfig
+		env["TRUSTED_QUOTE_TOKENS"] = "DAI" // neither pair, so the first deep enough one wins
+		a := newTestAgent(t, env)
+
+		response, err := a.processTask(context.Background(), session{}, "/price "+address+tt.flag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(response, tt.want) {
+			t.Errorf("%s: response missing %q:\n%s", tt.name, tt.want, response)
+		}
+	}
+}
+
+func TestSelectDexPairFollowsTrustedQuotes(t *testing.T) {
+	pair := func(quote string, liquidity float64) DexPair {
+		return DexPair{QuoteToken: Token{Symbol: quote}, Liquidity: &Liquidity{USD: liquidity}}
+	}
+	pairs := []DexPair{pair("SOL", 90000), pair("WETH", 50000), pair("usdt", 30000), pair("USDC", 100)}
+	tests := []struct {
+		trusted []string
+		minLiq  float64
+		want    string
+	}{
+		{defaultTrustedQuoteTokens, 0, "USDC"},
+		{defaultTrustedQuoteTokens, 1000, "usdt"}, // USDC is too shallow
+		{[]string{"WETH", "USDT"}, 0, "WETH"},
+		{[]string{"USDT", "WETH"}, 0, "usdt"},
+		{[]string{"DAI"}, 0, "SOL"}, // no trusted quote, so the first pair
+		{nil, 0, "SOL"},
+	}
+	for _, tt := range tests {
+		got := selectDexPair(pairs, tt.minLiq, tt.trusted)
+		if got == nil || got.QuoteToken.Symbol != tt.want {
+			t.Errorf("selectDexPair(%v, minliq %v) = %+v, want the %s pair", tt.trusted, tt.minLiq, got, tt.want)
+		}
+	}
+}
+
+func TestTrustedQuoteTokensOverride(t *testing.T) {
+	pairs := `{"pairs":[
+		{"chainId":"testchain","priceUsd":"1.02","baseToken":{"address":"0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","symbol":"TKN"},"quoteToken":{"symbol":"USDC"},"liquidity":{"usd":20000}},
+		{"chainId":"testchain","priceUsd":"0.98","baseToken":{"address":"0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","symbol":"TKN"},"quoteToken":{"symbol":"WETH"},"liquidity":{"usd":20000}}
+	]}`
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"dexscreener": body(pairs)})
+	env := f.env()
+	env["TRUSTED_QUOTE_TOKENS"] = " weth , usdc,"
+	a := newTestAgent(t, env)
+
+	if !slices.Equal(a.config.TrustedQuoteTokens, []string{"WETH", "USDC"}) {
+		t.Errorf("TrustedQuoteTokens = %v, want [WETH USDC]", a.config.TrustedQuoteTokens)
+	}
+	response, _ := a.processTask(context.Background(), session{}, "/price 0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	if !strings.Contains(response, "$0.98") {
+		t.Errorf("the WETH pair was not preferred:\n%s", response)
+	}
+}
+
+func TestFilterPairsByChain(t *testing.T) {
+	pair := func(chain string, liquidity float64) DexPair {
+		return DexPair{ChainID: chain, Liquidity: &Liquidity{USD: liquidity}}
+	}
+	pairs := []DexPair{pair("ethereum", 5000), pair("bsc", 100), pair("ethereum", 300), pair("base", 0)}
+
+	if matching, message := filterPairsByChain(pairs, "BSC"); len(matching) != 1 || message != "" {
+		t.Errorf("filterPairsByChain(BSC) = %v, %q; want the one bsc pair", matching, message)
+	}
+	if matching, _ := filterPairsByChain(pairs, ""); len(matching) != len(pairs) {
+		t.Errorf("filterPairsByChain without a chain kept %d pairs, want all", len(matching))
+	}
+	// base is left out of the list since that pool has no liquidity
+	want := "Dexscreener found no pairs for that token on polygon, but it is available on: ethereum, bsc."
+	if matching, message := filterPairsByChain(pairs, "Polygon"); matching != nil || message != want {
+		t.Errorf("filterPairsByChain(Polygon) = %v, %q; want %q", matching, message, want)
+	}
+	if _, message := filterPairsByChain([]DexPair{pair("base", 0)}, "polygon"); message != "Dexscreener found no pairs for that token on polygon." {
+		t.Errorf("message without liquidity elsewhere = %q", message)
+	}
+}
+
+func TestDexChainFilterListsOtherChains(t *testing.T) {
+	pairs := `{"pairs":[
+		{"chainId":"ethereum","priceUsd":"1.00","baseToken":{"address":"0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","symbol":"TKN"},"quoteToken":{"symbol":"USDC"},"liquidity":{"usd":20000}},
+		{"chainId":"bsc","priceUsd":"1.01","baseToken":{"address":"0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","symbol":"TKN"},"quoteToken":{"symbol":"USDT"},"liquidity":{"usd":9000}}
+	]}`
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"dexscreener": body(pairs)})
+	a := newTestAgent(t, f.env())
+
+	response, _ := a.processTask(context.Background(), session{}, "/price 0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa --chain=polygon")
+	if !strings.Contains(response, "no pairs for that token on polygon, but it is available on: ethereum, bsc.") {
+		t.Errorf("response = %q, want the chains the token trades on", response)
+	}
+}
+
+func TestCMCRateLimitFailsOver(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte("Too Many Requests"))
+		},
+		"coingecko": body(cgCoin("bitcoin", "btc", 60000)),
+	})
+	env := f.env()
+	env["RETRY_BUDGET_PER_MINUTE"] = "0" // fail over at once instead of backing off
+	a := newTestAgent(t, env)
+
+	response, err := a.getCMCData(context.Background(), "btc", "usd")
+	if !errors.Is(err, errUpstreamStatus) || response != "Error: CoinMarketCap rate limit reached. Try again shortly." {
+		t.Errorf("getCMCData = %q, %v; want the rate limit message", response, err)
+	}
+
+	response, _ = a.processTask(context.Background(), session{}, "/price btc")
+	if !strings.Contains(response, "60,000") || !strings.Contains(response, "COINGECKO") {
+		t.Errorf("/price = %q, want CoinGecko's data after the CMC rate limit", response)
+	}
+}
+
+func TestDexQuoteFlagShowsBothSides(t *testing.T) {
+	address := "0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"dexscreener": body(`{"pairs":[{"chainId":"testchain",
+		"priceUsd":"1.50","priceNative":"0.0005","baseToken":{"address":"` + address + `","symbol":"TKN"},
+		"quoteToken":{"symbol":"WETH"},"liquidity":{"usd":50000}}]}`)})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	baseSide := "- **Pair (base → quote):** 1 TKN = 0.0005 WETH"
+	quoteSide := "- **Pair (quote → base):** 1 WETH = 2,000.00 TKN"
+	quoteUSD := "- **WETH Price (USD):** $3,000.00"
+
+	response, _ := a.processTask(ctx, session{}, "/price "+address)
+	if !strings.Contains(response, baseSide) || strings.Contains(response, quoteSide) {
+		t.Errorf("without --quote, want only the base side:\n%s", response)
+	}
+	response, _ = a.processTask(ctx, session{}, "/price "+address+" --quote")
+	for _, want := range []string{baseSide, quoteSide, quoteUSD} {
+		if !strings.Contains(response, want) {
+			t.Errorf("with --quote, response missing %q:\n%s", want, response)
+		}
+	}
+}
+
+func TestFetchJSONRetriesTruncatedBodyOnce(t *testing.T) {
+	tests := []struct {
+		name      string
+		truncated func(w http.ResponseWriter)
+	}{
+		{"JSON cut short", func(w http.ResponseWriter) {
+			respond(w, http.StatusOK, `{"price":63245.1`)
+		}},
+		{"connection dropped mid-body", func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", "100")
+			w.Write([]byte(`{"price":63245.1`))
+		}},
+	}
+	for _, tt := range tests {
+		var hits atomic.Int32
+		server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if hits.Add(1) == 1 {
+				tt.truncated(w)
+				return
+			}
+			respond(w, http.StatusOK, `{"price":63245.12}`)
+		})
+		a := newTestAgent(t, nil)
+
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		var target map[string]float64
+		if _, err := a.fetchJSON(req, &target); err != nil {
+			t.Fatalf("%s: fetchJSON: %v", tt.name, err)
+		}
+		if target["price"] != 63245.12 || hits.Load() != 2 {
+			t.Errorf("%s: got %v after %d requests, want the complete body on the retry", tt.name, target, hits.Load())
+		}
+	}
+}
+
+func TestFetchJSONGivesUpAfterSecondTruncatedBody(t *testing.T) {
+	var hits atomic.Int32
+	server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		respond(w, http.StatusOK, `{"price":63`)
+	})
+	a := newTestAgent(t, nil)
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	var target map[string]float64
+	if _, err := a.fetchJSON(req, &target); !errors.Is(err, errTruncatedBody) {
+		t.Errorf("err = %v, want errTruncatedBody", err)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("server hit %d times, want exactly one retry", n)
+	}
+}
+
+func TestFetchJSONDoesNotRetrySchemaMismatch(t *testing.T) {
+	var hits atomic.Int32
+	server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		respond(w, http.StatusOK, `{"price":"not a number"}`)
+	})
+	a := newTestAgent(t, nil)
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	var target map[string]float64
+	_, err := a.fetchJSON(req, &target)
+	if err == nil || errors.Is(err, errTruncatedBody) {
+		t.Errorf("err = %v, want a decode error that isn't errTruncatedBody", err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("server hit %d times, want no retry", n)
+	}
+}
+
+func TestRawFlagReturnsBarePrice(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"cmc": body(cmcQuote("BTC", 63245.12))})
+	a := newTestAgent(t, f.env())
+
+	response, err := a.processTask(context.Background(), session{}, "/price btc --raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price, err := strconv.ParseFloat(response, 64); err != nil || price != 63245.12 {
+		t.Errorf("--raw = %q, want a parseable 63245.12", response)
+	}
+}
+
+func TestRawFlagFailsCleanly(t *testing.T) {
+	f := newFakeProviders(t, nil)
+	a := newTestAgent(t, f.env())
+
+	for _, input := range []string{"/price fakecoin --raw", "/price btc eth --raw"} {
+		response, err := a.processTask(context.Background(), session{}, input)
+		if err == nil || !strings.HasPrefix(response, "error: ") {
+			t.Errorf("%s = %q, %v; want an error message and an error", input, response, err)
+		}
+		if _, parseErr := strconv.ParseFloat(response, 64); parseErr == nil {
+			t.Errorf("%s returned a number on failure", input)
+		}
+	}
+}
+
+func TestIsContractAddress(t *testing.T) {
+	valid := "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{valid, true},
+		{valid[:41], false},       // one hex digit short
+		{valid + "0", false},      // one too many
+		{valid[:41] + "g", false}, // not hex
+		{"1x" + valid[2:], false}, // wrong prefix
+		{"0xdeadbeef", false},     // hex-looking ticker
+	}
+	for _, tt := range tests {
+		if got := isContractAddress(tt.input); got != tt.want {
+			t.Errorf("isContractAddress(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNearAddressFallsThroughToSymbolLookup(t *testing.T) {
+	f := newFakeProviders(t, nil)
+	a := newTestAgent(t, f.env())
+	nearAddress := "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc" // 41 characters
+
+	response, _ := a.processTask(context.Background(), session{}, "/price "+nearAddress)
+	if f.count("dexscreener") != 0 || f.count("cmc") == 0 {
+		t.Errorf("a 41-character near-address was not looked up as a symbol (dexscreener %d, cmc %d calls)", f.count("dexscreener"), f.count("cmc"))
+	}
+	if !strings.Contains(response, "is not a valid contract address (expected 0x followed by 40 hex characters)") {
+		t.Errorf("response = %q, want the malformed address hint", response)
+	}
+}
+
+func TestPriceNumericTicker(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			if symbol := r.URL.Query().Get("symbol"); symbol != "404" {
+				t.Errorf("CMC asked for symbol=%q, want 404", symbol)
+			}
+			respond(w, http.StatusOK, cmcQuote("404", 0.5))
+		},
+	})
+	a := newTestAgent(t, f.env())
+
+	response, _ := a.processTask(context.Background(), session{}, "/price 404")
+	if f.count("dexscreener") != 0 || !strings.Contains(response, "$0.50") {
+		t.Errorf("a numeric ticker was not looked up as a symbol (dexscreener %d calls):\n%s", f.count("dexscreener"), response)
+	}
+}
+
+func TestLookupTokensKeepsInputOrder(t *testing.T) {
+	// Earlier symbols answer later, so workers finish in reverse order
+	delays := map[string]time.Duration{"BTC": 60 * time.Millisecond, "ETH": 30 * time.Millisecond, "SOL": 0}
+	var finished []string
+	var mu sync.Mutex
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			symbol := r.URL.Query().Get("symbol")
+			time.Sleep(delays[symbol])
+			mu.Lock()
+			finished = append(finished, symbol)
+			mu.Unlock()
+			respond(w, http.StatusOK, cmcQuote(symbol, 100))
+		},
+	})
+	a := newTestAgent(t, f.env())
+
+	response, err := a.processTask(context.Background(), session{}, "/price btc eth sol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(finished, []string{"SOL", "ETH", "BTC"}) {
+		t.Fatalf("workers finished in order %v, want the reverse of the input", finished)
+	}
+	blocks := strings.Split(response, "\n\n---\n\n")
+	if len(blocks) != 3 {
+		t.Fatalf("response has %d blocks, want 3:\n%s", len(blocks), response)
+	}
+	for i, symbol := range []string{"BTC", "ETH", "SOL"} {
+		if !strings.Contains(blocks[i], symbol+" Price & Market Overview") {
+			t.Errorf("block %d is not %s:\n%s", i+1, symbol, blocks[i])
+		}
+	}
+}
+
+func TestCoinGeckoProKeyDetection(t *testing.T) {
+	var demoCalls, proCalls atomic.Int32
+	server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/demo/"):
+			demoCalls.Add(1)
+			respond(w, http.StatusUnauthorized, `{"status":{"error_code":10010,"error_message":"pro key"}}`)
+		case strings.HasPrefix(r.URL.Path, "/pro/") && r.Header.Get("x-cg-pro-api-key") == "pro-key":
+			proCalls.Add(1)
+			respond(w, http.StatusOK, cgCoin("bitcoin", "btc", 60000))
+		default:
+			respond(w, http.StatusForbidden, `{}`)
+		}
+	})
+	a := newTestAgent(t, map[string]string{
+		"COINGECKO_API_KEY":      "pro-key",
+		"COINGECKO_BASE_URL":     server.URL + "/demo",
+		"COINGECKO_PRO_BASE_URL": server.URL + "/pro",
+	})
+	ctx := context.Background()
+
+	response, err := a.getCoinGeckoData(ctx, "bitcoin", "usd")
+	if err != nil || !strings.Contains(response, "60,000") {
+		t.Fatalf("getCoinGeckoData = %q, %v; want the Pro API's data", response, err)
+	}
+	if demoCalls.Load() != 1 || proCalls.Load() != 1 || !a.coinGeckoPro.Load() {
+		t.Fatalf("demo %d, pro %d calls, detected %v; want one each and Pro remembered", demoCalls.Load(), proCalls.Load(), a.coinGeckoPro.Load())
+	}
+
+	// The choice is remembered, so the demo API isn't tried again
+	a.getCoinGeckoData(ctx, "bitcoin", "usd")
+	if demoCalls.Load() != 1 || proCalls.Load() != 2 {
+		t.Errorf("after detection: demo %d, pro %d calls; want the Pro API only", demoCalls.Load(), proCalls.Load())
+	}
+}
+
+func TestCoinGeckoDemoKeyStaysOnDemo(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"coingecko": body(cgCoin("bitcoin", "btc", 60000))})
+	env := f.env()
+	env["COINGECKO_API_KEY"] = "demo-key"
+	a := newTestAgent(t, env)
+
+	if response, _ := a.getCoinGeckoData(context.Background(), "bitcoin", "usd"); !strings.Contains(response, "60,000") {
+		t.Errorf("getCoinGeckoData = %q", response)
+	}
+	if a.coinGeckoPro.Load() {
+		t.Error("a working demo key was switched to the Pro API")
+	}
+}
+
+func TestPriceGuardsAgainstFillerWords(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"cmc": body(cmcQuote("BTC", 60000))})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	for _, input := range []string{"/price price", "/market market", "/price of", "/price convert"} {
+		response, _ := a.processTask(ctx, session{}, input)
+		if !strings.Contains(response, "Usage: ") {
+			t.Errorf("%s = %q, want the usage help", input, response)
+		}
+	}
+	if n := f.count("cmc"); n != 0 {
+		t.Errorf("filler words triggered %d lookups", n)
+	}
+
+	if response, _ := a.processTask(ctx, session{}, "/price btc"); !strings.Contains(response, "60,000") {
+		t.Errorf("/price btc = %q, want a normal lookup", response)
+	}
+}
+
+func TestOnlyFillerTargets(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"price"}, true},
+		{[]string{"price", "of"}, true},
+		{[]string{"price", "of", "btc"}, false},
+		{[]string{"btc"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := onlyFillerTargets(tt.args); got != tt.want {
+			t.Errorf("onlyFillerTargets(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestFormatChangeDecimals(t *testing.T) {
+	tests := []struct {
+		decimals string
+		percent  float64
+		want     string
+		signed   string
+		badge    string
+	}{
+		{"2", 1.23456, "1.23%", "+1.23%", "**🟢 +1.23%**"},
+		{"2", -0.004, "0.00%", "0.00%", "**🟢 +0.00%**"}, // rounds to zero, so no "-0.00%"
+		{"4", 1.23456, "1.2346%", "+1.2346%", "**🟢 +1.2346%**"},
+		{"4", -0.004, "-0.0040%", "-0.0040%", "**🔴 -0.0040%**"},
+	}
+	for _, tt := range tests {
+		a := newTestAgent(t, map[string]string{"CHANGE_DECIMALS": tt.decimals})
+		got := a.formatChange(tt.percent)
+		if got != tt.want {
+			t.Errorf("CHANGE_DECIMALS=%s: formatChange(%v) = %q, want %q", tt.decimals, tt.percent, got, tt.want)
+		}
+		if signed := a.formatSignedChange(tt.percent); signed != tt.signed {
+			t.Errorf("CHANGE_DECIMALS=%s: formatSignedChange(%v) = %q, want %q", tt.decimals, tt.percent, signed, tt.signed)
+		}
+		if badge := changeBadge(got); badge != tt.badge {
+			t.Errorf("CHANGE_DECIMALS=%s: changeBadge(%q) = %q, want %q", tt.decimals, got, badge, tt.badge)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// --- CoinGecko Markets (Multi-Timeframe Change Data) ---
+
+// changeTimeframes are the price change windows CoinGecko's markets endpoint
+// can return, in ascending order.
+var changeTimeframes = []string{"1h", "24h", "7d", "14d", "30d", "200d", "1y"}
+
+// CoinGeckoMarket is one row of /coins/markets. Change percentages are
+// pointers because CoinGecko returns null for windows a coin lacks history for.
+type CoinGeckoMarket struct {
+	ID            string  `json:"id"`
+	Symbol        string  `json:"symbol"`
+	Name          string  `json:"name"`
+	CurrentPrice  float64 `json:"current_price"`
+	MarketCap     float64 `json:"market_cap"`
+	MarketCapRank int     `json:"market_cap_rank"`
+	TotalVolume   float64 `json:"total_volume"`
+
+	Change1h   *float64 `json:"price_change_percentage_1h_in_currency"`
+	Change24h  *float64 `json:"price_change_percentage_24h_in_currency"`
+	Change7d   *float64 `json:"price_change_percentage_7d_in_currency"`
+	Change14d  *float64 `json:"price_change_percentage_14d_in_currency"`
+	Change30d  *float64 `json:"price_change_percentage_30d_in_currency"`
+	Change200d *float64 `json:"price_change_percentage_200d_in_currency"`
+	Change1y   *float64 `json:"price_change_percentage_1y_in_currency"`
+}
+
+// changeFor returns the percent change over a timeframe from changeTimeframes.
+func (m CoinGeckoMarket) changeFor(timeframe string) (float64, bool) {
+	var change *float64
+	switch timeframe {
+	case "1h":
+		change = m.Change1h
+	case "24h":
+		change = m.Change24h
+	case "7d":
+		change = m.Change7d
+	case "14d":
+		change = m.Change14d
+	case "30d":
+		change = m.Change30d
+	case "200d":
+		change = m.Change200d
+	case "1y":
+		change = m.Change1y
+	}
+	if change == nil {
+		return 0, false
+	}
+	return *change, true
+}
+
+func isChangeTimeframe(timeframe string) bool {
+	for _, tf := range changeTimeframes {
+		if tf == timeframe {
+			return true
+		}
+	}
+	return false
+}
+
+// marketsCacheTTL caps how long /coins/markets lists are reused. The endpoint
+// is one of CoinGecko's most rate-limited, so even a short reuse window spares
+// a burst of /category or /perf calls from tripping a 429.
+const marketsCacheTTL = 30 * time.Second
+
+// getCoinMarkets queries /coins/markets with the given extra parameters,
+// always requesting every change timeframe. On failure it returns a
+// human-readable message alongside the error (which may be nil). When
+// CoinGecko rate limits the request and an older copy of the same list is
+// cached, that copy is returned with a notice saying how old it is.
+func (a *PMOAgent) getCoinMarkets(ctx context.Context, params url.Values) ([]CoinGeckoMarket, string, error) {
+	if params.Get("vs_currency") == "" {
+		params.Set("vs_currency", "usd")
+	}
+	params.Set("price_change_percentage", strings.Join(changeTimeframes, ","))
+	path := "/coins/markets?" + params.Encode()
+
+	var markets []CoinGeckoMarket
+	if cached, ok := a.marketsCache.get(path); ok && json.Unmarshal([]byte(cached), &markets) == nil {
+		return markets, "", nil
+	}
+
+	req, err := a.newCoinGeckoRequest(ctx, path)
+	if err != nil {
+		slog.Error("Error creating CG markets request", "err", err)
+		return nil, "Error creating HTTP request.", err
+	}
+
+	fetchedAt := time.Now()
+	status, err := a.fetchJSON(req, &markets)
+	if errors.Is(err, errResponseTooLarge) {
+		return nil, "Error: CoinGecko response too large.", err
+	}
+	if status == 0 {
+		return nil, "Error contacting CoinGecko API.", err
+	}
+	if status == http.StatusTooManyRequests {
+		slog.Warn("CoinGecko markets API rate limited the request")
+		if cached, age, ok := a.marketsCache.getStale(path); ok {
+			var stale []CoinGeckoMarket
+			if json.Unmarshal([]byte(cached), &stale) == nil {
+				return stale, fmt.Sprintf("⚠️ CoinGecko is rate limiting market lists, showing data from %s ago.", formatAge(age)), nil
+			}
+		}
+		return nil, "CoinGecko's market list is temporarily unavailable (rate limited), please try again shortly.", nil
+	}
+	if status != http.StatusOK {
+		slog.Warn("CoinGecko markets API returned an error status", "status", status)
+		return nil, fmt.Sprintf("Error: CoinGecko API returned status %d. Could not load market data.", status), nil
+	}
+	if err != nil {
+		return nil, "Error processing CG API response.", err
+	}
+	// A null body decodes to a nil slice, which callers take as a failure, so
+	// it needs a message and must not be cached
+	if markets == nil {
+		return nil, "CoinGecko returned no market data.", nil
+	}
+
+	if encoded, err := json.Marshal(markets); err == nil {
+		a.marketsCache.set(path, string(encoded), fetchedAt)
+	}
+	return markets, "", nil
+}
+
+// getMarketsForSymbols resolves symbols to CoinGecko IDs and fetches their
+// market rows, keyed by the original (lower-cased) symbol. Symbols CoinGecko
+// doesn't know are simply absent from the result. A stale-data notice from
+// getCoinMarkets is passed through alongside the rows.
+func (a *PMOAgent) getMarketsForSymbols(ctx context.Context, symbols []string, currency string) (map[string]CoinGeckoMarket, string, error) {
+	// Aliases such as "btc" and "bitcoin" share an ID, and each gets the row
+	idToSymbols := make(map[string][]string, len(symbols))
+	var ids []string
+	for _, symbol := range symbols {
+		id := getCoinID(symbol)
+		if _, seen := idToSymbols[id]; !seen {
+			ids = append(ids, id)
+		}
+		idToSymbols[id] = append(idToSymbols[id], strings.ToLower(symbol))
+	}
+
+	params := url.Values{}
+	params.Set("ids", strings.Join(ids, ","))
+	params.Set("vs_currency", currency)
+	markets, message, err := a.getCoinMarkets(ctx, params)
+	if markets == nil {
+		return nil, message, err
+	}
+
+	bySymbol := make(map[string]CoinGeckoMarket, len(markets))
+	for _, market := range markets {
+		for _, symbol := range idToSymbols[market.ID] {
+			bySymbol[symbol] = market
+		}
+	}
+	return bySymbol, message, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoinMarketsServesStaleListWhenRateLimited(t *testing.T) {
+	var limited atomic.Bool
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": func(w http.ResponseWriter, r *http.Request) {
+			if limited.Load() {
+				respond(w, http.StatusTooManyRequests, `{"status":{"error_code":429}}`)
+				return
+			}
+			respond(w, http.StatusOK, `[{"id":"bitcoin","symbol":"btc","name":"Bitcoin","current_price":60000}]`)
+		},
+	})
+	env := f.env()
+	env["RETRY_BUDGET_PER_MINUTE"] = "0"
+	a := newTestAgent(t, env)
+	ctx := context.Background()
+	params := func() url.Values { return url.Values{"ids": {"bitcoin"}} }
+
+	if markets, message, err := a.getCoinMarkets(ctx, params()); len(markets) != 1 || message != "" || err != nil {
+		t.Fatalf("getCoinMarkets = %v, %q, %v", markets, message, err)
+	}
+	// A repeat within the TTL is served from the cache
+	a.getCoinMarkets(ctx, params())
+	if n := f.count("coingecko"); n != 1 {
+		t.Errorf("CoinGecko called %d times, want the repeat cached", n)
+	}
+
+	// Expire the list, then get rate limited
+	a.marketsCache.mu.Lock()
+	for key, entry := range a.marketsCache.entries {
+		entry.storedAt = entry.storedAt.Add(-5 * time.Minute)
+		entry.expiresAt = entry.expiresAt.Add(-5 * time.Minute)
+		a.marketsCache.entries[key] = entry
+	}
+	a.marketsCache.mu.Unlock()
+	limited.Store(true)
+
+	markets, message, _ := a.getCoinMarkets(ctx, params())
+	if len(markets) != 1 || markets[0].ID != "bitcoin" {
+		t.Errorf("rate-limited lookup returned %v, want the stale list", markets)
+	}
+	if !strings.Contains(message, "showing data from 5m") {
+		t.Errorf("stale notice = %q", message)
+	}
+
+	// A list that was never cached can't be served
+	markets, message, _ = a.getCoinMarkets(ctx, url.Values{"ids": {"ethereum"}})
+	if markets != nil || message != "CoinGecko's market list is temporarily unavailable (rate limited), please try again shortly." {
+		t.Errorf("uncached rate-limited lookup = %v, %q", markets, message)
+	}
+}
+
+func TestCoinMarketsRejectsNullBody(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"coingecko": body(`null`)})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	for range 2 {
+		response, _ := a.processTask(ctx, session{}, "/perf btc eth")
+		if !strings.Contains(response, "CoinGecko returned no market data.") {
+			t.Errorf("/perf on a null market list = %q, want the no-data message", response)
+		}
+	}
+	if n := f.count("coingecko"); n != 2 {
+		t.Errorf("CoinGecko called %d times, want the null list left uncached", n)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// --- Wrapped Native Tokens ---
+
+// wrappedNatives maps "<dexscreener chain id>:<lower-case address>" to the
+// native asset a wrapped token represents. Keys include the chain because the
+// same address is reused across chains (e.g. 0x4200…0006 on Base and Optimism).
+var wrappedNatives = map[string]string{
+	"ethereum:0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2":  "ETH",  // WETH
+	"arbitrum:0x82af49447d8a07e3bd95bd0d56f35241523fbab1":  "ETH",  // WETH
+	"base:0x4200000000000000000000000000000000000006":      "ETH",  // WETH
+	"optimism:0x4200000000000000000000000000000000000006":  "ETH",  // WETH
+	"bsc:0xbb4cdb9cbd36b01bd1cbaebf2de08d9173bc095c":       "BNB",  // WBNB
+	"polygon:0x0d500b1d8e8ef31e21c99d1db9a6444d3adf1270":   "POL",  // WPOL (formerly WMATIC)
+	"avalanche:0xb31f66aa3c1e785363f0875a1b74e27b85fd66c7": "AVAX", // WAVAX
+	"fantom:0x21be370d5312f44cb42ce377bc9b8a0cef1a4c83":    "FTM",  // WFTM
+}
+
+// nativeForWrapped returns the native asset symbol when a DEX response is for
+// a known wrapped native token.
+func nativeForWrapped(dexResponse string) (string, bool) {
+	parts := parseRawOutput(dexResponse)
+	native, ok := wrappedNatives[strings.ToLower(parts["chain_id"])+":"+strings.ToLower(parts["contract_address"])]
+	return native, ok
+}
+
+// wrappedNativeFields looks up the native asset's CEX price for a wrapped
+// native DEX result, returning the fields to append or "" when it isn't one
+// (or no CEX provider has a price).
+func (a *PMOAgent) wrappedNativeFields(ctx context.Context, dexResponse string) string {
+	native, ok := nativeForWrapped(dexResponse)
+	if !ok {
+		return ""
+	}
+
+	for _, provider := range a.cexProviders {
+		response, err := provider.lookup(ctx, native, "usd")
+		if !providerSucceeded(response, err) {
+			continue
+		}
+		price := parseRawOutput(response)["current_price_usd"]
+		if price == "" {
+			continue
+		}
+		return fmt.Sprintf(";native_symbol:%s;native_price:%s;native_source:%s", native, price, provider.name)
+	}
+
+	slog.Warn("No CEX price for native behind wrapped token", "native", native)
+	return ""
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const wethAddress = "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"
+
+func TestNativeForWrapped(t *testing.T) {
+	if native, ok := nativeForWrapped("chain_id:ethereum;contract_address:" + wethAddress); !ok || native != "ETH" {
+		t.Errorf("nativeForWrapped(WETH) = %q, %v; want ETH, true", native, ok)
+	}
+	// The mainnet WETH address means nothing on another chain
+	if _, ok := nativeForWrapped("chain_id:bsc;contract_address:" + wethAddress); ok {
+		t.Error("the Ethereum WETH address was recognised on BSC")
+	}
+}
+
+func TestWrappedNativeNote(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"cmc": body(cmcQuote("ETH", 3000))})
+	a := newTestAgent(t, f.env())
+
+	raw := "token_source:dexscreener;chain_id:ethereum;current_price_usd:$2,999.50;base_token:WETH;contract_address:" + wethAddress
+	raw += a.wrappedNativeFields(context.Background(), raw)
+
+	want := "- ℹ️ This is WETH, the wrapped form of ETH. Native ETH price via CMC: $3,000.00"
+	if output := a.formatOutput(raw, map[string]string{}); !strings.Contains(output, want) {
+		t.Errorf("output missing %q:\n%s", want, output)
+	}
+}
+
+func TestWrappedNativeNoteForForcedDexLookup(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": body(cmcQuote("ETH", 3000)),
+		"dexscreener": body(`{"pairs":[{"chainId":"ethereum","priceUsd":"2999.50","baseToken":{"address":"` + wethAddress + `","symbol":"WETH"},
+			"quoteToken":{"symbol":"USDC"},"liquidity":{"usd":5000000}}]}`),
+	})
+	env := f.env()
+	env["RETRY_BUDGET_PER_MINUTE"] = "0"
+	a := newTestAgent(t, env)
+
+	response, _ := a.processTask(context.Background(), session{}, "/price "+wethAddress+" --source=dexscreener")
+	if want := "This is WETH, the wrapped form of ETH. Native ETH price via CMC: $3,000.00"; !strings.Contains(response, want) {
+		t.Errorf("--source=dexscreener response missing %q:\n%s", want, response)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Alert Notifications ---
+
+// Notifier delivers an alert message through one channel. Implementations
+// must be safe for concurrent use.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, msg string) error
+}
+
+// newNotifiers builds every notifier the configuration enables, falling back
+// to the log so alerts are never silently dropped.
+func newNotifiers(cfg *AppConfig, client *http.Client) []Notifier {
+	var notifiers []Notifier
+	if cfg.AlertWebhookURL != "" {
+		notifiers = append(notifiers, &webhookNotifier{name: "webhook", url: cfg.AlertWebhookURL, client: client, maxBytes: cfg.MaxResponseBytes, payload: genericPayload})
+	}
+	if cfg.AlertDiscordWebhookURL != "" {
+		notifiers = append(notifiers, &webhookNotifier{name: "discord", url: cfg.AlertDiscordWebhookURL, client: client, maxBytes: cfg.MaxResponseBytes, payload: discordPayload})
+	}
+	if cfg.SMTPHost != "" {
+		notifiers = append(notifiers, &emailNotifier{
+			addr: net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+			host: cfg.SMTPHost, username: cfg.SMTPUsername, password: cfg.SMTPPassword,
+			from: cfg.AlertEmailFrom, to: cfg.AlertEmailTo,
+		})
+	}
+	if len(notifiers) == 0 {
+		notifiers = append(notifiers, logNotifier{})
+	}
+	return notifiers
+}
+
+// notify sends msg through every notifier, returning the names of those that
+// delivered it and of those that failed. Failure details are only logged.
+func (a *PMOAgent) notify(ctx context.Context, msg string) (delivered, failed []string) {
+	for _, notifier := range a.notifiers {
+		if err := notifier.Notify(ctx, msg); err != nil {
+			slog.Error("Notifier failed", "notifier", notifier.Name(), "err", err)
+			failed = append(failed, notifier.Name())
+			continue
+		}
+		delivered = append(delivered, notifier.Name())
+	}
+	return delivered, failed
+}
+
+// 1. Log Notifier
+
+type logNotifier struct{}
+
+func (logNotifier) Name() string { return "log" }
+
+func (logNotifier) Notify(_ context.Context, msg string) error {
+	// Warn so alerts still reach the log when LOG_LEVEL hides routine events
+	slog.Warn("ALERT: " + msg)
+	return nil
+}
+
+// 2. Webhook Notifier (generic JSON and Discord)
+
+// webhookNotifier POSTs a JSON body built by payload to url.
+type webhookNotifier struct {
+	name     string
+	url      string
+	client   *http.Client
+	maxBytes int64
+	payload  func(msg string) any
+}
+
+// genericPayload uses "text", which Slack and most chat webhooks render directly.
+func genericPayload(msg string) any {
+	return struct {
+		Text   string    `json:"text"`
+		SentAt time.Time `json:"sent_at"`
+	}{msg, time.Now().UTC()}
+}
+
+// discordPayload uses Discord's "content" field.
+func discordPayload(msg string) any {
+	return struct {
+		Content string `json:"content"`
+	}{msg}
+}
+
+func (w *webhookNotifier) Name() string { return w.name }
+
+func (w *webhookNotifier) Notify(ctx context.Context, msg string) error {
+	body, err := json.Marshal(w.payload(msg))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, w.maxBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// 3. Email Notifier (SMTP)
+
+type emailNotifier struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func (e *emailNotifier) Name() string { return "email" }
+
+// Notify sends a plain-text email. net/smtp has no context support, so the
+// send runs in a goroutine and is abandoned if ctx ends first.
+func (e *emailNotifier) Notify(ctx context.Context, msg string) error {
+	var auth smtp.Auth
+	if e.username != "" {
+		auth = smtp.PlainAuth("", e.username, e.password, e.host)
+	}
+
+	subject := msg
+	if line, _, found := strings.Cut(msg, "\n"); found {
+		subject = line
+	}
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		e.from, strings.Join(e.to, ", "), subject, msg)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(e.addr, auth, e.from, e.to, []byte(body))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendTestAlert handles `/testalert`, pushing a sample notification through
+// every configured notifier so users can verify their setup end to end.
+func (a *PMOAgent) sendTestAlert(ctx context.Context, _ []string, _ map[string]string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.config.HTTPTimeout)
+	defer cancel()
+
+	delivered, failed := a.notify(ctx, "🔔 Test alert from the Price and Market Overview agent. If you can read this, alert delivery works.")
+	if len(failed) > 0 {
+		if len(delivered) > 0 {
+			return fmt.Sprintf("⚠️ Test alert delivered via %s, but %s delivery failed. Check the agent log.", strings.Join(delivered, ", "), strings.Join(failed, ", ")), nil
+		}
+		return fmt.Sprintf("❌ Test alert could not be delivered via %s. Check the alert settings and the agent log.", strings.Join(failed, ", ")), nil
+	}
+	if len(delivered) == 1 && delivered[0] == "log" {
+		return "✅ Test alert written to the agent log. Configure ALERT_WEBHOOK_URL, ALERT_DISCORD_WEBHOOK_URL or SMTP_HOST to deliver alerts elsewhere.", nil
+	}
+	return fmt.Sprintf("✅ Test alert delivered via %s.", strings.Join(delivered, ", ")), nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTestAlertReachesWebhook(t *testing.T) {
+	received := make(chan map[string]any, 1)
+	server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" || json.NewDecoder(r.Body).Decode(&payload) != nil {
+			t.Errorf("webhook got %s with %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		received <- payload
+		w.WriteHeader(http.StatusNoContent)
+	})
+	a := newTestAgent(t, map[string]string{"ALERT_WEBHOOK_URL": server.URL + "/hook"})
+
+	response, err := a.processTask(context.Background(), session{}, "/testalert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != "✅ Test alert delivered via webhook." {
+		t.Errorf("response = %q", response)
+	}
+	select {
+	case payload := <-received:
+		if text, _ := payload["text"].(string); !strings.Contains(text, "Test alert") {
+			t.Errorf("webhook payload = %v, want the sample alert text", payload)
+		}
+	default:
+		t.Fatal("the webhook received nothing")
+	}
+}
+
+func TestTestAlertReportsWebhookFailure(t *testing.T) {
+	server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	a := newTestAgent(t, map[string]string{"ALERT_WEBHOOK_URL": server.URL})
+
+	response, _ := a.processTask(context.Background(), session{}, "/testalert")
+	if !strings.HasPrefix(response, "❌ Test alert could not be delivered via webhook.") {
+		t.Errorf("response = %q, want the delivery failure", response)
+	}
+}
+
+// fakeNotifier records the messages it is asked to deliver.
+type fakeNotifier struct {
+	name     string
+	err      error
+	mu       sync.Mutex
+	messages []string
+}
+
+func (f *fakeNotifier) Name() string { return f.name }
+
+func (f *fakeNotifier) Notify(_ context.Context, msg string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, msg)
+	return f.err
+}
+
+func TestNotifySendsThroughEveryNotifier(t *testing.T) {
+	a := newTestAgent(t, nil)
+	working := &fakeNotifier{name: "working"}
+	broken := &fakeNotifier{name: "broken", err: errors.New("down")}
+	a.notifiers = []Notifier{working, broken}
+
+	delivered, failed := a.notify(context.Background(), "BTC crossed $70,000")
+	if !slices.Equal(delivered, []string{"working"}) || !slices.Equal(failed, []string{"broken"}) {
+		t.Errorf("notify = %v delivered, %v failed", delivered, failed)
+	}
+	for _, f := range []*fakeNotifier{working, broken} {
+		if !slices.Equal(f.messages, []string{"BTC crossed $70,000"}) {
+			t.Errorf("%s got %q", f.name, f.messages)
+		}
+	}
+
+	response, _ := a.sendTestAlert(context.Background(), nil, nil)
+	if response != "⚠️ Test alert delivered via working, but broken delivery failed. Check the agent log." {
+		t.Errorf("sendTestAlert = %q", response)
+	}
+}
+
+func TestDeliverWatchWithoutRoomUsesNotifiers(t *testing.T) {
+	a := newTestAgent(t, nil)
+	fake := &fakeNotifier{name: "fake"}
+	a.notifiers = []Notifier{fake}
+
+	a.deliverWatch(context.Background(), nil, "ETH dropped below $2,000")
+	if !slices.Equal(fake.messages, []string{"ETH dropped below $2,000"}) {
+		t.Errorf("notifier got %q", fake.messages)
+	}
+}
+
+func TestNewNotifiersFromConfig(t *testing.T) {
+	names := func(notifiers []Notifier) []string {
+		var result []string
+		for _, n := range notifiers {
+			result = append(result, n.Name())
+		}
+		return result
+	}
+
+	if got := names(newNotifiers(&AppConfig{}, http.DefaultClient)); !slices.Equal(got, []string{"log"}) {
+		t.Errorf("notifiers without config = %v, want the log fallback", got)
+	}
+	cfg := &AppConfig{
+		AlertWebhookURL:        "https://example.com/hook",
+		AlertDiscordWebhookURL: "https://discord.com/api/webhooks/1/x",
+		SMTPHost:               "smtp.example.com",
+		SMTPPort:               587,
+	}
+	if got := names(newNotifiers(cfg, http.DefaultClient)); !slices.Equal(got, []string{"webhook", "discord", "email"}) {
+		t.Errorf("notifiers = %v, want webhook, discord and email", got)
+	}
+}
+
+func TestDiscordPayload(t *testing.T) {
+	encoded, _ := json.Marshal(discordPayload("hello"))
+	if string(encoded) != `{"content":"hello"}` {
+		t.Errorf("discordPayload = %s", encoded)
+	}
+}
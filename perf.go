@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// performanceRow is one coin's change over the requested timeframe.
+type performanceRow struct {
+	symbol   string
+	change   float64
+	hasValue bool
+}
+
+// rankPerformance sorts rows by change, best first. Coins without data for
+// the timeframe keep their input order at the end.
+func rankPerformance(rows []performanceRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].hasValue != rows[j].hasValue {
+			return rows[i].hasValue
+		}
+		return rows[i].hasValue && rows[i].change > rows[j].change
+	})
+}
+
+// getPerformance handles `/perf <symbols...> over <timeframe>`, ranking the
+// coins by their percent change over that window.
+func (a *PMOAgent) getPerformance(ctx context.Context, args []string, _ map[string]string) (string, error) {
+	timeframe := "24h"
+	if len(args) >= 2 && strings.EqualFold(args[len(args)-2], "over") {
+		timeframe = strings.ToLower(args[len(args)-1])
+		args = args[:len(args)-2]
+		if !isChangeTimeframe(timeframe) {
+			return withUsage(fmt.Sprintf("Unknown timeframe: %s. Use one of %s.", timeframe, strings.Join(changeTimeframes, ", ")), "/perf"), nil
+		}
+	}
+	if len(args) < 2 {
+		return withUsage("Please provide at least two coins to rank.", "/perf"), nil
+	}
+	if len(args) > maxTokensPerRequest {
+		return withUsage(fmt.Sprintf("Please rank at most %d coins at a time.", maxTokensPerRequest), "/perf"), nil
+	}
+
+	markets, message, err := a.getMarketsForSymbols(ctx, args, "usd")
+	if markets == nil {
+		return message, err
+	}
+
+	rows := make([]performanceRow, 0, len(args))
+	for _, symbol := range args {
+		row := performanceRow{symbol: strings.ToUpper(symbol)}
+		if market, ok := markets[strings.ToLower(symbol)]; ok {
+			row.change, row.hasValue = market.changeFor(timeframe)
+		}
+		rows = append(rows, row)
+	}
+	rankPerformance(rows)
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString(fmt.Sprintf("📈 **Performance over %s**\n", timeframe))
+	for i, row := range rows {
+		if !row.hasValue {
+			responseBuilder.WriteString(fmt.Sprintf("%d. **%s** n/a (no %s data)\n", i+1, row.symbol, timeframe))
+			continue
+		}
+		leader := ""
+		if i == 0 {
+			leader = "🏆 "
+		}
+		responseBuilder.WriteString(fmt.Sprintf("%d. %s**%s** %s\n", i+1, leader, row.symbol, a.formatSignedChange(row.change)))
+	}
+	if message != "" {
+		responseBuilder.WriteString("\n" + message + "\n")
+	}
+	responseBuilder.WriteString("\n*(Data provided by COINGECKO)*")
+
+	return responseBuilder.String(), nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRankPerformance(t *testing.T) {
+	rows := []performanceRow{
+		{symbol: "BTC", change: 2, hasValue: true},
+		{symbol: "NEW"},
+		{symbol: "ETH", change: 5.5, hasValue: true},
+		{symbol: "OLD"},
+		{symbol: "SOL", change: -3, hasValue: true},
+	}
+	rankPerformance(rows)
+
+	var got []string
+	for _, row := range rows {
+		got = append(got, row.symbol)
+	}
+	// Coins without data go last, in their original order
+	if want := []string{"ETH", "BTC", "SOL", "NEW", "OLD"}; !slices.Equal(got, want) {
+		t.Errorf("ranking = %v, want %v", got, want)
+	}
+}
+
+func TestPerformanceCommand(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": body(`[
+			{"id":"bitcoin","symbol":"btc","price_change_percentage_7d_in_currency":4.2},
+			{"id":"ethereum","symbol":"eth","price_change_percentage_7d_in_currency":9.1},
+			{"id":"solana","symbol":"sol","price_change_percentage_7d_in_currency":null}
+		]`),
+	})
+	a := newTestAgent(t, f.env())
+
+	response, err := a.processTask(context.Background(), session{}, "/perf btc eth sol over 7d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"**Performance over 7d**",
+		"1. 🏆 **ETH** +9.10%",
+		"2. **BTC** +4.20%",
+		"3. **SOL** n/a (no 7d data)",
+	} {
+		if !strings.Contains(response, want) {
+			t.Errorf("response missing %q:\n%s", want, response)
+		}
+	}
+}
+
+func TestPerformanceRejectsUnknownTimeframe(t *testing.T) {
+	a := newTestAgent(t, nil)
+	response, _ := a.processTask(context.Background(), session{}, "/perf btc eth over 3w")
+	if !strings.Contains(response, "Unknown timeframe: 3w") {
+		t.Errorf("response = %q, want the unknown timeframe message", response)
+	}
+}
+
+func TestPerformanceKeepsAliasRows(t *testing.T) {
+	var ids string
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": func(w http.ResponseWriter, r *http.Request) {
+			ids = r.URL.Query().Get("ids")
+			respond(w, http.StatusOK, `[
+				{"id":"bitcoin","symbol":"btc","price_change_percentage_24h_in_currency":1.5},
+				{"id":"ethereum","symbol":"eth","price_change_percentage_24h_in_currency":2.5}
+			]`)
+		},
+	})
+	a := newTestAgent(t, f.env())
+
+	response, _ := a.processTask(context.Background(), session{}, "/perf btc bitcoin eth")
+	if ids != "bitcoin,ethereum" {
+		t.Errorf("requested ids = %q, want each coin once", ids)
+	}
+	for _, want := range []string{"**BTC** +1.50%", "**BITCOIN** +1.50%", "**ETH** +2.50%"} {
+		if !strings.Contains(response, want) {
+			t.Errorf("response missing %q:\n%s", want, response)
+		}
+	}
+}
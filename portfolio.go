@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// allocationTolerance is how far percentage allocations may stray from 100%
+// (in percentage points) before they are rejected, allowing for rounding.
+const allocationTolerance = 0.5
+
+// holding is one `symbol:value` entry of a /portfolio request. value is a
+// coin quantity, or a percentage of the total when percent is set.
+type holding struct {
+	symbol  string
+	value   float64
+	percent bool
+}
+
+// parsePortfolioArgs reads an optional `total=<usd>` entry and the holdings.
+// The returned message is non-empty when the input is malformed.
+func parsePortfolioArgs(args []string) ([]holding, float64, string) {
+	var holdings []holding
+	total := 0.0
+	for _, arg := range args {
+		if rawTotal, ok := strings.CutPrefix(strings.ToLower(arg), "total="); ok {
+			amount, ok := parseAmount(rawTotal)
+			if !ok {
+				return nil, 0, fmt.Sprintf("Invalid total: %s. Please provide a positive number.", rawTotal)
+			}
+			total = amount
+			continue
+		}
+
+		symbol, rawValue, found := strings.Cut(arg, ":")
+		if !found || symbol == "" {
+			return nil, 0, fmt.Sprintf("Invalid holding: %s. Use symbol:amount or symbol:percent%%.", arg)
+		}
+		h := holding{symbol: strings.ToLower(symbol)}
+		rawValue, h.percent = strings.CutSuffix(rawValue, "%")
+		value, ok := parseAmount(rawValue)
+		if !ok {
+			return nil, 0, fmt.Sprintf("Invalid amount for %s: %s. Please provide a positive number.", strings.ToUpper(symbol), rawValue)
+		}
+		h.value = value
+		holdings = append(holdings, h)
+	}
+	return holdings, total, ""
+}
+
+// parseAmount parses a positive number, ignoring a leading $ and thousands commas.
+// Only plain decimals are accepted: ParseFloat alone would also take "1e3",
+// "0x1p4", "Inf" or "NaN", which are far more likely to be typos or tickers.
+func parseAmount(raw string) (float64, bool) {
+	digits := strings.ReplaceAll(strings.TrimPrefix(raw, "$"), ",", "")
+	if !isPlainDecimal(digits) {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(digits, 64)
+	if err != nil || value <= 0 || math.IsInf(value, 0) {
+		return 0, false
+	}
+	return value, true
+}
+
+// isPlainDecimal reports whether s is digits with at most one decimal point.
+func isPlainDecimal(s string) bool {
+	digits, points := 0, 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r == '.':
+			points++
+		default:
+			return false
+		}
+	}
+	return digits > 0 && points <= 1
+}
+
+// validateAllocation checks that holdings are either all quantities or all
+// percentages of a total summing to ~100%.
+func validateAllocation(holdings []holding, total float64) string {
+	percentages := 0
+	sum := 0.0
+	for _, h := range holdings {
+		if h.percent {
+			percentages++
+			sum += h.value
+		}
+	}
+
+	switch {
+	case percentages == 0:
+		if total > 0 {
+			return "A total= is only used with percentage allocations such as btc:60%."
+		}
+		return ""
+	case percentages != len(holdings):
+		return "Please use either coin amounts or percentages for every holding, not a mix."
+	case total == 0:
+		return "Percentage allocations need a total, e.g. total=10000."
+	case math.Abs(sum-100) > allocationTolerance:
+		return fmt.Sprintf("Allocations add up to %.2f%%, not 100%%.", sum)
+	}
+	return ""
+}
+
+// getPortfolio handles `/portfolio [total=<usd>] <symbol:amount|symbol:pct%>...`,
+// valuing coin holdings or splitting a USD total into per-coin amounts.
+func (a *PMOAgent) getPortfolio(ctx context.Context, args []string, _ map[string]string) (string, error) {
+	holdings, total, message := parsePortfolioArgs(args)
+	if message == "" {
+		message = validateAllocation(holdings, total)
+	}
+	if message == "" && len(holdings) == 0 {
+		message = "Please list at least one holding."
+	}
+	if message == "" && len(holdings) > maxTokensPerRequest {
+		message = fmt.Sprintf("Please list at most %d holdings.", maxTokensPerRequest)
+	}
+	if message != "" {
+		return withUsage(message, "/portfolio"), nil
+	}
+
+	ids := make([]string, len(holdings))
+	for i, h := range holdings {
+		ids[i] = getCoinID(h.symbol)
+	}
+	prices, err := a.getSimplePrices(ctx, ids, []string{"usd"})
+	if err != nil {
+		slog.Error("Portfolio price lookup failed", "err", err)
+		return "Could not load portfolio prices from CoinGecko.", nil
+	}
+
+	var missing []string
+	for i, h := range holdings {
+		if _, ok := prices[ids[i]]["usd"]; !ok {
+			missing = append(missing, strings.ToUpper(h.symbol))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Sprintf("❌ **Could not price:** %s", strings.Join(missing, ", ")), nil
+	}
+
+	var responseBuilder strings.Builder
+
+	// 1. Percentage allocations: split the total into USD and coin amounts
+	if total > 0 {
+		responseBuilder.WriteString(fmt.Sprintf("💼 **Portfolio Allocation** (Total: %s)\n", formatCurrency(total, "usd")))
+		for i, h := range holdings {
+			value := total * h.value / 100
+			price := prices[ids[i]]["usd"]
+			responseBuilder.WriteString(fmt.Sprintf("- **%s** %.2f%% → %s = %s\n",
+				strings.ToUpper(h.symbol), h.value, formatCurrency(value, "usd"), formatConvertedAmount(value/price, h.symbol)))
+		}
+		responseBuilder.WriteString("\n*(Data provided by COINGECKO)*")
+		return responseBuilder.String(), nil
+	}
+
+	// 2. Coin quantities: value each holding and show its share of the whole
+	values := make([]float64, len(holdings))
+	sum := 0.0
+	for i, h := range holdings {
+		values[i] = h.value * prices[ids[i]]["usd"]
+		sum += values[i]
+	}
+	responseBuilder.WriteString(fmt.Sprintf("💼 **Portfolio Value:** %s\n", formatCurrency(sum, "usd")))
+	for i, h := range holdings {
+		share := 0.0
+		if sum > 0 {
+			share = values[i] / sum * 100
+		}
+		responseBuilder.WriteString(fmt.Sprintf("- **%s** %s × %s = %s (%.2f%%)\n",
+			strings.ToUpper(h.symbol), formatConvertedAmount(h.value, h.symbol), formatPrice(prices[ids[i]]["usd"], "usd"), formatCurrency(values[i], "usd"), share))
+	}
+	responseBuilder.WriteString("\n*(Data provided by COINGECKO)*")
+	return responseBuilder.String(), nil
+}
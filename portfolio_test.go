@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPortfolioPercentageAllocation(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": body(`{"bitcoin":{"usd":60000},"ethereum":{"usd":2000}}`),
+	})
+	a := newTestAgent(t, f.env())
+
+	response, err := a.processTask(context.Background(), session{}, "/portfolio total=10000 btc:60% eth:40%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"💼 **Portfolio Allocation** (Total: $10,000.00)",
+		"- **BTC** 60.00% → $6,000.00 = 0.1 BTC",
+		"- **ETH** 40.00% → $4,000.00 = 2 ETH",
+	} {
+		if !strings.Contains(response, want) {
+			t.Errorf("response missing %q:\n%s", want, response)
+		}
+	}
+}
+
+func TestPortfolioRejectsAllocationsNotSummingTo100(t *testing.T) {
+	f := newFakeProviders(t, nil)
+	a := newTestAgent(t, f.env())
+
+	response, err := a.processTask(context.Background(), session{}, "/portfolio total=10000 btc:60% eth:30%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(response, "Allocations add up to 90.00%, not 100%.") {
+		t.Errorf("response = %q, want the sum error", response)
+	}
+	if f.count("coingecko") != 0 {
+		t.Error("prices were fetched for an invalid allocation")
+	}
+}
+
+func TestValidateAllocation(t *testing.T) {
+	percent := func(values ...float64) []holding {
+		holdings := make([]holding, len(values))
+		for i, v := range values {
+			holdings[i] = holding{symbol: "btc", value: v, percent: true}
+		}
+		return holdings
+	}
+	tests := []struct {
+		name     string
+		holdings []holding
+		total    float64
+		ok       bool
+	}{
+		{"exactly 100", percent(60, 40), 10000, true},
+		{"within rounding tolerance", percent(33.3, 33.3, 33.3), 10000, true},
+		{"over 100", percent(60, 50), 10000, false},
+		{"no total", percent(60, 40), 0, false},
+		{"mixed", []holding{{symbol: "btc", value: 60, percent: true}, {symbol: "eth", value: 2}}, 10000, false},
+		{"quantities", []holding{{symbol: "btc", value: 0.5}}, 0, true},
+	}
+	for _, tt := range tests {
+		if message := validateAllocation(tt.holdings, tt.total); (message == "") != tt.ok {
+			t.Errorf("%s: validateAllocation = %q, want ok = %v", tt.name, message, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Price at a Past Block (/priceat) ---
+
+// defaultPriceAtChain is used when /priceat doesn't name a chain.
+const defaultPriceAtChain = "ethereum"
+
+// priceAtSearchWidth is how far from the block time DefiLlama may look for
+// a price point; thin tokens are only sampled every few hours.
+const priceAtSearchWidth = "4h"
+
+// rpcResponse is a JSON-RPC response; Result is decoded by the caller.
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// rpcBlock is the eth_getBlockByNumber result; only the timestamp is used.
+type rpcBlock struct {
+	Timestamp string `json:"timestamp"` // hex seconds, e.g. "0x64e9c6f7"
+}
+
+// llamaHistoricalResponse is DefiLlama's /prices/historical response, keyed
+// by "<chain>:<address>". Tokens without a price point are simply absent.
+type llamaHistoricalResponse struct {
+	Coins map[string]struct {
+		Symbol     string  `json:"symbol"`
+		Price      float64 `json:"price"`
+		Timestamp  int64   `json:"timestamp"`
+		Confidence float64 `json:"confidence"`
+	} `json:"coins"`
+}
+
+// callRPC sends one JSON-RPC call to the chain's configured node (the caller
+// checks one is configured) and returns its raw result. Being a POST, it is
+// not retried. On failure it returns a human-readable message alongside the
+// error (which may be nil).
+func (a *PMOAgent) callRPC(ctx context.Context, chain, method string, params []any) (json.RawMessage, string, error) {
+	payload, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return nil, "Error creating RPC request.", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", a.config.EVMRPCURLs[chain], bytes.NewReader(payload))
+	if err != nil {
+		slog.Error("Error creating RPC request", "err", err)
+		return nil, "Error creating HTTP request.", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var rpc rpcResponse
+	if message, err := a.fetchProviderJSON(req, &rpc, chain+" RPC"); message != "" {
+		return nil, message, err
+	}
+	if rpc.Error != nil {
+		slog.Warn("RPC error", "chain", chain, "method", method, "error", rpc.Error.Message)
+		return nil, fmt.Sprintf("The %s RPC node rejected %s: %s", chain, method, rpc.Error.Message), nil
+	}
+	return rpc.Result, "", nil
+}
+
+// getBlockTime asks the chain's configured RPC node when block was mined.
+func (a *PMOAgent) getBlockTime(ctx context.Context, chain string, block uint64) (time.Time, string, error) {
+	if _, ok := a.config.EVMRPCURLs[chain]; !ok {
+		return time.Time{}, fmt.Sprintf("No RPC node is configured for %s, so block times can't be looked up there. Set EVM_RPC_URLS to enable it.", chain), nil
+	}
+
+	result, message, err := a.callRPC(ctx, chain, "eth_getBlockByNumber", []any{"0x" + strconv.FormatUint(block, 16), false})
+	if message != "" {
+		return time.Time{}, message, err
+	}
+	// A missing or null result means the block hasn't been mined
+	var mined *rpcBlock
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &mined); err != nil {
+			return time.Time{}, "Error processing RPC response.", err
+		}
+	}
+	if mined == nil {
+		return time.Time{}, fmt.Sprintf("Block %d does not exist on %s yet.", block, chain), nil
+	}
+
+	seconds, err := strconv.ParseInt(strings.TrimPrefix(mined.Timestamp, "0x"), 16, 64)
+	if err != nil {
+		return time.Time{}, "Error processing RPC response.", fmt.Errorf("block timestamp %q: %w", mined.Timestamp, err)
+	}
+	return time.Unix(seconds, 0).UTC(), "", nil
+}
+
+// getHistoricalPrice fetches a token's USD price nearest to a moment from
+// DefiLlama. On failure it returns a human-readable message alongside the
+// error (which may be nil).
+func (a *PMOAgent) getHistoricalPrice(ctx context.Context, chain, address string, at time.Time) (*llamaHistoricalResponse, string, error) {
+	coin := lookupChain(chain).llama + ":" + address
+	path := fmt.Sprintf("/prices/historical/%d/%s?searchWidth=%s", at.Unix(), url.PathEscape(coin), priceAtSearchWidth)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", a.config.DefiLlamaCoinsBaseURL+path, nil)
+	if err != nil {
+		slog.Error("Error creating DefiLlama request", "err", err)
+		return nil, "Error creating HTTP request.", err
+	}
+
+	var prices llamaHistoricalResponse
+	if message, err := a.fetchProviderJSON(req, &prices, "DefiLlama"); message != "" {
+		return nil, message, err
+	}
+	return &prices, "", nil
+}
+
+// getPriceAtBlock handles `/priceat <address> <block> [chain]`: the block's
+// timestamp comes from the chain's RPC node and the price nearest to it from
+// DefiLlama's price history. Only EVM chains with both are supported.
+func (a *PMOAgent) getPriceAtBlock(ctx context.Context, args []string, _ map[string]string) (string, error) {
+	address := strings.ToLower(args[0])
+	if !isContractAddress(address) {
+		return withUsage(fmt.Sprintf("%s is not a valid contract address (expected 0x followed by 40 hex characters).", args[0]), "/priceat"), nil
+	}
+	block, err := strconv.ParseUint(strings.ReplaceAll(args[1], ",", ""), 10, 64)
+	if err != nil {
+		return withUsage(fmt.Sprintf("Invalid block number: %s.", args[1]), "/priceat"), nil
+	}
+	chain := defaultPriceAtChain
+	if len(args) > 2 {
+		chain = strings.ToLower(args[2])
+	}
+	if lookupChain(chain).llama == "" {
+		var supported []string
+		for name, info := range chainTable {
+			if info.llama != "" {
+				supported = append(supported, name)
+			}
+		}
+		sort.Strings(supported)
+		return fmt.Sprintf("Block-level price history isn't available for %s. Supported chains: %s.", chain, strings.Join(supported, ", ")), nil
+	}
+
+	blockTime, message, err := a.getBlockTime(ctx, chain, block)
+	if message != "" {
+		return message, err
+	}
+	prices, message, err := a.getHistoricalPrice(ctx, chain, address, blockTime)
+	if prices == nil {
+		return message, err
+	}
+	point, ok := prices.Coins[lookupChain(chain).llama+":"+address]
+	if !ok {
+		return fmt.Sprintf("DefiLlama has no price for %s within %s of block %d on %s.", truncateAddress(address), priceAtSearchWidth, block, chain), nil
+	}
+
+	symbol := strings.ToUpper(point.Symbol)
+	if symbol == "" {
+		symbol = "Token"
+	}
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString(fmt.Sprintf("⏱ **%s Price at Block %s** (%s)\n", symbol, formatQuantity(float64(block)), chainDisplayName(chain)))
+	responseBuilder.WriteString(fmt.Sprintf("- **Contract:** `%s`\n", truncateAddress(address)))
+	responseBuilder.WriteString(fmt.Sprintf("- **Block Time:** %s\n", blockTime.Format("2006-01-02 15:04 UTC")))
+	responseBuilder.WriteString(fmt.Sprintf("- **Price (USD):** %s\n", formatPrice(point.Price, "usd")))
+	if pricedAt := time.Unix(point.Timestamp, 0).UTC(); point.Timestamp != 0 && !pricedAt.Equal(blockTime) {
+		responseBuilder.WriteString(fmt.Sprintf("- **Nearest Price Point:** %s (%s from the block)\n", pricedAt.Format("2006-01-02 15:04 UTC"), formatAge(absDuration(pricedAt.Sub(blockTime)))))
+	}
+	responseBuilder.WriteString("\n*(Data provided by DEFILLAMA, block time via RPC)*")
+
+	return responseBuilder.String(), nil
+}
+
+// absDuration returns the magnitude of d.
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
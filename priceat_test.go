@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const priceAtToken = "0x6982508145454ce325ddbe47a25d4ec3d2311933"
+
+// priceAtFakes serves block 17,000,000 from the "rpc" node and a DefiLlama
+// price point 30 minutes after it.
+func priceAtFakes(t *testing.T) (*fakeProviders, map[string]string) {
+	t.Helper()
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"rpc": func(w http.ResponseWriter, r *http.Request) {
+			var call struct {
+				Method string `json:"method"`
+				Params []any  `json:"params"`
+			}
+			json.NewDecoder(r.Body).Decode(&call)
+			if call.Method != "eth_getBlockByNumber" || len(call.Params) == 0 || call.Params[0] != "0x1036640" {
+				respond(w, http.StatusOK, `{"jsonrpc":"2.0","id":1,"result":null}`)
+				return
+			}
+			respond(w, http.StatusOK, `{"jsonrpc":"2.0","id":1,"result":{"number":"0x1036640","timestamp":"0x64373057"}}`)
+		},
+		"llama": func(w http.ResponseWriter, r *http.Request) {
+			if want := "/prices/historical/1681338455/ethereum:" + priceAtToken; r.URL.Path != want {
+				t.Errorf("DefiLlama path = %q, want %q", r.URL.Path, want)
+			}
+			respond(w, http.StatusOK, `{"coins":{"ethereum:`+priceAtToken+`":{"symbol":"PEPE","price":0.00000123,"timestamp":1681340255,"confidence":0.99}}}`)
+		},
+	})
+	env := f.env()
+	env["EVM_RPC_URLS"] = "ethereum=" + f.url + "/rpc"
+	env["DEFILLAMA_COINS_BASE_URL"] = f.url + "/llama"
+	return f, env
+}
+
+func TestPriceAtBlock(t *testing.T) {
+	_, env := priceAtFakes(t)
+	a := newTestAgent(t, env)
+
+	response, err := a.processTask(context.Background(), session{}, "/priceat "+priceAtToken+" 17,000,000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"⏱ **PEPE Price at Block 17,000,000** (Ethereum)",
+		"- **Block Time:** 2023-04-12 22:27 UTC",
+		"- **Price (USD):** $0.00000123",
+		"- **Nearest Price Point:** 2023-04-12 22:57 UTC (30m from the block)",
+	} {
+		if !strings.Contains(response, want) {
+			t.Errorf("response is missing %q:\n%s", want, response)
+		}
+	}
+}
+
+func TestPriceAtBlockErrors(t *testing.T) {
+	f, env := priceAtFakes(t)
+	a := newTestAgent(t, env)
+	ctx := context.Background()
+
+	tests := map[string]string{
+		"/priceat " + priceAtToken + " 99999999":    "Block 99999999 does not exist on ethereum yet.",
+		"/priceat " + priceAtToken + " 100 solana":  "Block-level price history isn't available for solana.",
+		"/priceat " + priceAtToken + " 100 polygon": "No RPC node is configured for polygon",
+		"/priceat " + priceAtToken + " latest":      "Invalid block number: latest.",
+		"/priceat 0x1234 100":                       "is not a valid contract address",
+	}
+	for input, want := range tests {
+		if response, _ := a.processTask(ctx, session{}, input); !strings.Contains(response, want) {
+			t.Errorf("%s: response = %q, want %q", input, response, want)
+		}
+	}
+	if n := f.count("llama"); n != 0 {
+		t.Errorf("DefiLlama was called %d times for failed lookups", n)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log/slog"
+	"runtime/debug"
+)
+
+// --- Panic Recovery ---
+
+// internalErrorMessage is what users see when a handler panics.
+const internalErrorMessage = "Internal error while processing your request. Please try again later."
+
+// safeCall runs fn, logging a panic (with its stack) instead of letting it
+// crash the agent. It reports whether fn returned normally.
+func safeCall(name string, fn func()) (ok bool) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			slog.Error("Panic in "+name, "panic", recovered, "stack", string(debug.Stack()))
+			ok = false
+		}
+	}()
+	fn()
+	return true
+}
+
+// safeGo runs fn on a new goroutine under safeCall. A recover in the calling
+// goroutine can't catch a panic in one it spawned, so every background
+// worker goes through here.
+func safeGo(name string, fn func()) {
+	go safeCall(name, fn)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func panickingCommand() command {
+	return command{run: func(*PMOAgent, context.Context, []string, map[string]string) (string, error) {
+		panic("handler bug")
+	}}
+}
+
+func TestProcessTaskRecoversHandlerPanic(t *testing.T) {
+	a := newTestAgent(t, nil)
+	withCommand(t, "/boom", panickingCommand())
+
+	response, err := a.processTask(context.Background(), session{room: "room"}, "/boom")
+	if err != nil {
+		t.Fatalf("processTask returned error %v, want nil", err)
+	}
+	if response != internalErrorMessage {
+		t.Errorf("response = %q, want %q", response, internalErrorMessage)
+	}
+}
+
+func TestBatchRecoversSubcommandPanic(t *testing.T) {
+	a := newTestAgent(t, nil)
+	withCommand(t, "/boom", panickingCommand())
+	withCommand(t, "/ok", command{run: func(*PMOAgent, context.Context, []string, map[string]string) (string, error) {
+		return "fine", nil
+	}})
+
+	response, err := a.runBatch(context.Background(), session{room: "room"}, []string{"/boom", "/ok"})
+	if err != nil {
+		t.Fatalf("runBatch returned error %v, want nil", err)
+	}
+	if !strings.Contains(response, internalErrorMessage) {
+		t.Errorf("batch output is missing the internal error for /boom:\n%s", response)
+	}
+	if !strings.Contains(response, "fine") {
+		t.Errorf("batch output is missing the /ok result:\n%s", response)
+	}
+}
+
+func TestSafeCallReportsPanic(t *testing.T) {
+	if safeCall("test", func() { panic("boom") }) {
+		t.Error("safeCall reported ok for a panicking func")
+	}
+	if !safeCall("test", func() {}) {
+		t.Error("safeCall reported a panic for a normal func")
+	}
+}
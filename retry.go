@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// --- Retry Policy ---
+
+const (
+	maxFetchRetries         = 2
+	baseRetryDelay          = 250 * time.Millisecond
+	defaultRetriesPerMinute = 30
+)
+
+// retryBudget is a token bucket shared by every outbound request. Each retry
+// spends one token; when the bucket is empty we stop retrying and let the
+// caller fail over, so a provider outage cannot turn into a retry storm.
+type retryBudget struct {
+	mu           sync.Mutex
+	tokens       float64
+	capacity     float64
+	refillPerSec float64
+	last         time.Time
+}
+
+func newRetryBudget(perMinute int) *retryBudget {
+	return &retryBudget{
+		tokens:       float64(perMinute),
+		capacity:     float64(perMinute),
+		refillPerSec: float64(perMinute) / 60,
+		last:         time.Now(),
+	}
+}
+
+// allow reports whether a retry may proceed, consuming a token if so.
+func (b *retryBudget) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.refillPerSec
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// isRetryable is the retry policy for every provider: it reports whether a
+// fetch outcome is worth another attempt. An oversized body is never retried,
+// whatever its status, since the same response would be cut off again. With
+// a status, only rate limiting and server errors are transient; other 4xx
+// statuses and 200s whose body didn't decode would fail the same way again.
+// Without one, transport failures such as timeouts and reset or refused
+// connections are retried, except those no retry can fix: cancellation, an
+// unknown host or a certificate that doesn't verify. A timeout that is
+// really the caller's deadline is stopped by waitForRetry instead.
+func isRetryable(err error, status int) bool {
+	if errors.Is(err, errResponseTooLarge) {
+		return false
+	}
+	if status != 0 {
+		return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+	}
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	var certErr *tls.CertificateVerificationError
+	return !errors.As(err, &certErr)
+}
+
+// waitForRetry sleeps for the exponential backoff of the given attempt,
+// returning early with false if the request context is done.
+func waitForRetry(ctx context.Context, attempt int) bool {
+	timer := time.NewTimer(baseRetryDelay << attempt)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
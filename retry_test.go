@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryBudgetSaturates(t *testing.T) {
+	budget := newRetryBudget(3)
+	for i := range 3 {
+		if !budget.allow() {
+			t.Fatalf("retry %d was refused within the budget", i+1)
+		}
+	}
+	if budget.allow() {
+		t.Fatal("a retry was allowed past the budget")
+	}
+
+	// 20 seconds at 3 per minute refills exactly one token
+	budget.mu.Lock()
+	budget.last = budget.last.Add(-20 * time.Second)
+	budget.mu.Unlock()
+	if !budget.allow() {
+		t.Error("the budget did not refill over time")
+	}
+	if budget.allow() {
+		t.Error("the budget refilled more than the elapsed time allows")
+	}
+}
+
+func TestRetryBudgetIsSharedAcrossGoroutines(t *testing.T) {
+	budget := newRetryBudget(10)
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if budget.allow() {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	// Allow for a sliver of refill while the goroutines ran
+	if n := allowed.Load(); n < 10 || n > 11 {
+		t.Errorf("%d concurrent retries allowed, want the budget of 10", n)
+	}
+}
+
+func TestFetchJSONStopsRetryingWhenBudgetIsExhausted(t *testing.T) {
+	var hits atomic.Int32
+	server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		respond(w, http.StatusServiceUnavailable, `{}`)
+	})
+	a := newTestAgent(t, map[string]string{"RETRY_BUDGET_PER_MINUTE": "1"})
+
+	fetch := func() {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		var target map[string]any
+		if status, _ := a.fetchJSON(req, &target); status != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want 503", status)
+		}
+	}
+
+	fetch()
+	if n := hits.Load(); n != 2 {
+		t.Fatalf("first fetch made %d attempts, want 2 (one retry from the budget)", n)
+	}
+	fetch()
+	if n := hits.Load(); n != 3 {
+		t.Errorf("second fetch made %d attempts, want 1 with the budget spent", n-2)
+	}
+}
+
+func TestFetchProviderJSONRetriesOnlyGets(t *testing.T) {
+	var hits atomic.Int32
+	server := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		respond(w, http.StatusServiceUnavailable, `{}`)
+	})
+	a := newTestAgent(t, map[string]string{"RETRY_BUDGET_PER_MINUTE": "10"})
+
+	for _, tt := range []struct {
+		method   string
+		attempts int32
+	}{
+		{"GET", 1 + maxFetchRetries},
+		{"POST", 1},
+	} {
+		hits.Store(0)
+		req, _ := http.NewRequest(tt.method, server.URL, nil)
+		var target map[string]any
+		message, err := a.fetchProviderJSON(req, &target, "Example")
+		if message != "Error: Example API returned status 503." || err != nil {
+			t.Errorf("%s: fetchProviderJSON = %q, %v", tt.method, message, err)
+		}
+		if n := hits.Load(); n != tt.attempts {
+			t.Errorf("%s: made %d attempts, want %d", tt.method, n, tt.attempts)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	decodeErr := json.Unmarshal([]byte("{oops"), &struct{}{})
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		want   bool
+	}{
+		{"rate limited", nil, http.StatusTooManyRequests, true},
+		{"server error", nil, http.StatusInternalServerError, true},
+		{"bad gateway", nil, http.StatusBadGateway, true},
+		{"service unavailable", nil, http.StatusServiceUnavailable, true},
+		{"bad request", nil, http.StatusBadRequest, false},
+		{"unauthorized", nil, http.StatusUnauthorized, false},
+		{"not found", nil, http.StatusNotFound, false},
+		{"decode error on a 200", decodeErr, http.StatusOK, false},
+		{"no error", nil, 0, false},
+		{"timeout", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, 0, true},
+		{"connection reset", &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, 0, true},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, 0, true},
+		{"temporary DNS failure", &net.DNSError{Err: "server misbehaving", Name: "api.example.com", IsTemporary: true}, 0, true},
+		{"unknown host", &net.DNSError{Err: "no such host", Name: "api.example.com", IsNotFound: true}, 0, false},
+		{"bad certificate", &tls.CertificateVerificationError{Err: errors.New("x509: certificate signed by unknown authority")}, 0, false},
+		{"cancelled", fmt.Errorf("get: %w", context.Canceled), 0, false},
+		{"response too large", errResponseTooLarge, 0, false},
+		{"oversized error page", fmt.Errorf("%w: api.example.com returned more than 1024 bytes", errResponseTooLarge), http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.err, tt.status); got != tt.want {
+			t.Errorf("%s: isRetryable(%v, %d) = %v, want %v", tt.name, tt.err, tt.status, got, tt.want)
+		}
+	}
+}
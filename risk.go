@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// --- Token Risk Scan (GoPlus) ---
+
+const (
+	highTaxThreshold     = 0.10 // Buy or sell tax above this is flagged
+	lowLockedLPThreshold = 50.0 // Locked LP share (%) below this is flagged
+)
+
+// GoPlusResponse is the token_security response. GoPlus encodes every
+// number and flag as a string, and omits fields it could not determine.
+type GoPlusResponse struct {
+	Code    int                         `json:"code"`
+	Message string                      `json:"message"`
+	Result  map[string]GoPlusTokenRisks `json:"result"`
+}
+
+// GoPlusTokenRisks holds the subset of security fields the risk summary uses.
+type GoPlusTokenRisks struct {
+	IsHoneypot string `json:"is_honeypot"` // "1" when selling appears to be blocked
+	BuyTax     string `json:"buy_tax"`     // Fraction, e.g. "0.05"
+	SellTax    string `json:"sell_tax"`
+	LPHolders  []struct {
+		IsLocked int    `json:"is_locked"`
+		Percent  string `json:"percent"` // Fraction of the LP supply held
+	} `json:"lp_holders"`
+}
+
+// riskFields scans the DEX token with GoPlus and returns the risk_ fields to
+// append to the response. Chains GoPlus doesn't cover yield ""; a scan that
+// fails yields risk_scan:unavailable so the overview can say so.
+func (a *PMOAgent) riskFields(ctx context.Context, dexResponse string) string {
+	parts := parseRawOutput(dexResponse)
+	chain := lookupChain(parts["chain_id"]).goplus
+	address := strings.ToLower(parts["contract_address"])
+	if chain == "" || address == "" {
+		return ""
+	}
+
+	url := fmt.Sprintf("%s/api/v1/token_security/%s?contract_addresses=%s", a.config.GoPlusBaseURL, chain, address)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		slog.Error("Error creating GoPlus request", "err", err)
+		return ";risk_scan:unavailable"
+	}
+
+	var scan GoPlusResponse
+	status, err := a.fetchJSON(req, &scan)
+	risks, found := scan.Result[address]
+	if status != http.StatusOK || err != nil || scan.Code != 1 || !found {
+		slog.Warn("GoPlus scan unavailable", "address", address, "chain", chain, "status", status, "code", scan.Code, "err", err)
+		return ";risk_scan:unavailable"
+	}
+
+	return summarizeRisks(risks)
+}
+
+// summarizeRisks converts a GoPlus result into raw response fields.
+func summarizeRisks(risks GoPlusTokenRisks) string {
+	var fields strings.Builder
+	fields.WriteString(";risk_scan:goplus")
+
+	switch risks.IsHoneypot {
+	case "1":
+		fields.WriteString(";risk_honeypot:yes")
+	case "0":
+		fields.WriteString(";risk_honeypot:no")
+	}
+
+	highTax := false
+	for _, tax := range []struct{ key, raw string }{{"risk_buy_tax", risks.BuyTax}, {"risk_sell_tax", risks.SellTax}} {
+		value, err := strconv.ParseFloat(tax.raw, 64)
+		if err != nil {
+			continue
+		}
+		fields.WriteString(fmt.Sprintf(";%s:%.1f%%", tax.key, value*100))
+		highTax = highTax || value > highTaxThreshold
+	}
+	if highTax {
+		fields.WriteString(";risk_high_tax:yes")
+	}
+
+	if len(risks.LPHolders) > 0 {
+		locked := 0.0
+		for _, holder := range risks.LPHolders {
+			if share, err := strconv.ParseFloat(holder.Percent, 64); err == nil && holder.IsLocked == 1 {
+				locked += share * 100
+			}
+		}
+		fields.WriteString(fmt.Sprintf(";risk_lp_locked:%.1f%%", locked))
+		if locked < lowLockedLPThreshold {
+			fields.WriteString(";risk_lp_unlocked:yes")
+		}
+	}
+	return fields.String()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const riskTokenAddress = "0xdddddddddddddddddddddddddddddddddddddddd"
+
+// riskFakes serves an Ethereum DEX pair for riskTokenAddress and the given
+// GoPlus handler.
+func riskFakes(t *testing.T, goplus http.HandlerFunc) map[string]string {
+	t.Helper()
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"dexscreener": body(`{"pairs":[{"chainId":"ethereum","priceUsd":"0.01","baseToken":{"address":"` + riskTokenAddress + `","symbol":"SCAM"},
+			"quoteToken":{"symbol":"WETH"},"liquidity":{"usd":50000}}]}`),
+		"goplus": goplus,
+	})
+	env := f.env()
+	env["GOPLUS_BASE_URL"] = f.url + "/goplus"
+	env["RETRY_BUDGET_PER_MINUTE"] = "0"
+	return env
+}
+
+func TestRiskScanFlagsToken(t *testing.T) {
+	var query string
+	env := riskFakes(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Path + "?" + r.URL.RawQuery
+		respond(w, http.StatusOK, `{"code":1,"message":"OK","result":{"`+riskTokenAddress+`":{
+			"is_honeypot":"1","buy_tax":"0.05","sell_tax":"0.25",
+			"lp_holders":[{"is_locked":1,"percent":"0.1"},{"is_locked":0,"percent":"0.9"}]}}}`)
+	})
+	a := newTestAgent(t, env)
+
+	response, err := a.processTask(context.Background(), session{}, "/price "+riskTokenAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/api/v1/token_security/1?contract_addresses=" + riskTokenAddress; query != want {
+		t.Errorf("GoPlus request = %q, want %q", query, want)
+	}
+	for _, want := range []string{
+		"🛡 **Risk Scan**",
+		"- 🚨 **Possible honeypot:** the token may not be sellable",
+		"- **Buy / Sell Tax:** 5.0% / 25.0% ⚠️ high",
+		"- **Locked Liquidity:** 10.0% ⚠️ mostly unlocked",
+		"risk scan by GOPLUS",
+	} {
+		if !strings.Contains(response, want) {
+			t.Errorf("response missing %q:\n%s", want, response)
+		}
+	}
+}
+
+func TestRiskScanUnavailable(t *testing.T) {
+	env := riskFakes(t, func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusInternalServerError, `{}`)
+	})
+	a := newTestAgent(t, env)
+
+	response, _ := a.processTask(context.Background(), session{}, "/price "+riskTokenAddress)
+	if !strings.Contains(response, "SCAM $0.01") || !strings.Contains(response, "🛡 Risk scan unavailable right now") {
+		t.Errorf("response = %q, want the price and the unavailable note", response)
+	}
+}
+
+func TestRiskScanOnlyForContractLookups(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"cmc": body(cmcQuote("BTC", 60000))})
+	env := f.env()
+	env["GOPLUS_BASE_URL"] = f.url + "/goplus"
+	a := newTestAgent(t, env)
+
+	a.processTask(context.Background(), session{}, "/price btc")
+	if n := f.count("goplus"); n != 0 {
+		t.Errorf("a symbol lookup made %d GoPlus calls", n)
+	}
+}
+
+func TestRiskScanForForcedDexLookup(t *testing.T) {
+	env := riskFakes(t, func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, `{"code":1,"message":"OK","result":{"`+riskTokenAddress+`":{"is_honeypot":"1"}}}`)
+	})
+	a := newTestAgent(t, env)
+
+	response, _ := a.processTask(context.Background(), session{}, "/price "+riskTokenAddress+" --source=dexscreener")
+	if !strings.Contains(response, "SCAM $0.01") || !strings.Contains(response, "🚨 **Possible honeypot:**") {
+		t.Errorf("--source=dexscreener response = %q, want the price and the risk scan", response)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// --- Scaled Prices (--scaled) ---
+
+const (
+	// tinyPriceThreshold and hugePriceThreshold bound the prices --scaled
+	// leaves alone; anything in between already reads naturally.
+	tinyPriceThreshold = 0.01
+	hugePriceThreshold = 100_000
+)
+
+// priceScales are the lot sizes tried for tiny prices, smallest first.
+var priceScales = []struct {
+	name     string
+	exponent int
+}{{"thousand", 3}, {"million", 6}, {"billion", 9}, {"trillion", 12}}
+
+// scaledPrice picks a lot size that makes an extreme price readable: the
+// smallest lot of a tiny-priced token worth at least one unit of currency,
+// or a thousandth of a huge-priced one. It reports false for ordinary prices.
+func scaledPrice(price float64) (value float64, lot string, exponent int, ok bool) {
+	switch {
+	case price <= 0:
+		return 0, "", 0, false
+	case price < tinyPriceThreshold:
+		scale := priceScales[len(priceScales)-1]
+		for _, s := range priceScales {
+			if price*math.Pow10(s.exponent) >= 1 {
+				scale = s
+				break
+			}
+		}
+		return price * math.Pow10(scale.exponent), "per " + scale.name + " tokens", scale.exponent, true
+	case price >= hugePriceThreshold:
+		return price / 1000, "per 0.001 token", -3, true
+	}
+	return 0, "", 0, false
+}
+
+// withScaledView adds the --scaled representation of an extreme price.
+// Like withPairView it only changes presentation, so it runs after the cache.
+func withScaledView(response string, flags map[string]string) string {
+	if _, scaled := flags["scaled"]; !scaled {
+		return response
+	}
+	price, err := strconv.ParseFloat(parseRawOutput(response)["price_value"], 64)
+	if err != nil {
+		return response
+	}
+	value, lot, exponent, ok := scaledPrice(price)
+	if !ok {
+		return response
+	}
+	// Lots are usually worth at least one unit of currency, but a price below
+	// the largest lot's reach stays sub-unit and needs significant digits
+	formatted := formatPrice(value, responseCurrency(response))
+	return response + fmt.Sprintf(";scaled_price:%s %s (price × 1e%d)", formatted, lot, exponent)
+}
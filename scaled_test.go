@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestScaledPrice(t *testing.T) {
+	tests := []struct {
+		price    float64
+		value    float64
+		lot      string
+		exponent int
+		ok       bool
+	}{
+		{0.0000012, 1.2, "per million tokens", 6, true},
+		{0.0000009, 900, "per billion tokens", 9, true}, // a million is worth under $1
+		{0.005, 5, "per thousand tokens", 3, true},
+		{1e-15, 0.001, "per trillion tokens", 12, true}, // capped at the largest lot
+		{250000, 250, "per 0.001 token", -3, true},
+		{0.01, 0, "", 0, false},
+		{1.5, 0, "", 0, false},
+		{0, 0, "", 0, false},
+	}
+	for _, tt := range tests {
+		value, lot, exponent, ok := scaledPrice(tt.price)
+		if ok != tt.ok || lot != tt.lot || exponent != tt.exponent || math.Abs(value-tt.value) > 1e-9*math.Max(1, tt.value) {
+			t.Errorf("scaledPrice(%v) = %v, %q, %d, %v; want %v, %q, %d, %v",
+				tt.price, value, lot, exponent, ok, tt.value, tt.lot, tt.exponent, tt.ok)
+		}
+	}
+}
+
+func TestScaledFlagShowsPerMillion(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("symbol") == "PEPE" {
+				respond(w, http.StatusOK, cmcQuote("PEPE", 0.0000012))
+				return
+			}
+			respond(w, http.StatusOK, cmcQuote("ETH", 3000))
+		},
+	})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	want := "- **Scaled:** $1.20 per million tokens (price × 1e6)"
+	if response, _ := a.processTask(ctx, session{}, "/price pepe --scaled"); !strings.Contains(response, want) {
+		t.Errorf("response is missing %q:\n%s", want, response)
+	}
+	if response, _ := a.processTask(ctx, session{}, "/price pepe"); strings.Contains(response, "Scaled") {
+		t.Errorf("scaled view shown without --scaled:\n%s", response)
+	}
+	if response, _ := a.processTask(ctx, session{}, "/price eth --scaled"); strings.Contains(response, "Scaled") {
+		t.Errorf("scaled view shown for an ordinary price:\n%s", response)
+	}
+}
+
+func TestScaledViewKeepsSubUnitLotsReadable(t *testing.T) {
+	raw := "token_source:dexscreener;currency:usd;price_value:1e-15"
+	want := ";scaled_price:$0.001 per trillion tokens (price × 1e12)"
+	if got := withScaledView(raw, map[string]string{"scaled": ""}); got != raw+want {
+		t.Errorf("withScaledView(1e-15) = %q, want the suffix %q", got, want)
+	}
+
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"cmc": body(cmcQuote("DUST", 1e-15))})
+	a := newTestAgent(t, f.env())
+	response, _ := a.processTask(context.Background(), session{}, "/price dust --scaled")
+	if want := "- **Scaled:** $0.001 per trillion tokens (price × 1e12)"; !strings.Contains(response, want) {
+		t.Errorf("response is missing %q:\n%s", want, response)
+	}
+}
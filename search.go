@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// --- CoinGecko Search (ID Recovery) ---
+
+// CoinGeckoSearchResponse is the /search response; only coins are used.
+type CoinGeckoSearchResponse struct {
+	Coins []CoinGeckoSearchCoin `json:"coins"`
+}
+
+// CoinGeckoSearchCoin is one coin search hit. Unranked coins have rank 0.
+type CoinGeckoSearchCoin struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Symbol        string `json:"symbol"`
+	MarketCapRank int    `json:"market_cap_rank"`
+}
+
+// searchCoinGecko runs a /search query. On failure it returns a
+// human-readable message alongside the error (which may be nil).
+func (a *PMOAgent) searchCoinGecko(ctx context.Context, query string) (*CoinGeckoSearchResponse, string, error) {
+	req, err := a.newCoinGeckoRequest(ctx, fmt.Sprintf("/search?query=%s", url.QueryEscape(query)))
+	if err != nil {
+		slog.Error("Error creating CG search request", "err", err)
+		return nil, "Error creating HTTP request.", err
+	}
+
+	var search CoinGeckoSearchResponse
+	status, err := a.fetchJSON(req, &search)
+	if errors.Is(err, errResponseTooLarge) {
+		return nil, "Error: CoinGecko response too large.", err
+	}
+	if status == 0 {
+		return nil, "Error contacting CoinGecko API.", err
+	}
+	if status != http.StatusOK {
+		slog.Warn("CoinGecko search API returned an error status", "status", status, "query", query)
+		return nil, fmt.Sprintf("Error: CoinGecko API returned status %d. Could not search coins.", status), nil
+	}
+	if err != nil {
+		return nil, "Error processing CG API response.", err
+	}
+
+	return &search, "", nil
+}
+
+// searchCoinGeckoID finds the CoinGecko ID for a guessed ID, symbol or name.
+// CoinGecko ranks results by relevance and market cap, so the first coin
+// whose ID, symbol or name matches the guess exactly wins. Fuzzy hits are
+// never used: a mistyped ticker must fail rather than price an unrelated coin.
+func (a *PMOAgent) searchCoinGeckoID(ctx context.Context, guess string) (string, bool) {
+	query := strings.ReplaceAll(guess, "-", " ")
+	search, _, err := a.searchCoinGecko(ctx, query)
+	if search == nil || len(search.Coins) == 0 {
+		slog.Warn("CoinGecko search found nothing", "query", query, "err", err)
+		return "", false
+	}
+
+	for _, coin := range search.Coins {
+		if strings.EqualFold(coin.ID, guess) || strings.EqualFold(coin.Symbol, guess) || strings.EqualFold(coin.Name, query) {
+			return coin.ID, true
+		}
+	}
+	slog.Debug("CoinGecko search has no exact match", "query", query, "top_hit", search.Coins[0].ID)
+	return "", false
+}
+
+// maxCollisions caps how many same-ticker coins /collisions lists.
+const maxCollisions = 10
+
+// symbolCollisions returns the search hits whose ticker is exactly symbol,
+// ranked coins first by market cap rank, then unranked ones in search order.
+func symbolCollisions(coins []CoinGeckoSearchCoin, symbol string) []CoinGeckoSearchCoin {
+	var matches []CoinGeckoSearchCoin
+	for _, coin := range coins {
+		if strings.EqualFold(coin.Symbol, symbol) {
+			matches = append(matches, coin)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		ri, rj := matches[i].MarketCapRank, matches[j].MarketCapRank
+		if (ri > 0) != (rj > 0) {
+			return ri > 0
+		}
+		return ri > 0 && ri < rj
+	})
+	return matches
+}
+
+// getSymbolCollisions handles `/collisions <symbol>`, listing every CoinGecko
+// coin that shares the ticker so users can look the right one up by ID.
+func (a *PMOAgent) getSymbolCollisions(ctx context.Context, args []string, _ map[string]string) (string, error) {
+	symbol := strings.ToLower(args[0])
+	search, message, err := a.searchCoinGecko(ctx, symbol)
+	if search == nil {
+		return message, err
+	}
+
+	matches := symbolCollisions(search.Coins, symbol)
+	if len(matches) == 0 {
+		return fmt.Sprintf("CoinGecko has no coins with the ticker %s.", strings.ToUpper(symbol)), nil
+	}
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString(fmt.Sprintf("🔀 **Coins Using the Ticker %s** (%d found)\n", strings.ToUpper(symbol), len(matches)))
+	for i, coin := range matches {
+		if i == maxCollisions {
+			responseBuilder.WriteString(fmt.Sprintf("- ...and %d more\n", len(matches)-maxCollisions))
+			break
+		}
+		rank := "unranked"
+		if coin.MarketCapRank > 0 {
+			rank = fmt.Sprintf("rank #%d", coin.MarketCapRank)
+		}
+		responseBuilder.WriteString(fmt.Sprintf("%d. **%s** (%s) — ID `%s`\n", i+1, coin.Name, rank, coin.ID))
+	}
+	responseBuilder.WriteString("\nLook one up by ID with `/price <id> --source=coingecko`.\n")
+	responseBuilder.WriteString("\n*(Data provided by COINGECKO)*")
+
+	return responseBuilder.String(), nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCoinGeckoSearchRecoversWrongIDGuess(t *testing.T) {
+	var searches []string
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/search":
+				searches = append(searches, r.URL.Query().Get("query"))
+				respond(w, http.StatusOK, `{"coins":[
+					{"id":"shiba-inu-fork","name":"Shiba Inu Fork","symbol":"SHIBF","market_cap_rank":900},
+					{"id":"shib","name":"Shiba Inu","symbol":"SHIB","market_cap_rank":20}]}`)
+			case "/coins/shib":
+				respond(w, http.StatusOK, cgCoin("shib", "shib", 0.00001))
+			default:
+				respond(w, http.StatusNotFound, `{"error":"coin not found"}`)
+			}
+		},
+	})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	response, err := a.getCoinGeckoData(ctx, "shiba-inu", "usd")
+	if err != nil || !strings.Contains(response, "coin_id:shib;") {
+		t.Fatalf("getCoinGeckoData = %q, %v; want the search match's data", response, err)
+	}
+	if len(searches) != 1 || searches[0] != "shiba inu" {
+		t.Errorf("searches = %q, want one query for the guess", searches)
+	}
+
+	// A guess with no exact match fails instead of pricing a fuzzy hit
+	searches = nil
+	response, _ = a.getCoinGeckoData(ctx, "shiba-fork", "usd")
+	if !strings.Contains(response, "status 404") {
+		t.Errorf("getCoinGeckoData(shiba-fork) = %q, want the original 404", response)
+	}
+	if len(searches) != 1 {
+		t.Errorf("made %d searches, want exactly one retry", len(searches))
+	}
+}
+
+func TestSymbolCollisionsSortsByRank(t *testing.T) {
+	coins := []CoinGeckoSearchCoin{
+		{ID: "uni-fan-token", Symbol: "UNI"},
+		{ID: "unicorn", Symbol: "UNICORN", MarketCapRank: 400},
+		{ID: "uniswap", Symbol: "uni", MarketCapRank: 20},
+		{ID: "universe", Symbol: "UNI", MarketCapRank: 3000},
+		{ID: "unidex", Symbol: "UNI"},
+	}
+
+	var ids []string
+	for _, coin := range symbolCollisions(coins, "UNI") {
+		ids = append(ids, coin.ID)
+	}
+	// Ranked first by rank, then unranked in search order; UNICORN is only a prefix
+	if want := "uniswap universe uni-fan-token unidex"; strings.Join(ids, " ") != want {
+		t.Errorf("symbolCollisions = %v, want %s", ids, want)
+	}
+}
+
+func TestCollisionsCommand(t *testing.T) {
+	coins := []string{`{"id":"uniswap","name":"Uniswap","symbol":"UNI","market_cap_rank":20}`}
+	for i := range maxCollisions + 2 {
+		coins = append(coins, fmt.Sprintf(`{"id":"uni-%d","name":"Uni %d","symbol":"UNI"}`, i, i))
+	}
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": body(`{"coins":[` + strings.Join(coins, ",") + `]}`),
+	})
+	a := newTestAgent(t, f.env())
+
+	response, _ := a.processTask(context.Background(), session{}, "/collisions uni")
+	for _, want := range []string{
+		"🔀 **Coins Using the Ticker UNI** (13 found)",
+		"1. **Uniswap** (rank #20) — ID `uniswap`",
+		"2. **Uni 0** (unranked) — ID `uni-0`",
+		"- ...and 3 more",
+	} {
+		if !strings.Contains(response, want) {
+			t.Errorf("response is missing %q:\n%s", want, response)
+		}
+	}
+	if strings.Contains(response, "`uni-9`") {
+		t.Errorf("more than %d coins were listed:\n%s", maxCollisions, response)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// --- Stablecoin Denomination (--vs) ---
+
+// stablecoins are the USD-pegged coins accepted by --vs.
+var stablecoins = map[string]bool{"usdt": true, "usdc": true, "dai": true}
+
+// withStablecoinView adds the price in the --vs stablecoin, using that coin's
+// actual USD market price rather than assuming a perfect peg. Like
+// withPairView it runs after the cache, so the peg is always current. The
+// stablecoin lookup itself goes through the normal (cached) lookup path.
+func (a *PMOAgent) withStablecoinView(ctx context.Context, response string, flags map[string]string) string {
+	vs := strings.ToLower(flags["vs"])
+	if vs == "" || responseCurrency(response) != "usd" {
+		return response
+	}
+	priceUSD, err := strconv.ParseFloat(parseRawOutput(response)["price_value"], 64)
+	if err != nil || priceUSD <= 0 {
+		return response
+	}
+
+	stable := a.lookupToken(ctx, vs, map[string]string{"currency": "usd"})
+	pegUSD, err := strconv.ParseFloat(parseRawOutput(stable.raw)["price_value"], 64)
+	if !stable.found || err != nil || pegUSD <= 0 {
+		slog.Warn("No USD price for stablecoin, skipping --vs view", "vs", vs, "err", stable.err)
+		return response
+	}
+
+	return response + fmt.Sprintf(";vs_symbol:%s;vs_price:%s;vs_peg:%s",
+		strings.ToUpper(vs), formatPrice(priceUSD/pegUSD, vs), formatPrice(pegUSD, "usd"))
+}
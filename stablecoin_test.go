@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestVsStablecoinUsesMarketPeg(t *testing.T) {
+	prices := map[string]float64{"BTC": 60000, "USDT": 0.998}
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			symbol := r.URL.Query().Get("symbol")
+			respond(w, http.StatusOK, cmcQuote(symbol, prices[symbol]))
+		},
+	})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	response, _ := a.processTask(ctx, session{}, "/price btc --vs=usdt")
+	if !strings.Contains(response, "$60,000.00") {
+		t.Errorf("the USD price is missing:\n%s", response)
+	}
+	// 60000 / 0.998, not 60000 as a perfect peg would give
+	if want := "**Price (USDT):** 60,120.24 USDT (USDT at $0.998)"; !strings.Contains(response, want) {
+		t.Errorf("response is missing %q:\n%s", want, response)
+	}
+
+	if response, _ := a.processTask(ctx, session{}, "/price btc"); strings.Contains(response, "USDT") {
+		t.Errorf("a plain lookup showed the stablecoin view:\n%s", response)
+	}
+}
+
+func TestVsRejectsUnsupportedOptions(t *testing.T) {
+	a := newTestAgent(t, nil)
+	ctx := context.Background()
+
+	if response, _ := a.processTask(ctx, session{}, "/price btc --vs=busd"); !strings.Contains(response, "Unsupported --vs: busd") {
+		t.Errorf("--vs=busd response = %q", response)
+	}
+	if response, _ := a.processTask(ctx, session{}, "/price btc in eur --vs=usdt"); !strings.Contains(response, "can't be combined with EUR") {
+		t.Errorf("--vs with a fiat response = %q", response)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// getStats handles `/stats`, reporting cache effectiveness so operators can tune CACHE_TTL_SECONDS.
+func (a *PMOAgent) getStats(ctx context.Context, _ []string, _ map[string]string) (string, error) {
+	hits, misses := a.cache.stats()
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString("📊 **Agent Stats**\n")
+	responseBuilder.WriteString(fmt.Sprintf("- **Cache Hits:** %d\n", hits))
+	responseBuilder.WriteString(fmt.Sprintf("- **Cache Misses:** %d\n", misses))
+	responseBuilder.WriteString(fmt.Sprintf("- **Hit Ratio:** %.1f%%\n", a.cache.hitRatio()))
+	responseBuilder.WriteString(fmt.Sprintf("- **Cache TTL:** %s\n", a.config.CacheTTL))
+	responseBuilder.WriteString(fmt.Sprintf("- **Cache Entries:** %d (max %d)\n", a.cache.size(), maxCacheEntries))
+
+	return responseBuilder.String(), nil
+}
+
+// getStatus handles `/status`, a quick self-diagnosis for users and operators:
+// version, uptime, provider health since startup and cache effectiveness.
+func (a *PMOAgent) getStatus(ctx context.Context, _ []string, _ map[string]string) (string, error) {
+	hits, misses := a.cache.stats()
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString("🩺 **Agent Status**\n")
+	responseBuilder.WriteString(fmt.Sprintf("- **Version:** %s\n", version))
+	responseBuilder.WriteString(fmt.Sprintf("- **Uptime:** %s\n", time.Since(a.startedAt).Truncate(time.Second)))
+	responseBuilder.WriteString(fmt.Sprintf("- **Cache Hit Ratio:** %.1f%% (%d hits, %d misses)\n", a.cache.hitRatio(), hits, misses))
+
+	responseBuilder.WriteString("\n**Provider Health** (since startup)\n")
+	providers := append([]priceProvider{a.dexProvider}, a.cexProviders...)
+	// Providers outside the chain (e.g. Binance by default) still serve --source
+	for _, name := range []string{"cmc", "coingecko", "binance"} {
+		if p, ok := a.sourceProviders[name]; ok && !slices.ContainsFunc(a.cexProviders, func(c priceProvider) bool { return c.name == name }) {
+			providers = append(providers, p)
+		}
+	}
+	for _, provider := range providers {
+		attempts, failures := a.health.totals(provider.name)
+		if attempts == 0 {
+			responseBuilder.WriteString(fmt.Sprintf("- **%s:** no calls yet\n", provider.name))
+			continue
+		}
+		icon := "🟢"
+		if failures*2 >= attempts {
+			icon = "🔴"
+		} else if failures > 0 {
+			icon = "🟡"
+		}
+		responseBuilder.WriteString(fmt.Sprintf("- %s **%s:** %d calls, %d failed (%.0f%%)\n", icon, provider.name, attempts, failures,
+			float64(failures)/float64(attempts)*100))
+	}
+
+	return responseBuilder.String(), nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestStatusReportsHealthSections(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"coingecko": body(cgCoin("bitcoin", "btc", 60000))})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	// CMC 404s and CoinGecko answers; the repeat is a cache hit
+	a.processTask(ctx, session{}, "/price btc")
+	a.processTask(ctx, session{}, "/price btc")
+
+	response, err := a.processTask(ctx, session{}, "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"🩺 **Agent Status**",
+		"- **Version:** " + version,
+		"- **Uptime:** ",
+		"- **Cache Hit Ratio:** 50.0% (1 hits, 1 misses)",
+		"**Provider Health** (since startup)",
+		"- **dexscreener:** no calls yet",
+		"- 🔴 **cmc:** 1 calls, 1 failed (100%)",
+		"- 🟢 **coingecko:** 1 calls, 0 failed (0%)",
+		"- **binance:** no calls yet",
+	} {
+		if !strings.Contains(response, want) {
+			t.Errorf("/status missing %q:\n%s", want, response)
+		}
+	}
+}
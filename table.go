@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// --- Table Rendering (--table) ---
+
+// tableSymbol picks the label for a result row: the DEX base token when known,
+// a shortened address for other contracts, or the requested ticker.
+func tableSymbol(result tokenResult, parts map[string]string) string {
+	if base := parts["base_token"]; base != "" {
+		return strings.ToUpper(base)
+	}
+	if isContractAddress(strings.ToLower(result.target)) {
+		return truncateAddress(result.target)
+	}
+	return strings.ToUpper(result.target)
+}
+
+// tableChange renders the 24h change with an explicit sign so columns of
+// gains and losses line up, keeping the provider's CHANGE_DECIMALS precision.
+func tableChange(change string) string {
+	value, err := strconv.ParseFloat(strings.TrimSuffix(change, "%"), 64)
+	if err != nil {
+		if change == "" {
+			return "n/a"
+		}
+		return change
+	}
+	if value >= 0 && !strings.HasPrefix(change, "+") {
+		return "+" + change
+	}
+	return change
+}
+
+// renderTable lays out successful lookups as a monospaced table. Column widths
+// come from the longest cell, counted in runes so currency symbols such as €
+// don't skew the padding. Text columns are left-aligned, numbers right-aligned.
+// hideSource drops the Source column, as --nosource drops the footer.
+func renderTable(results []tokenResult, hideSource bool) string {
+	rows := make([][]string, 0, len(results))
+	for _, result := range results {
+		parts := parseRawOutput(result.raw)
+		price := parts["current_price_"+responseCurrency(result.raw)]
+		if price == "" {
+			price = "n/a"
+		}
+		rows = append(rows, []string{tableSymbol(result, parts), price, tableChange(parts["24h_change"]), strings.ToUpper(parts["token_source"])})
+	}
+
+	// DEX prices are always USD, so the currency lives in each price cell rather than the header
+	header := []string{"Symbol", "Price", "24h", "Source"}
+	rightAligned := []bool{false, true, true, false}
+	if hideSource {
+		header, rightAligned = header[:3], rightAligned[:3]
+		for i := range rows {
+			rows[i] = rows[i][:3]
+		}
+	}
+
+	return formatGrid(header, rows, rightAligned)
+}
+
+// formatGrid renders a header and rows as a monospaced code block, aligning
+// each column to the side given by rightAligned.
+func formatGrid(header []string, rows [][]string, rightAligned []bool) string {
+	widths := make([]int, len(header))
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	formatRow := func(row []string) string {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			padding := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			if rightAligned[i] {
+				cells[i] = padding + cell
+			} else {
+				cells[i] = cell + padding
+			}
+		}
+		return strings.TrimRight(strings.Join(cells, " | "), " ")
+	}
+
+	separators := make([]string, len(widths))
+	for i, width := range widths {
+		separators[i] = strings.Repeat("-", width)
+	}
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString("```\n")
+	responseBuilder.WriteString(formatRow(header) + "\n")
+	responseBuilder.WriteString(strings.Join(separators, "-+-") + "\n")
+	for _, row := range rows {
+		responseBuilder.WriteString(formatRow(row) + "\n")
+	}
+	responseBuilder.WriteString("```")
+	return responseBuilder.String()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// separatorColumns returns the rune offsets of sep in line.
+func separatorColumns(line string, sep rune) []int {
+	var columns []int
+	for i, r := range []rune(line) {
+		if r == sep {
+			columns = append(columns, i)
+		}
+	}
+	return columns
+}
+
+func TestRenderTableAlignsColumns(t *testing.T) {
+	results := []tokenResult{
+		{target: "btc", raw: "token_source:cmc;current_price_usd:$63,245.12;24h_change:2.50%"},
+		{target: "shib", raw: "token_source:coingecko;current_price_usd:$0.00001234;24h_change:-1.20%"},
+		{target: "eth", raw: "token_source:cmc;currency:eur;current_price_eur:€3,000.00"},
+	}
+	table := renderTable(results, false)
+
+	lines := strings.Split(strings.Trim(table, "`\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("table has %d lines, want a header, a separator and 3 rows:\n%s", len(lines), table)
+	}
+	want := separatorColumns(lines[0], '|')
+	if got := separatorColumns(lines[1], '+'); !slices.Equal(got, want) {
+		t.Errorf("separator line crosses at %v, want %v:\n%s", got, want, table)
+	}
+	for _, line := range lines[2:] {
+		if got := separatorColumns(line, '|'); !slices.Equal(got, want) {
+			t.Errorf("separators at %v, want %v:\n%s", got, want, table)
+		}
+	}
+	for _, want := range []string{"+2.50%", "-1.20%", "n/a", "€3,000.00", "COINGECKO"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("table missing %q:\n%s", want, table)
+		}
+	}
+}
+
+func TestRenderTableRightAlignsPrices(t *testing.T) {
+	results := []tokenResult{
+		{target: "btc", raw: "token_source:cmc;current_price_usd:$63,245.12"},
+		{target: "doge", raw: "token_source:cmc;current_price_usd:$0.10"},
+	}
+	table := renderTable(results, true)
+	if !strings.Contains(table, "DOGE   |      $0.10 |") {
+		t.Errorf("the short price is not right-aligned:\n%s", table)
+	}
+	if strings.Contains(table, "Source") {
+		t.Errorf("hideSource kept the Source column:\n%s", table)
+	}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// --- Market Overview Rendering ---
+
+// MarketData is the parsed form of a raw provider response, and the data
+// passed to market overview templates and returned by the HTTP API. Fields a
+// provider doesn't supply are empty strings, so templates can test them with
+// {{if .Field}} and JSON omits them.
+type MarketData struct {
+	Name     string `json:"name"`             // Token name, or "Token" when the provider gives none
+	Symbol   string `json:"symbol,omitempty"` // Ticker, e.g. "BTC"; the base token for DEX results
+	Source   string `json:"source"`           // Provider that served the data, e.g. "coingecko"
+	Currency string `json:"currency"`         // Lower-case quote currency, e.g. "usd"
+
+	Price             string  `json:"price,omitempty"`
+	PriceValue        float64 `json:"price_value,omitempty"`  // Unrounded price, for machine consumers
+	PriceNote         string  `json:"price_note,omitempty"`   // Set when the price is shown in a fallback currency
+	VsSymbol          string  `json:"vs_symbol,omitempty"`    // --vs stablecoin, e.g. "USDT"
+	VsPrice           string  `json:"vs_price,omitempty"`     // Price in that stablecoin
+	VsPeg             string  `json:"vs_peg,omitempty"`       // The stablecoin's own USD price
+	ScaledPrice       string  `json:"scaled_price,omitempty"` // --scaled, e.g. "$1.23 per million tokens (price × 1e6)"
+	Change24h         string  `json:"change_24h,omitempty"`   // e.g. "-2.10%"
+	MarketCap         string  `json:"market_cap,omitempty"`
+	Volume24h         string  `json:"volume_24h,omitempty"`
+	LowTrustVolume    string  `json:"low_trust_volume,omitempty"`     // Share of volume on low-trust markets, when most of it is
+	VolumeToMarketCap float64 `json:"volume_to_market_cap,omitempty"` // 24h volume as a percentage of market cap
+	Liquidity         string  `json:"liquidity,omitempty"`
+	FDV               string  `json:"fdv,omitempty"`
+	CirculatingSupply string  `json:"circulating_supply,omitempty"`
+	TotalSupply       string  `json:"total_supply,omitempty"`
+	High24h           string  `json:"high_24h,omitempty"`
+	Low24h            string  `json:"low_24h,omitempty"`
+	RangePosition24h  string  `json:"range_position_24h,omitempty"` // Where the price sits in the 24h range, "0%" at the low
+	From7dHigh        string  `json:"from_7d_high,omitempty"`       // e.g. "-12.0%"; 0 or below (CoinGecko with --details)
+	FromATH           string  `json:"from_ath,omitempty"`
+
+	ChainID         string `json:"chain_id,omitempty"`
+	BaseToken       string `json:"base_token,omitempty"`
+	Image           string `json:"image,omitempty"` // Logo URL (CoinGecko only)
+	ShowLogo        bool   `json:"-"`               // --logo: render Image in markdown output
+	ContractAddress string `json:"contract_address,omitempty"`
+	ContractChain   string `json:"contract_chain,omitempty"` // Issuing chain name, from CMC's platform
+	DexID           string `json:"dex_id,omitempty"`         // Dexscreener DEX ID of the pool used, e.g. "uniswap"
+	Pair            string `json:"pair,omitempty"`           // That pool's pair, e.g. "WBTC/USDC"
+
+	// DEX pair sides: the base price in quote units and its inverse
+	QuoteToken    string `json:"quote_token,omitempty"`
+	PriceInQuote  string `json:"price_in_quote,omitempty"`
+	QuoteInBase   string `json:"quote_in_base,omitempty"`
+	QuotePriceUSD string `json:"quote_price_usd,omitempty"`
+	ShowQuote     bool   `json:"-"` // --quote: show the pair from the quote token's side too
+
+	// Native asset behind a wrapped native DEX token (e.g. ETH for WETH)
+	NativeSymbol string `json:"native_symbol,omitempty"`
+	NativePrice  string `json:"native_price,omitempty"`
+	NativeSource string `json:"native_source,omitempty"`
+
+	// CoinGecko details merged into a DEX result by /market (or --details)
+	CGRank              string `json:"coingecko_rank,omitempty"`
+	CGMarketCap         string `json:"coingecko_market_cap,omitempty"`
+	CGCirculatingSupply string `json:"coingecko_circulating_supply,omitempty"`
+	CGTotalSupply       string `json:"coingecko_total_supply,omitempty"`
+	CGATH               string `json:"coingecko_ath,omitempty"`
+
+	// Contract risk scan for DEX tokens; RiskScan is "goplus", or "unavailable" when the scan failed
+	RiskScan         string `json:"risk_scan,omitempty"`
+	Honeypot         string `json:"honeypot,omitempty"` // "yes" or "no" when the scanner could tell
+	BuyTax           string `json:"buy_tax,omitempty"`
+	SellTax          string `json:"sell_tax,omitempty"`
+	HighTax          bool   `json:"high_tax,omitempty"`
+	LPLocked         string `json:"lp_locked,omitempty"` // Share of the LP supply that is locked
+	LPMostlyUnlocked bool   `json:"lp_mostly_unlocked,omitempty"`
+
+	Ambiguous   bool   `json:"ambiguous,omitempty"`    // Providers disagree wildly on the price
+	LastUpdated string `json:"last_updated,omitempty"` // RFC 3339, when the provider reports it
+	StaleFor    string `json:"stale_for,omitempty"`    // Set when the data is older than the stale threshold
+	CachedFor   string `json:"cached_for,omitempty"`   // Set when every provider failed and a cached entry is served
+
+	// HideSource omits the attribution footer (--nosource). Machine formats
+	// keep the source field, since there it is data rather than a footer.
+	HideSource bool `json:"-"`
+}
+
+// parseMarketData converts a raw provider response into MarketData.
+func parseMarketData(rawOutput string) MarketData {
+	parts := parseRawOutput(rawOutput)
+	currency := responseCurrency(rawOutput)
+
+	data := MarketData{
+		Name:                parts["name"],
+		Symbol:              parts["symbol"],
+		Source:              parts["token_source"],
+		Currency:            currency,
+		Price:               parts["current_price_"+currency],
+		PriceNote:           parts["price_note"],
+		VsSymbol:            parts["vs_symbol"],
+		VsPrice:             parts["vs_price"],
+		VsPeg:               parts["vs_peg"],
+		ScaledPrice:         parts["scaled_price"],
+		Change24h:           parts["24h_change"],
+		MarketCap:           parts["market_cap_"+currency],
+		Volume24h:           parts["volume_24h"],
+		LowTrustVolume:      parts["low_trust_volume"],
+		Liquidity:           parts["liquidity_usd"],
+		FDV:                 parts["fdv"],
+		CirculatingSupply:   parts["circulating_supply"],
+		TotalSupply:         parts["total_supply"],
+		High24h:             parts["high_24h"],
+		Low24h:              parts["low_24h"],
+		RangePosition24h:    parts["range_position_24h"],
+		From7dHigh:          parts["from_7d_high"],
+		FromATH:             parts["from_ath"],
+		ChainID:             parts["chain_id"],
+		BaseToken:           parts["base_token"],
+		Image:               parts["image"],
+		ContractAddress:     parts["contract_address"],
+		ContractChain:       parts["contract_chain"],
+		DexID:               parts["dex_id"],
+		Pair:                parts["pair"],
+		QuoteToken:          parts["quote_token"],
+		PriceInQuote:        parts["price_in_quote"],
+		QuoteInBase:         parts["quote_in_base"],
+		QuotePriceUSD:       parts["quote_price_usd"],
+		NativeSymbol:        parts["native_symbol"],
+		NativePrice:         parts["native_price"],
+		NativeSource:        parts["native_source"],
+		CGRank:              parts["cg_rank"],
+		CGMarketCap:         parts["cg_market_cap"],
+		CGCirculatingSupply: parts["cg_circulating_supply"],
+		CGTotalSupply:       parts["cg_total_supply"],
+		CGATH:               parts["cg_ath"],
+		RiskScan:            parts["risk_scan"],
+		Honeypot:            parts["risk_honeypot"],
+		BuyTax:              parts["risk_buy_tax"],
+		SellTax:             parts["risk_sell_tax"],
+		HighTax:             parts["risk_high_tax"] == "yes",
+		LPLocked:            parts["risk_lp_locked"],
+		LPMostlyUnlocked:    parts["risk_lp_unlocked"] == "yes",
+		LastUpdated:         parts["last_updated"],
+		StaleFor:            parts["stale_for"],
+		CachedFor:           parts["cached_for"],
+	}
+	data.PriceValue, _ = strconv.ParseFloat(parts["price_value"], 64)
+	// Computed before any --compact rewriting, while the figures are exact
+	data.VolumeToMarketCap, _ = volumeToMarketCap(data.Volume24h, data.MarketCap)
+	_, data.Ambiguous = parts["ambiguity_warning"]
+	data.ShowQuote = parts["pair_view"] == "quote"
+
+	if data.Symbol == "" {
+		data.Symbol = data.BaseToken
+	}
+	// The CMC response contains the full name, which is ideal
+	if data.Name == "" {
+		data.Name = "Token" // Fallback if name is missing
+	}
+	if data.CirculatingSupply == "N/A" {
+		data.CirculatingSupply = ""
+	}
+	return data
+}
+
+// HasData reports whether any market figure is present, as opposed to a
+// response that would render as nothing but a header.
+func (d MarketData) HasData() bool {
+	for _, field := range []string{d.Price, d.Change24h, d.MarketCap, d.Volume24h, d.Liquidity, d.FDV, d.CirculatingSupply} {
+		if field != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// compactFigures abbreviates the large currency figures (market cap, volume,
+// liquidity, FDV) with K/M/B/T suffixes. Prices are left at full precision.
+func (d *MarketData) compactFigures() {
+	for _, field := range []*string{&d.MarketCap, &d.Volume24h, &d.Liquidity, &d.FDV, &d.CGMarketCap} {
+		*field = compactFigure(*field)
+	}
+}
+
+// compactFigure rewrites the number inside an already formatted amount such as
+// "$1,234,567.00" or "1,234,567.00 CHF", keeping its currency decoration.
+// Anything it can't parse is returned unchanged.
+func compactFigure(formatted string) string {
+	start := strings.IndexAny(formatted, "0123456789")
+	end := strings.LastIndexAny(formatted, "0123456789") + 1
+	if start < 0 {
+		return formatted
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(formatted[start:end], ",", ""), 64)
+	if err != nil || value < 1000 {
+		return formatted
+	}
+	return formatted[:start] + formatLargeNumber(value) + formatted[end:]
+}
+
+// Summary is a one-line gist of the result for quick glancing, e.g.
+// "BTC $63,245.12 🟢 +2.10% (24h)". The change is left off when unknown, and
+// the name stands in for a missing ticker; without a price there is no summary.
+func (d MarketData) Summary() string {
+	if d.Price == "" {
+		return ""
+	}
+	label := d.Symbol
+	if label == "" {
+		label = d.Name
+	}
+	summary := label + " " + d.Price
+	if value, err := strconv.ParseFloat(strings.TrimSuffix(d.Change24h, "%"), 64); err == nil {
+		if value >= 0 {
+			summary += fmt.Sprintf(" 🟢 +%s (24h)", d.Change24h)
+		} else {
+			summary += fmt.Sprintf(" 🔴 %s (24h)", d.Change24h)
+		}
+	}
+	return summary
+}
+
+// HasCGDetails reports whether CoinGecko details were merged into the data.
+func (d MarketData) HasCGDetails() bool {
+	return d.CGRank != "" || d.CGMarketCap != "" || d.CGCirculatingSupply != "" || d.CGTotalSupply != "" || d.CGATH != ""
+}
+
+// changeBadge renders a 24h change with a colour emoji, or the raw string
+// when it isn't a parseable percentage.
+func changeBadge(change string) string {
+	value, err := strconv.ParseFloat(strings.TrimSuffix(change, "%"), 64)
+	if err != nil {
+		return change
+	}
+	if value >= 0 {
+		return fmt.Sprintf("**🟢 +%s**", change)
+	}
+	return fmt.Sprintf("**🔴 %s**", change)
+}
+
+// readableSupplyThreshold is the supply from which the full figure gets a
+// K/M/B/T reading alongside it; smaller counts are easy enough to read.
+const readableSupplyThreshold = 1e6
+
+// readableSupply appends the suffixed form to a formatted supply, e.g.
+// "589,000,000,000,000 (589.00T)". Small, absent or unparseable supplies
+// (including "N/A") are returned unchanged.
+func readableSupply(supply string) string {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(supply, ",", ""), 64)
+	if err != nil || value < readableSupplyThreshold {
+		return supply
+	}
+	return fmt.Sprintf("%s (%s)", supply, formatLargeNumber(value))
+}
+
+// marketTemplateFuncs are available to every market overview template,
+// including custom ones loaded from TEMPLATE_FILE.
+var marketTemplateFuncs = template.FuncMap{
+	"upper":  strings.ToUpper,
+	"short":  truncateAddress,
+	"change": changeBadge,
+	"supply": readableSupply,
+}
+
+// defaultMarketTemplate is the standard market overview layout.
+const defaultMarketTemplate = `💰 **{{.Name}} Price & Market Overview**
+{{- if and .ShowLogo .Image}}
+![{{.Name}} logo]({{.Image}})
+{{- end}}
+{{- with .Summary}}
+**{{.}}**
+{{- end}}
+{{- if .Price}}
+- **Price ({{upper .Currency}}):** {{.Price}}
+{{- end}}
+{{- if .PriceNote}}
+- ⚠️ {{.PriceNote}}
+{{- end}}
+{{- if .VsSymbol}}
+- **Price ({{.VsSymbol}}):** {{.VsPrice}} ({{.VsSymbol}} at {{.VsPeg}})
+{{- end}}
+{{- with .ScaledPrice}}
+- **Scaled:** {{.}}
+{{- end}}
+{{- if .Change24h}}
+- **24h Change:** {{change .Change24h}}
+{{- end}}
+{{- if .High24h}}
+- **24h Range:** {{.Low24h}} – {{.High24h}}{{with .RangePosition24h}} (price at {{.}} of the range){{end}}
+{{- end}}
+{{- if .MarketCap}}
+- **Market Cap:** {{.MarketCap}}
+{{- end}}
+{{- if .Volume24h}}
+- **24h Volume:** {{.Volume24h}}
+{{- end}}
+{{- if .LowTrustVolume}}
+- ⚠️ Volume may be inflated: {{.LowTrustVolume}} trades on low-trust exchanges
+{{- end}}
+{{- with .TurnoverSummary}}
+- **Volume/Market Cap:** {{.}}
+{{- end}}
+{{- if .Liquidity}}
+- **Liquidity:** {{.Liquidity}}
+{{- end}}
+{{- if .FDV}}
+- **Fully Diluted Value (FDV):** {{.FDV}}
+{{- end}}
+{{- if .CirculatingSupply}}
+- **Circulating Supply:** {{supply .CirculatingSupply}}
+{{- end}}
+{{- with .HighsSummary}}
+- **vs Highs:** currently {{.}}
+{{- end}}
+{{- if .QuoteToken}}
+- **Pair (base → quote):** 1 {{.BaseToken}} = {{.PriceInQuote}}
+{{- if .ShowQuote}}
+- **Pair (quote → base):** 1 {{.QuoteToken}} = {{.QuoteInBase}}
+- **{{.QuoteToken}} Price (USD):** {{.QuotePriceUSD}}
+{{- end}}
+{{- end}}
+{{- if .ContractAddress}}
+- **Contract:** ` + "`{{short .ContractAddress}}`" + `{{with .ContractChain}} on {{.}}{{end}}
+{{- end}}
+{{- with .PairAttribution}}
+- **Priced via:** {{.}}
+{{- end}}
+{{- if .NativeSymbol}}
+- ℹ️ This is {{.BaseToken}}, the wrapped form of {{.NativeSymbol}}. Native {{.NativeSymbol}} price via {{upper .NativeSource}}: {{.NativePrice}}
+{{- end}}
+{{- if .HasCGDetails}}
+
+📇 **CoinGecko Details**
+{{- if .CGRank}}
+- **Market Cap Rank:** #{{.CGRank}}
+{{- end}}
+{{- if .CGMarketCap}}
+- **Market Cap:** {{.CGMarketCap}}
+{{- end}}
+{{- if .CGCirculatingSupply}}
+- **Circulating Supply:** {{supply .CGCirculatingSupply}}
+{{- end}}
+{{- if .CGTotalSupply}}
+- **Total Supply:** {{supply .CGTotalSupply}}
+{{- end}}
+{{- if .CGATH}}
+- **All-Time High:** {{.CGATH}}
+{{- end}}
+{{- end}}
+{{- if eq .RiskScan "goplus"}}
+
+🛡 **Risk Scan**
+{{- if eq .Honeypot "yes"}}
+- 🚨 **Possible honeypot:** the token may not be sellable
+{{- else if eq .Honeypot "no"}}
+- ✅ No honeypot behaviour detected
+{{- end}}
+{{- if or .BuyTax .SellTax}}
+- **Buy / Sell Tax:** {{or .BuyTax "?"}} / {{or .SellTax "?"}}{{if .HighTax}} ⚠️ high{{end}}
+{{- end}}
+{{- if .LPLocked}}
+- **Locked Liquidity:** {{.LPLocked}}{{if .LPMostlyUnlocked}} ⚠️ mostly unlocked{{end}}
+{{- end}}
+{{- else if eq .RiskScan "unavailable"}}
+
+🛡 Risk scan unavailable right now
+{{- end}}
+{{- if .Ambiguous}}
+
+⚠️ Ticker ambiguity — specify a contract address or ID
+{{- end}}
+{{- if .StaleFor}}
+
+⚠️ Data may be stale (updated {{.StaleFor}} ago)
+{{- end}}
+{{- if .CachedFor}}
+
+⚠️ All sources unavailable, showing cached data from {{.CachedFor}} ago
+{{- end}}
+{{- if not .HideSource}}
+
+*(Data provided by {{upper .Source}}{{if .HasCGDetails}}, details by COINGECKO{{end}}{{if eq .RiskScan "goplus"}}, risk scan by GOPLUS{{end}})*
+{{- end}}`
+
+// builtinMarketTemplate is parsed once; a broken built-in template is a bug.
+var builtinMarketTemplate = template.Must(template.New("market").Funcs(marketTemplateFuncs).Parse(defaultMarketTemplate))
+
+// loadMarketTemplate parses a custom market overview template from path.
+func loadMarketTemplate(path string) (*template.Template, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New("market").Funcs(marketTemplateFuncs).Parse(string(content))
+}
+
+// markdownFormatter renders the chat markdown overview from a market
+// template, falling back to the built-in one if a custom template fails.
+type markdownFormatter struct {
+	tmpl *template.Template
+}
+
+// Format implements Formatter.
+func (f markdownFormatter) Format(data MarketData) string {
+	var responseBuilder strings.Builder
+	if err := f.tmpl.Execute(&responseBuilder, data); err != nil {
+		// A custom template can fail at runtime (e.g. a bad field name); fall back to the built-in one
+		slog.Error("Market template failed, using the default", "err", err)
+		responseBuilder.Reset()
+		builtinMarketTemplate.Execute(&responseBuilder, data)
+	}
+	return responseBuilder.String()
+}
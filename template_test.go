@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestBuiltinTemplateRendersMarketData(t *testing.T) {
+	data := MarketData{
+		Name:      "Bitcoin",
+		Symbol:    "BTC",
+		Source:    "coingecko",
+		Currency:  "usd",
+		Price:     "$63,245.12",
+		Change24h: "-2.10%",
+		MarketCap: "$1.2T",
+	}
+	got := markdownFormatter{tmpl: builtinMarketTemplate}.Format(data)
+
+	want := "💰 **Bitcoin Price & Market Overview**\n" +
+		"**BTC $63,245.12 🔴 -2.10% (24h)**\n" +
+		"- **Price (USD):** $63,245.12\n" +
+		"- **24h Change:** **🔴 -2.10%**\n" +
+		"- **Market Cap:** $1.2T\n" +
+		"\n*(Data provided by COINGECKO)*"
+	if got != want {
+		t.Errorf("rendered\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCustomTemplateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "market.tmpl")
+	if err := os.WriteFile(path, []byte(`{{upper .Source}} says {{.Name}} is {{.Price}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a := newTestAgent(t, map[string]string{"TEMPLATE_FILE": path})
+
+	got := a.formatOutput("token_source:cmc;name:Bitcoin;current_price_usd:$63,245.12", map[string]string{})
+	if got != "CMC says Bitcoin is $63,245.12" {
+		t.Errorf("formatOutput = %q, want the custom template", got)
+	}
+}
+
+func TestBrokenTemplateFallsBackToBuiltin(t *testing.T) {
+	tmpl := template.Must(template.New("market").Funcs(marketTemplateFuncs).Parse(`{{.Price.Missing}}`))
+	got := markdownFormatter{tmpl: tmpl}.Format(MarketData{Name: "Bitcoin", Source: "cmc", Currency: "usd", Price: "$1.00"})
+	if !strings.HasPrefix(got, "💰 **Bitcoin Price & Market Overview**") {
+		t.Errorf("rendered %q, want the built-in layout", got)
+	}
+}
+
+func TestReadableSupply(t *testing.T) {
+	tests := map[string]string{
+		"589,000,000,000,000": "589,000,000,000,000 (589.00T)",
+		"19,000,000":          "19,000,000 (19.00M)",
+		"999,999":             "999,999",
+		"N/A":                 "N/A",
+		"":                    "",
+	}
+	for supply, want := range tests {
+		if got := readableSupply(supply); got != want {
+			t.Errorf("readableSupply(%q) = %q, want %q", supply, got, want)
+		}
+	}
+}
+
+func TestTrillionsSupplyIsReadable(t *testing.T) {
+	shib := strings.Replace(cmcQuote("SHIB", 0.00001), `"circulating_supply":19000000`, `"circulating_supply":589000000000000`, 1)
+	f := newFakeProviders(t, map[string]http.HandlerFunc{"cmc": body(shib)})
+	a := newTestAgent(t, f.env())
+
+	response, _ := a.processTask(context.Background(), session{}, "/price shib")
+	if want := "- **Circulating Supply:** 589,000,000,000,000 (589.00T)"; !strings.Contains(response, want) {
+		t.Errorf("response is missing %q:\n%s", want, response)
+	}
+}
+
+func TestSummaryLine(t *testing.T) {
+	tests := []struct {
+		data MarketData
+		want string
+	}{
+		{MarketData{Symbol: "BTC", Price: "$63,245.12", Change24h: "2.10%"}, "BTC $63,245.12 🟢 +2.10% (24h)"},
+		{MarketData{Symbol: "ETH", Price: "€2,900.00", Change24h: "-1.30%"}, "ETH €2,900.00 🔴 -1.30% (24h)"},
+		{MarketData{Symbol: "PEPE", Price: "$0.000001234"}, "PEPE $0.000001234"},
+		{MarketData{Name: "Token", Price: "$1.00", Change24h: "n/a"}, "Token $1.00"},
+		{MarketData{Symbol: "BTC", Change24h: "2.10%"}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.data.Summary(); got != tt.want {
+			t.Errorf("Summary(%+v) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestSummaryLineLeadsTheOverview(t *testing.T) {
+	a := newTestAgent(t, nil)
+	output := a.formatOutput("token_source:coinmarketcap;symbol:BTC;name:Bitcoin;current_price_usd:$63,245.12;24h_change:2.10%", map[string]string{})
+
+	lines := strings.Split(output, "\n")
+	if len(lines) < 2 || lines[1] != "**BTC $63,245.12 🟢 +2.10% (24h)**" {
+		t.Errorf("the summary is not the line after the header:\n%s", output)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// --- Per-Session Request Throttle ---
+
+const (
+	defaultSessionRequestsPerMinute = 20
+	sessionThrottleWindow           = time.Minute
+
+	// directThrottleKey is the throttle session used by direct ProcessTask
+	// calls, which have no room. Room IDs never contain parentheses.
+	directThrottleKey = "(direct)"
+)
+
+// sessionThrottle limits how many requests one room may make per window, so
+// a single chatty session can't burn the shared upstream quotas. Direct calls
+// share directThrottleKey and HTTP API clients are keyed by address. Each room
+// keeps the timestamps of its requests inside the sliding window; rooms that
+// go quiet are dropped on the next sweep.
+type sessionThrottle struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	requests  map[string][]time.Time
+	lastSweep time.Time
+}
+
+// newSessionThrottle creates a throttle; a zero limit disables it.
+func newSessionThrottle(limit int, window time.Duration) *sessionThrottle {
+	return &sessionThrottle{
+		limit:     limit,
+		window:    window,
+		requests:  make(map[string][]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow reports whether room may make cost more requests now, recording them
+// if so, along with how many requests the room has left in the window. A
+// request that doesn't fit is rejected whole.
+func (t *sessionThrottle) allow(room string, cost int) (bool, int) {
+	if t.limit <= 0 {
+		return true, t.limit
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-t.window)
+	if now.Sub(t.lastSweep) >= t.window {
+		for r, times := range t.requests {
+			if len(times) == 0 || !times[len(times)-1].After(cutoff) {
+				delete(t.requests, r)
+			}
+		}
+		t.lastSweep = now
+	}
+
+	recent := t.requests[room]
+	for len(recent) > 0 && !recent[0].After(cutoff) {
+		recent = recent[1:]
+	}
+	remaining := t.limit - len(recent)
+	if cost > remaining {
+		t.requests[room] = recent
+		return false, remaining
+	}
+	for range cost {
+		recent = append(recent, now)
+	}
+	t.requests[room] = recent
+	return true, remaining - cost
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottleAllowChargesCost(t *testing.T) {
+	throttle := newSessionThrottle(3, time.Minute)
+
+	if ok, remaining := throttle.allow("room", 2); !ok || remaining != 1 {
+		t.Fatalf("allow(2) = %v, %d; want true, 1", ok, remaining)
+	}
+	if ok, remaining := throttle.allow("room", 2); ok || remaining != 1 {
+		t.Fatalf("allow(2) over budget = %v, %d; want false, 1", ok, remaining)
+	}
+	if ok, _ := throttle.allow("room", 1); !ok {
+		t.Fatal("a rejected request still consumed budget")
+	}
+	if ok, _ := throttle.allow("other", 3); !ok {
+		t.Error("one room's requests limited another room")
+	}
+	if ok, _ := throttle.allow("", 4); ok {
+		t.Error("requests without a room were not limited")
+	}
+}
+
+func TestBatchCountsAgainstSessionThrottle(t *testing.T) {
+	a := newTestAgent(t, map[string]string{"SESSION_REQUESTS_PER_MINUTE": "3"})
+	withCommand(t, "/ok", command{run: func(*PMOAgent, context.Context, []string, map[string]string) (string, error) {
+		return "fine", nil
+	}})
+	s := session{room: "room"}
+
+	if response, _ := a.processTask(context.Background(), s, "/batch\n/ok\n/ok"); strings.Count(response, "fine") != 2 {
+		t.Fatalf("first batch was not run:\n%s", response)
+	}
+	response, _ := a.processTask(context.Background(), s, "/batch\n/ok\n/ok")
+	if !strings.Contains(response, "only send 1 more") {
+		t.Errorf("over-budget batch response = %q, want the remaining-budget message", response)
+	}
+	if response, _ := a.processTask(context.Background(), s, "/ok"); response != "fine" {
+		t.Errorf("single command after a rejected batch = %q, want fine", response)
+	}
+	if response, _ := a.processTask(context.Background(), s, "/ok"); !strings.Contains(response, "too fast") {
+		t.Errorf("command over the limit = %q, want the throttle message", response)
+	}
+}
+
+func TestSessionThrottleRejectsFlood(t *testing.T) {
+	a := newTestAgent(t, map[string]string{"SESSION_REQUESTS_PER_MINUTE": "2"})
+	withCommand(t, "/ok", command{run: func(*PMOAgent, context.Context, []string, map[string]string) (string, error) {
+		return "fine", nil
+	}})
+	ctx := context.Background()
+	s := session{room: "flooder"}
+
+	for range 2 {
+		if response, _ := a.processTask(ctx, s, "/ok"); response != "fine" {
+			t.Fatalf("request within the limit = %q", response)
+		}
+	}
+	if response, _ := a.processTask(ctx, s, "/ok"); !strings.Contains(response, "sending requests too fast, please slow down") {
+		t.Errorf("request over the limit = %q, want the throttle message", response)
+	}
+	if response, _ := a.processTask(ctx, session{room: "quiet"}, "/ok"); response != "fine" {
+		t.Errorf("another room was throttled: %q", response)
+	}
+}
+
+func TestThrottleWindowSlidesAndSweeps(t *testing.T) {
+	throttle := newSessionThrottle(1, 20*time.Millisecond)
+	throttle.allow("old", 1)
+	if ok, _ := throttle.allow("room", 1); !ok {
+		t.Fatal("first request rejected")
+	}
+	if ok, _ := throttle.allow("room", 1); ok {
+		t.Fatal("second request inside the window allowed")
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if ok, _ := throttle.allow("room", 1); !ok {
+		t.Error("request after the window passed was rejected")
+	}
+	throttle.mu.Lock()
+	_, kept := throttle.requests["old"]
+	throttle.mu.Unlock()
+	if kept {
+		t.Error("a room idle for a whole window was not swept")
+	}
+}
+
+func TestDirectCallsAreThrottled(t *testing.T) {
+	a := newTestAgent(t, map[string]string{"SESSION_REQUESTS_PER_MINUTE": "2"})
+	withCommand(t, "/ok", command{run: func(*PMOAgent, context.Context, []string, map[string]string) (string, error) {
+		return "fine", nil
+	}})
+
+	for range 2 {
+		if response, _ := a.ProcessTask(context.Background(), "/ok"); response != "fine" {
+			t.Fatalf("direct call within the limit = %q", response)
+		}
+	}
+	if response, _ := a.ProcessTask(context.Background(), "/ok"); !strings.Contains(response, "too fast") {
+		t.Errorf("direct call over the limit = %q, want the throttle message", response)
+	}
+}
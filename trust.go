@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// --- Volume Trust (Wash-Trading Flag) ---
+
+// lowTrustVolumeThreshold is the share of volume on red trust-score markets
+// above which the reported volume is flagged as possibly inflated.
+const lowTrustVolumeThreshold = 0.5
+
+// CoinGeckoTicker is one exchange market of a coin. TrustScore is "green",
+// "yellow", "red" or null when CoinGecko hasn't rated the market.
+type CoinGeckoTicker struct {
+	TrustScore      *string            `json:"trust_score"`
+	ConvertedVolume map[string]float64 `json:"converted_volume"`
+}
+
+// CoinGeckoTickersResponse is the /coins/{id}/tickers payload (the first page
+// holds the top 100 markets by volume).
+type CoinGeckoTickersResponse struct {
+	Tickers []CoinGeckoTicker `json:"tickers"`
+}
+
+// volumeTrustFields returns a low_trust_volume field when most of the rated
+// USD volume trades on red trust-score markets, a common sign of wash
+// trading. Unrated markets are ignored; with no rated volume it returns "".
+func volumeTrustFields(tickers []CoinGeckoTicker) string {
+	var rated, lowTrust float64
+	for _, ticker := range tickers {
+		if ticker.TrustScore == nil {
+			continue
+		}
+		volume := ticker.ConvertedVolume["usd"]
+		rated += volume
+		if *ticker.TrustScore == "red" {
+			lowTrust += volume
+		}
+	}
+	if rated <= 0 || lowTrust/rated <= lowTrustVolumeThreshold {
+		return ""
+	}
+	return fmt.Sprintf(";low_trust_volume:%.0f%%", lowTrust/rated*100)
+}
+
+// coinTrustFields fetches the coin's markets from the tickers endpoint and
+// returns its volumeTrustFields. It is best effort: any failure yields "".
+func (a *PMOAgent) coinTrustFields(ctx context.Context, coinID string) string {
+	req, err := a.newCoinGeckoRequest(ctx, fmt.Sprintf("/coins/%s/tickers?order=volume_desc", coinID))
+	if err != nil {
+		slog.Error("Error creating CG tickers request", "err", err)
+		return ""
+	}
+
+	var tickers CoinGeckoTickersResponse
+	status, err := a.fetchJSON(req, &tickers)
+	if status != http.StatusOK || err != nil {
+		slog.Warn("No CoinGecko tickers", "id", coinID, "status", status, "err", err)
+		return ""
+	}
+	return volumeTrustFields(tickers.Tickers)
+}
+
+// coinGeckoDetailFields returns the --details extras for a CoinGecko result:
+// the low-trust volume flag. The tickers endpoint is an extra request, so
+// plain price lookups skip it.
+func (a *PMOAgent) coinGeckoDetailFields(ctx context.Context, response string) string {
+	parts := parseRawOutput(response)
+	coinID := parts["coin_id"]
+	if parts["token_source"] != "coingecko" || coinID == "" {
+		return ""
+	}
+	return a.coinTrustFields(ctx, coinID)
+}
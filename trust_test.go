@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestVolumeTrustFields(t *testing.T) {
+	ticker := func(trust string, usd float64) CoinGeckoTicker {
+		ticker := CoinGeckoTicker{ConvertedVolume: map[string]float64{"usd": usd}}
+		if trust != "" {
+			ticker.TrustScore = &trust
+		}
+		return ticker
+	}
+	tests := []struct {
+		name    string
+		tickers []CoinGeckoTicker
+		want    string
+	}{
+		{"low-trust dominated", []CoinGeckoTicker{ticker("red", 800), ticker("green", 150), ticker("yellow", 50)}, ";low_trust_volume:80%"},
+		{"mostly trusted", []CoinGeckoTicker{ticker("red", 400), ticker("green", 600)}, ""},
+		{"exactly half is not flagged", []CoinGeckoTicker{ticker("red", 500), ticker("green", 500)}, ""},
+		{"unrated markets are ignored", []CoinGeckoTicker{ticker("red", 300), ticker("green", 100), ticker("", 10000)}, ";low_trust_volume:75%"},
+		{"no trust data", []CoinGeckoTicker{ticker("", 1000)}, ""},
+		{"no tickers", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := volumeTrustFields(tt.tickers); got != tt.want {
+			t.Errorf("%s: volumeTrustFields = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetailsFlagsLowTrustVolume(t *testing.T) {
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"coingecko": func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/coins/bitcoin/tickers":
+				respond(w, http.StatusOK, `{"tickers":[
+					{"trust_score":"red","converted_volume":{"usd":900000}},
+					{"trust_score":"green","converted_volume":{"usd":100000}}]}`)
+			case "/coins/bitcoin":
+				respond(w, http.StatusOK, cgCoin("bitcoin", "btc", 60000))
+			default:
+				respond(w, http.StatusNotFound, `{}`)
+			}
+		},
+	})
+	a := newTestAgent(t, f.env())
+	ctx := context.Background()
+
+	want := "⚠️ Volume may be inflated: 90% trades on low-trust exchanges"
+	if response, _ := a.processTask(ctx, session{}, "/price btc --source=cg --details"); !strings.Contains(response, want) {
+		t.Errorf("response is missing %q:\n%s", want, response)
+	}
+	if response, _ := a.processTask(ctx, session{}, "/price btc --source=cg"); strings.Contains(response, "inflated") {
+		t.Errorf("the trust flag was shown without --details:\n%s", response)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import "fmt"
+
+// --- Volume to Market Cap Ratio (Turnover) ---
+
+const (
+	// highTurnoverPercent and lowActivityPercent bound a "normal" day's
+	// volume as a share of market cap.
+	highTurnoverPercent = 10.0
+	lowActivityPercent  = 1.0
+)
+
+// volumeToMarketCap returns 24h volume as a percentage of market cap, read
+// from the formatted figures. It reports false unless both are positive.
+func volumeToMarketCap(volume, marketCap string) (float64, bool) {
+	volumeValue, ok := parseDisplayNumber(volume)
+	if !ok || volumeValue <= 0 {
+		return 0, false
+	}
+	capValue, ok := parseDisplayNumber(marketCap)
+	if !ok || capValue <= 0 {
+		return 0, false
+	}
+	return volumeValue / capValue * 100, true
+}
+
+// TurnoverSummary describes the volume/market cap ratio, e.g. "12.40% (high
+// turnover)", or "" when either figure is missing.
+func (d MarketData) TurnoverSummary() string {
+	if d.VolumeToMarketCap <= 0 {
+		return ""
+	}
+	label := "normal turnover"
+	switch {
+	case d.VolumeToMarketCap >= highTurnoverPercent:
+		label = "high turnover"
+	case d.VolumeToMarketCap < lowActivityPercent:
+		label = "low activity"
+	}
+	return fmt.Sprintf("%.2f%% (%s)", d.VolumeToMarketCap, label)
+}
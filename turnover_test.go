@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVolumeToMarketCap(t *testing.T) {
+	tests := []struct {
+		volume, marketCap string
+		want              float64
+		ok                bool
+	}{
+		{"$124,000,000.00", "$1,000,000,000.00", 12.4, true},
+		{"€5,000.00", "€1,000,000.00", 0.5, true},
+		{"1,500.00 CHF", "30,000.00 CHF", 5, true},
+		{"", "$1,000,000.00", 0, false},
+		{"$5,000.00", "", 0, false},
+		{"$5,000.00", "$0.00", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := volumeToMarketCap(tt.volume, tt.marketCap)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("volumeToMarketCap(%q, %q) = %v, %v; want %v, %v", tt.volume, tt.marketCap, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTurnoverSummary(t *testing.T) {
+	tests := []struct {
+		ratio float64
+		want  string
+	}{
+		{12.4, "12.40% (high turnover)"},
+		{10, "10.00% (high turnover)"},
+		{4.25, "4.25% (normal turnover)"},
+		{1, "1.00% (normal turnover)"},
+		{0.5, "0.50% (low activity)"},
+		{0, ""},
+	}
+	for _, tt := range tests {
+		if got := (MarketData{VolumeToMarketCap: tt.ratio}).TurnoverSummary(); got != tt.want {
+			t.Errorf("TurnoverSummary(%v) = %q, want %q", tt.ratio, got, tt.want)
+		}
+	}
+
+	data := parseMarketData("token_source:coinmarketcap;current_price_usd:$1.00;volume_24h:$124,000,000.00;market_cap_usd:$1,000,000,000.00")
+	if got := data.TurnoverSummary(); got != "12.40% (high turnover)" {
+		t.Errorf("TurnoverSummary from a raw response = %q", got)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- DefiLlama Protocol TVL (/tvl) ---
+
+const (
+	maxTVLChains          = 5
+	maxProtocolSuggestion = 5
+
+	// protocolListTTL is how long the /protocols list is reused. It only
+	// resolves queries to slugs; everything shown is fetched fresh from
+	// /protocol/{slug}.
+	protocolListTTL = time.Hour
+)
+
+// tvlExtraKeys are the currentChainTvls entries DefiLlama keeps out of a
+// protocol's headline TVL. Per-chain variants ("Ethereum-staking") are
+// excluded by their hyphen instead.
+var tvlExtraKeys = map[string]bool{
+	"borrowed":      true,
+	"staking":       true,
+	"pool2":         true,
+	"doublecounted": true,
+	"liquidstaking": true,
+	"vesting":       true,
+	"treasury":      true,
+	"offers":        true,
+}
+
+// DefiLlamaProtocolSummary is one entry of DefiLlama's /protocols list, used
+// to resolve a user's query to a slug. TVL only ranks matches and suggestions.
+type DefiLlamaProtocolSummary struct {
+	Name   string  `json:"name"`
+	Slug   string  `json:"slug"`
+	Symbol string  `json:"symbol"`
+	TVL    float64 `json:"tvl"`
+}
+
+// DefiLlamaProtocol is DefiLlama's /protocol/{slug} response. TVL is the
+// protocol's daily history, oldest first; its last point is the current TVL.
+type DefiLlamaProtocol struct {
+	Name             string              `json:"name"`
+	Category         string              `json:"category"`
+	CurrentChainTvls map[string]float64  `json:"currentChainTvls"`
+	TVL              []DefiLlamaTVLPoint `json:"tvl"`
+}
+
+// DefiLlamaTVLPoint is one point of a protocol's TVL history.
+type DefiLlamaTVLPoint struct {
+	Date              int64   `json:"date"`
+	TotalLiquidityUSD float64 `json:"totalLiquidityUSD"`
+}
+
+// protocolList caches the /protocols list, which is several megabytes and
+// changes slowly. The zero value is an empty list.
+type protocolList struct {
+	mu        sync.Mutex
+	protocols []DefiLlamaProtocolSummary
+	fetchedAt time.Time
+}
+
+// chainTVL is one chain's share of a protocol's TVL.
+type chainTVL struct {
+	chain string
+	tvl   float64
+}
+
+// chainBreakdown returns the protocol's TVL per chain, largest first, without
+// the borrowed/staking/... extras.
+func (p DefiLlamaProtocol) chainBreakdown() []chainTVL {
+	var chains []chainTVL
+	for chain, tvl := range p.CurrentChainTvls {
+		if strings.Contains(chain, "-") || tvlExtraKeys[strings.ToLower(chain)] || tvl <= 0 {
+			continue
+		}
+		chains = append(chains, chainTVL{chain, tvl})
+	}
+	sort.Slice(chains, func(i, j int) bool {
+		if chains[i].tvl != chains[j].tvl {
+			return chains[i].tvl > chains[j].tvl
+		}
+		return chains[i].chain < chains[j].chain
+	})
+	return chains
+}
+
+// change returns the percentage change of the current TVL since the newest
+// history point at least ago before it, or nil if the history doesn't reach
+// back that far.
+func (p DefiLlamaProtocol) change(ago time.Duration) *float64 {
+	if len(p.TVL) == 0 {
+		return nil
+	}
+	latest := p.TVL[len(p.TVL)-1]
+	cutoff := latest.Date - int64(ago/time.Second)
+	for i := len(p.TVL) - 2; i >= 0; i-- {
+		if p.TVL[i].Date > cutoff {
+			continue
+		}
+		if p.TVL[i].TotalLiquidityUSD <= 0 {
+			return nil
+		}
+		change := (latest.TotalLiquidityUSD/p.TVL[i].TotalLiquidityUSD - 1) * 100
+		return &change
+	}
+	return nil
+}
+
+// getDefiLlama fetches a DefiLlama API path into target. On failure it
+// returns a human-readable message alongside the error (which may be nil).
+func (a *PMOAgent) getDefiLlama(ctx context.Context, path string, target interface{}) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.config.DefiLlamaBaseURL+path, nil)
+	if err != nil {
+		slog.Error("Error creating DefiLlama request", "err", err)
+		return "Error creating HTTP request.", err
+	}
+
+	message, err := a.fetchProviderJSON(req, target, "DefiLlama")
+	if errors.Is(err, errResponseTooLarge) {
+		message += " Raise DEFILLAMA_MAX_RESPONSE_BYTES to allow it."
+	}
+	return message, err
+}
+
+// getProtocolList returns DefiLlama's protocol list, fetching it at most once
+// per protocolListTTL; concurrent callers share a single fetch. If a refresh
+// fails, the previous list is kept.
+func (a *PMOAgent) getProtocolList(ctx context.Context) ([]DefiLlamaProtocolSummary, string, error) {
+	a.protocols.mu.Lock()
+	defer a.protocols.mu.Unlock()
+	if a.protocols.protocols != nil && time.Since(a.protocols.fetchedAt) < protocolListTTL {
+		return a.protocols.protocols, "", nil
+	}
+
+	var protocols []DefiLlamaProtocolSummary
+	if message, err := a.getDefiLlama(ctx, "/protocols", &protocols); message != "" {
+		if a.protocols.protocols != nil {
+			slog.Warn("DefiLlama protocol list refresh failed, keeping the cached list", "err", err)
+			return a.protocols.protocols, "", nil
+		}
+		return nil, message, err
+	}
+	a.protocols.protocols, a.protocols.fetchedAt = protocols, time.Now()
+	return protocols, "", nil
+}
+
+// findProtocol matches a query against protocol slugs, names and symbols,
+// case-insensitively. Spaces in the query are treated as hyphens for slugs.
+// Several protocols can share a symbol, so the largest by TVL wins there.
+func findProtocol(protocols []DefiLlamaProtocolSummary, query string) (DefiLlamaProtocolSummary, bool) {
+	slug := strings.ToLower(strings.Join(strings.Fields(query), "-"))
+	var bySymbol *DefiLlamaProtocolSummary
+	for i, p := range protocols {
+		if p.Slug == slug || strings.EqualFold(p.Name, query) {
+			return p, true
+		}
+		if strings.EqualFold(p.Symbol, query) && (bySymbol == nil || p.TVL > bySymbol.TVL) {
+			bySymbol = &protocols[i]
+		}
+	}
+	if bySymbol != nil {
+		return *bySymbol, true
+	}
+	return DefiLlamaProtocolSummary{}, false
+}
+
+// suggestProtocols returns the slugs closest to query: those that contain it
+// first (largest TVL first), then by edit distance, dropping anything too far
+// off. Versioned slugs are also compared by their base ("uniswap" of "uniswap-v3").
+func suggestProtocols(protocols []DefiLlamaProtocolSummary, query string) []string {
+	query = strings.ToLower(strings.Join(strings.Fields(query), "-"))
+	maxDistance := len(query)/3 + 1
+
+	type candidate struct {
+		slug     string
+		distance int
+		tvl      float64
+	}
+	var candidates []candidate
+	for _, p := range protocols {
+		base, _, _ := strings.Cut(p.Slug, "-")
+		distance := min(editDistance(query, p.Slug), editDistance(query, base))
+		if strings.Contains(p.Slug, query) {
+			distance = 0
+		}
+		if distance <= maxDistance {
+			candidates = append(candidates, candidate{p.Slug, distance, p.TVL})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].tvl > candidates[j].tvl
+	})
+
+	var suggestions []string
+	for i := 0; i < len(candidates) && i < maxProtocolSuggestion; i++ {
+		suggestions = append(suggestions, candidates[i].slug)
+	}
+	return suggestions
+}
+
+// getProtocolTVL handles `/tvl <protocol>`: the protocol's current TVL, its
+// 24h and 7d change and the chains it is spread across. The cached
+// /protocols list resolves the query; the figures come from /protocol/{slug}.
+func (a *PMOAgent) getProtocolTVL(ctx context.Context, args []string, _ map[string]string) (string, error) {
+	query := strings.Join(args, " ")
+
+	protocols, message, err := a.getProtocolList(ctx)
+	if message != "" {
+		return message, err
+	}
+	protocol, ok := findProtocol(protocols, query)
+	if !ok {
+		response := fmt.Sprintf("Unknown protocol: %s.", query)
+		if suggestions := suggestProtocols(protocols, query); len(suggestions) > 0 {
+			response += fmt.Sprintf(" Did you mean: %s?", strings.Join(suggestions, ", "))
+		}
+		return response, nil
+	}
+
+	// Everything shown comes from this one response, so the TVL, its changes
+	// and the chain split always agree with each other
+	var details DefiLlamaProtocol
+	if message, err := a.getDefiLlama(ctx, "/protocol/"+url.PathEscape(protocol.Slug), &details); message != "" {
+		return message, err
+	}
+	if len(details.TVL) == 0 {
+		return fmt.Sprintf("No TVL data found for %s.", protocol.Name), nil
+	}
+	current := details.TVL[len(details.TVL)-1].TotalLiquidityUSD
+	chains := details.chainBreakdown()
+	var chainTotal float64
+	for _, c := range chains {
+		chainTotal += c.tvl
+	}
+
+	var responseBuilder strings.Builder
+	responseBuilder.WriteString(fmt.Sprintf("🏦 **%s TVL**", details.Name))
+	if details.Category != "" {
+		responseBuilder.WriteString(fmt.Sprintf(" (%s)", details.Category))
+	}
+	responseBuilder.WriteString("\n")
+	responseBuilder.WriteString(fmt.Sprintf("- **TVL:** %s\n", formatLargeCurrency(current, "usd")))
+	if change := details.change(24 * time.Hour); change != nil {
+		responseBuilder.WriteString(fmt.Sprintf("- **24h Change:** %s\n", a.formatChange(*change)))
+	}
+	if change := details.change(7 * 24 * time.Hour); change != nil {
+		responseBuilder.WriteString(fmt.Sprintf("- **7d Change:** %s\n", a.formatChange(*change)))
+	}
+	// Shares use the chains' own total so they always add up to 100%
+	if len(chains) > 0 && chainTotal > 0 {
+		responseBuilder.WriteString("- **Chains:**\n")
+		var others float64
+		for i, c := range chains {
+			if i >= maxTVLChains {
+				others += c.tvl
+				continue
+			}
+			responseBuilder.WriteString(fmt.Sprintf("  - %s: %s (%.1f%%)\n", c.chain, formatLargeCurrency(c.tvl, "usd"), c.tvl/chainTotal*100))
+		}
+		if others > 0 {
+			responseBuilder.WriteString(fmt.Sprintf("  - %d others: %s (%.1f%%)\n", len(chains)-maxTVLChains, formatLargeCurrency(others, "usd"), others/chainTotal*100))
+		}
+	}
+	responseBuilder.WriteString("\n*(Data provided by DEFILLAMA)*")
+
+	return responseBuilder.String(), nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newTVLServer serves a one-protocol /protocols list and that protocol's
+// /protocol/{slug} response, counting list fetches.
+func newTVLServer(t *testing.T, listFetches *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/protocols":
+			listFetches.Add(1)
+			w.Write([]byte(`[{"name":"Aave V3","slug":"aave-v3","symbol":"AAVE","tvl":1},
+				{"name":"Uniswap V3","slug":"uniswap-v3","symbol":"UNI","tvl":2}]`))
+		case "/protocol/aave-v3":
+			w.Write([]byte(`{"name":"Aave V3","category":"Lending",
+				"currentChainTvls":{"Ethereum":300000000,"Arbitrum":100000000,"Ethereum-borrowed":50,"borrowed":50},
+				"tvl":[{"date":1700000000,"totalLiquidityUSD":390000000},{"date":1700086400,"totalLiquidityUSD":400000000}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProtocolTVLCachesProtocolList(t *testing.T) {
+	var listFetches atomic.Int32
+	server := newTVLServer(t, &listFetches)
+	a := newTestAgent(t, map[string]string{"DEFILLAMA_BASE_URL": server.URL})
+
+	for range 2 {
+		if _, err := a.getProtocolTVL(context.Background(), []string{"aave", "v3"}, nil); err != nil {
+			t.Fatalf("getProtocolTVL: %v", err)
+		}
+	}
+	if n := listFetches.Load(); n != 1 {
+		t.Errorf("/protocols fetched %d times, want 1", n)
+	}
+}
+
+func TestProtocolTVLUsesProtocolDetails(t *testing.T) {
+	server := newTVLServer(t, new(atomic.Int32))
+	a := newTestAgent(t, map[string]string{"DEFILLAMA_BASE_URL": server.URL})
+
+	response, err := a.getProtocolTVL(context.Background(), []string{"AAVE"}, nil)
+	if err != nil {
+		t.Fatalf("getProtocolTVL: %v", err)
+	}
+	for _, want := range []string{"Aave V3 TVL", "(Lending)", "- **TVL:** $400.00M", "24h Change:** 2.56%", "Ethereum: $300.00M (75.0%)", "Arbitrum: $100.00M (25.0%)"} {
+		if !strings.Contains(response, want) {
+			t.Errorf("response is missing %q:\n%s", want, response)
+		}
+	}
+	if strings.Contains(response, "7d Change") || strings.Contains(response, "borrowed") {
+		t.Errorf("response shows a 7d change or borrowed TVL DefiLlama didn't report:\n%s", response)
+	}
+}
+
+func TestProtocolTVLSuggestsUnknownNames(t *testing.T) {
+	server := newTVLServer(t, new(atomic.Int32))
+	a := newTestAgent(t, map[string]string{"DEFILLAMA_BASE_URL": server.URL})
+
+	response, err := a.getProtocolTVL(context.Background(), []string{"uniswap"}, nil)
+	if err != nil {
+		t.Fatalf("getProtocolTVL: %v", err)
+	}
+	if want := "Unknown protocol: uniswap. Did you mean: uniswap-v3?"; response != want {
+		t.Errorf("response = %q, want %q", response, want)
+	}
+}
+
+func TestResponseLimitAllowsLargeDefiLlamaBodies(t *testing.T) {
+	a := newTestAgent(t, map[string]string{"DEFILLAMA_BASE_URL": "https://api.llama.fi"})
+	llama, _ := http.NewRequest("GET", "https://api.llama.fi/protocols", nil)
+	other, _ := http.NewRequest("GET", "https://api.llama.fi.example.com/protocols", nil)
+
+	if got := a.responseLimit(llama); got != a.config.DefiLlamaMaxResponseBytes {
+		t.Errorf("DefiLlama limit = %d, want %d", got, a.config.DefiLlamaMaxResponseBytes)
+	}
+	if got := a.responseLimit(other); got != a.config.MaxResponseBytes {
+		t.Errorf("other host limit = %d, want %d", got, a.config.MaxResponseBytes)
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TeneoProtocolAI/teneo-agent-sdk/pkg/types"
+)
+
+// --- Price Watches (/watch, /unwatch) ---
+
+// maxWatchesPerRoom caps how many alerts one room can have active.
+const maxWatchesPerRoom = 20
+
+// priceWatch is a one-shot alert on a symbol's USD price crossing target.
+// Volume watches instead fire when the 24h USD volume reaches target, which
+// is multiplier times the baseline volume recorded when the watch was set.
+type priceWatch struct {
+	symbol string // upper-case, as typed (or a contract address)
+	above  bool
+	target float64
+
+	volume     bool
+	multiplier float64
+	baseline   float64
+}
+
+func (w priceWatch) String() string {
+	if w.volume {
+		return fmt.Sprintf("%s volume above %s× its %s baseline", w.symbol,
+			strconv.FormatFloat(w.multiplier, 'f', -1, 64), formatLargeCurrency(w.baseline, "usd"))
+	}
+	direction := "below"
+	if w.above {
+		direction = "above"
+	}
+	return fmt.Sprintf("%s %s %s", w.symbol, direction, formatPrice(w.target, "usd"))
+}
+
+// triggered reports whether the latest quote satisfies the watch.
+func (w priceWatch) triggered(q watchQuote) bool {
+	switch {
+	case w.volume:
+		return q.volume > 0 && q.volume >= w.target
+	case q.price <= 0:
+		return false
+	case w.above:
+		return q.price >= w.target
+	}
+	return q.price <= w.target
+}
+
+// watchQuote is the USD price and 24h volume a watch is checked against;
+// either is 0 when the provider didn't report it.
+type watchQuote struct {
+	price, volume float64
+}
+
+// quoteForWatch looks a watched symbol up in USD through the cache. It
+// reports false when no provider could price the symbol.
+func (a *PMOAgent) quoteForWatch(ctx context.Context, symbol string) (watchQuote, bool) {
+	result := a.lookupToken(ctx, symbol, map[string]string{"currency": "usd"})
+	parts := parseRawOutput(result.raw)
+	var q watchQuote
+	q.price, _ = strconv.ParseFloat(parts["price_value"], 64)
+	q.volume, _ = parseDisplayNumber(parts["volume_24h"])
+	return q, result.found && q.price > 0
+}
+
+// parseMultiplier parses a volume multiplier such as "3", "2.5x" or "3×",
+// which must be above 1 for a spike to mean anything.
+func parseMultiplier(raw string) (float64, bool) {
+	raw = strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(raw), "x"), "×")
+	value, ok := parseAmount(raw)
+	return value, ok && value > 1
+}
+
+// watchRegistry holds every room's alerts. Commands and the background
+// checker use it concurrently, so every access takes the lock.
+type watchRegistry struct {
+	mu      sync.Mutex
+	rooms   map[string][]priceWatch
+	senders map[string]types.MessageSender // latest sender per room, for delivery
+}
+
+func newWatchRegistry() *watchRegistry {
+	return &watchRegistry{
+		rooms:   make(map[string][]priceWatch),
+		senders: make(map[string]types.MessageSender),
+	}
+}
+
+// add registers w for the session's room, returning the room's alert count.
+// It reports false, adding nothing, when the room is already at the cap.
+func (r *watchRegistry) add(s session, w priceWatch) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.rooms[s.room]) >= maxWatchesPerRoom {
+		return len(r.rooms[s.room]), false
+	}
+	r.rooms[s.room] = append(r.rooms[s.room], w)
+	if s.sender != nil {
+		r.senders[s.room] = s.sender
+	}
+	return len(r.rooms[s.room]), true
+}
+
+// remove deletes every alert on symbol in room, returning how many there were.
+func (r *watchRegistry) remove(room, symbol string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.rooms[room][:0]
+	for _, w := range r.rooms[room] {
+		if !strings.EqualFold(w.symbol, symbol) {
+			kept = append(kept, w)
+		}
+	}
+	removed := len(r.rooms[room]) - len(kept)
+	r.setRoom(room, kept)
+	return removed
+}
+
+// clear deletes all of room's alerts, returning how many there were.
+func (r *watchRegistry) clear(room string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := len(r.rooms[room])
+	r.setRoom(room, nil)
+	return removed
+}
+
+// take removes one alert matching w, reporting false if it is already gone
+// (e.g. unwatched while its price was being checked).
+func (r *watchRegistry) take(room string, w priceWatch) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.rooms[room] {
+		if existing == w {
+			r.setRoom(room, append(r.rooms[room][:i:i], r.rooms[room][i+1:]...))
+			return true
+		}
+	}
+	return false
+}
+
+// setRoom replaces room's alerts, forgetting the room once it has none.
+// Callers must hold the lock.
+func (r *watchRegistry) setRoom(room string, watches []priceWatch) {
+	if len(watches) == 0 {
+		delete(r.rooms, room)
+		delete(r.senders, room)
+		return
+	}
+	r.rooms[room] = watches
+}
+
+// list returns a copy of room's alerts.
+func (r *watchRegistry) list(room string) []priceWatch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]priceWatch(nil), r.rooms[room]...)
+}
+
+// snapshot returns a copy of every room's alerts and senders.
+func (r *watchRegistry) snapshot() (map[string][]priceWatch, map[string]types.MessageSender) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rooms := make(map[string][]priceWatch, len(r.rooms))
+	for room, watches := range r.rooms {
+		rooms[room] = append([]priceWatch(nil), watches...)
+	}
+	senders := make(map[string]types.MessageSender, len(r.senders))
+	for room, sender := range r.senders {
+		senders[room] = sender
+	}
+	return rooms, senders
+}
+
+// watchCommand handles `/watch <symbol> <above|below> <usd price>` and
+// `/watch <symbol> volume <multiplier>`, plus `/watch` to list the room's
+// alerts and `/watch clear` to remove them all.
+func (a *PMOAgent) watchCommand(ctx context.Context, s session, args []string, _ map[string]string) (string, error) {
+	switch {
+	case len(args) == 0:
+		watches := a.watches.list(s.room)
+		if len(watches) == 0 {
+			return "You have no active alerts. " + usageFor("/watch"), nil
+		}
+		var responseBuilder strings.Builder
+		responseBuilder.WriteString(fmt.Sprintf("🔔 **Active Alerts** (%d)\n", len(watches)))
+		for _, w := range watches {
+			responseBuilder.WriteString(fmt.Sprintf("- %s\n", w))
+		}
+		return responseBuilder.String(), nil
+
+	case len(args) == 1 && strings.EqualFold(args[0], "clear"):
+		return fmt.Sprintf("Removed %s.", pluralAlerts(a.watches.clear(s.room))), nil
+
+	case len(args) != 3:
+		return usageFor("/watch"), nil
+	}
+
+	w := priceWatch{symbol: strings.ToUpper(args[0])}
+	switch strings.ToLower(args[1]) {
+	case "above", ">":
+		w.above = true
+	case "below", "<":
+	case "volume":
+		multiplier, ok := parseMultiplier(args[2])
+		if !ok {
+			return withUsage(fmt.Sprintf("Invalid multiplier: %s. Please provide a number above 1, e.g. 3x.", args[2]), "/watch"), nil
+		}
+		w.volume, w.multiplier = true, multiplier
+	default:
+		return withUsage(fmt.Sprintf("Unknown direction: %s. Use above, below or volume.", args[1]), "/watch"), nil
+	}
+	if !w.volume {
+		target, ok := parseAmount(args[2])
+		if !ok {
+			return withUsage(fmt.Sprintf("Invalid price: %s. Please provide a positive USD amount.", args[2]), "/watch"), nil
+		}
+		w.target = target
+	}
+
+	// A symbol that can't be priced now never will be, and would cost a full
+	// provider walk every interval, so typos are rejected up front
+	q, ok := a.quoteForWatch(ctx, w.symbol)
+	if !ok {
+		return fmt.Sprintf("Could not find a USD price for %s, so no alert was set. Please check the symbol.", w.symbol), nil
+	}
+	if w.volume {
+		if q.volume <= 0 {
+			return fmt.Sprintf("No 24h volume is available for %s right now, so a volume alert can't be set.", w.symbol), nil
+		}
+		w.baseline, w.target = q.volume, q.volume*w.multiplier
+	}
+
+	count, added := a.watches.add(s, w)
+	if !added {
+		return fmt.Sprintf("You already have %d alerts. Remove some with /unwatch or /watch clear first.", count), nil
+	}
+	if a.config.WatchInterval <= 0 {
+		slog.Warn("Watch added but WATCH_INTERVAL_SECONDS is 0, so it will never be checked", "symbol", w.symbol)
+	}
+	return fmt.Sprintf("🔔 Alert set: %s (%d active).", w, count), nil
+}
+
+// unwatchCommand handles `/unwatch <symbol>`, removing the room's alerts on it.
+func (a *PMOAgent) unwatchCommand(ctx context.Context, s session, args []string, _ map[string]string) (string, error) {
+	symbol := strings.ToUpper(args[0])
+	removed := a.watches.remove(s.room, symbol)
+	if removed == 0 {
+		return fmt.Sprintf("You have no alerts on %s.", symbol), nil
+	}
+	return fmt.Sprintf("Removed %s on %s.", pluralAlerts(removed), symbol), nil
+}
+
+// pluralAlerts renders a count such as "1 alert" or "3 alerts".
+func pluralAlerts(n int) string {
+	if n == 1 {
+		return "1 alert"
+	}
+	return strconv.Itoa(n) + " alerts"
+}
+
+// runWatches checks every alert each interval until the process exits.
+func (a *PMOAgent) runWatches(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// Each check recovers on its own so one bad alert can't stop the loop
+	for range ticker.C {
+		safeCall("watch check", func() { a.checkWatches(context.Background()) })
+	}
+}
+
+// checkWatches looks each watched symbol up once (through the cache) and
+// fires the alerts whose condition is met. Fired alerts are removed.
+func (a *PMOAgent) checkWatches(ctx context.Context) {
+	rooms, senders := a.watches.snapshot()
+
+	quotes := make(map[string]watchQuote)
+	for room, watches := range rooms {
+		for _, w := range watches {
+			q, checked := quotes[w.symbol]
+			if !checked {
+				q, _ = a.quoteForWatch(ctx, w.symbol)
+				quotes[w.symbol] = q
+			}
+			if !w.triggered(q) || !a.watches.take(room, w) {
+				continue
+			}
+			now := formatPrice(q.price, "usd")
+			if w.volume {
+				now = formatLargeCurrency(q.volume, "usd") + " 24h volume"
+			}
+			a.deliverWatch(ctx, senders[room], fmt.Sprintf("🔔 **Alert:** %s (now %s)", w, now))
+		}
+	}
+}
+
+// deliverWatch sends a fired alert to the room that set it, or through the
+// configured notifiers when there is no room (e.g. direct ProcessTask calls).
+func (a *PMOAgent) deliverWatch(ctx context.Context, sender types.MessageSender, msg string) {
+	if sender != nil {
+		err := sender.SendMessage(msg)
+		if err == nil {
+			return
+		}
+		slog.Error("Error sending alert to room, using notifiers instead", "err", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, a.config.HTTPTimeout)
+	defer cancel()
+	a.notify(ctx, msg)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUnwatchAndClear(t *testing.T) {
+	a := newTestAgent(t, nil)
+	ctx := context.Background()
+	s, other := session{room: "room"}, session{room: "other"}
+	for _, w := range []priceWatch{
+		{symbol: "BTC", above: true, target: 70000},
+		{symbol: "BTC", target: 50000},
+		{symbol: "ETH", above: true, target: 4000},
+		{symbol: "SOL", target: 100},
+	} {
+		a.watches.add(s, w)
+	}
+	a.watches.add(other, priceWatch{symbol: "BTC", above: true, target: 80000})
+
+	// Remove one symbol, case-insensitively
+	if response, _ := a.processTask(ctx, s, "/unwatch btc"); response != "Removed 2 alerts on BTC." {
+		t.Errorf("/unwatch btc = %q", response)
+	}
+	if got := len(a.watches.list(s.room)); got != 2 {
+		t.Errorf("%d alerts left after /unwatch, want 2", got)
+	}
+
+	// Remove a missing one
+	if response, _ := a.processTask(ctx, s, "/unwatch doge"); response != "You have no alerts on DOGE." {
+		t.Errorf("/unwatch doge = %q", response)
+	}
+	if got := len(a.watches.list(s.room)); got != 2 {
+		t.Errorf("/unwatch of a missing symbol removed alerts, %d left", got)
+	}
+
+	// Clear the rest
+	if response, _ := a.processTask(ctx, s, "/watch clear"); response != "Removed 2 alerts." {
+		t.Errorf("/watch clear = %q", response)
+	}
+	if response, _ := a.processTask(ctx, s, "/watch clear"); response != "Removed 0 alerts." {
+		t.Errorf("/watch clear with no alerts = %q", response)
+	}
+
+	// Another room's alerts are untouched throughout
+	if got := a.watches.list(other.room); len(got) != 1 || got[0].symbol != "BTC" {
+		t.Errorf("other room's alerts = %v, want its BTC alert kept", got)
+	}
+}
+
+func TestVolumeWatchTriggered(t *testing.T) {
+	w := priceWatch{symbol: "BTC", volume: true, multiplier: 3, baseline: 1e6, target: 3e6}
+	tests := []struct {
+		quote watchQuote
+		want  bool
+	}{
+		{watchQuote{price: 60000, volume: 2.9e6}, false},
+		{watchQuote{price: 60000, volume: 3e6}, true},
+		{watchQuote{price: 60000, volume: 5e6}, true},
+		// An unreported volume never fires, whatever the price does
+		{watchQuote{price: 1e9}, false},
+	}
+	for _, tt := range tests {
+		if got := w.triggered(tt.quote); got != tt.want {
+			t.Errorf("triggered(%+v) = %v, want %v", tt.quote, got, tt.want)
+		}
+	}
+
+	for raw, want := range map[string]float64{"3": 3, "2.5x": 2.5, "4×": 4, "1": 0, "0.5x": 0, "abc": 0} {
+		if got, ok := parseMultiplier(raw); ok != (want > 0) || (ok && got != want) {
+			t.Errorf("parseMultiplier(%q) = %v, %v; want %v", raw, got, ok, want)
+		}
+	}
+}
+
+func TestVolumeWatchFiresOnSpike(t *testing.T) {
+	var volume atomic.Int64
+	volume.Store(1_000_000)
+	f := newFakeProviders(t, map[string]http.HandlerFunc{
+		"cmc": func(w http.ResponseWriter, r *http.Request) {
+			respond(w, http.StatusOK, strings.Replace(cmcQuote("BTC", 60000), `"volume_24h":1000000`, fmt.Sprintf(`"volume_24h":%d`, volume.Load()), 1))
+		},
+	})
+	env := f.env()
+	env["CACHE_TTL_SECONDS"] = "0" // every check sees the latest volume
+	a := newTestAgent(t, env)
+	fake := &fakeNotifier{name: "fake"}
+	a.notifiers = []Notifier{fake}
+	ctx := context.Background()
+
+	response, _ := a.processTask(ctx, session{}, "/watch btc volume 3x")
+	if want := "🔔 Alert set: BTC volume above 3× its $1.00M baseline (1 active)."; response != want {
+		t.Fatalf("/watch response = %q, want %q", response, want)
+	}
+
+	volume.Store(2_500_000)
+	a.checkWatches(ctx)
+	if len(fake.messages) != 0 {
+		t.Fatalf("alert fired below the multiplier: %q", fake.messages)
+	}
+
+	volume.Store(3_500_000)
+	a.checkWatches(ctx)
+	want := []string{"🔔 **Alert:** BTC volume above 3× its $1.00M baseline (now $3.50M 24h volume)"}
+	if !slices.Equal(fake.messages, want) {
+		t.Errorf("alerts = %q, want %q", fake.messages, want)
+	}
+	if got := a.watches.list(""); len(got) != 0 {
+		t.Errorf("fired alert was kept: %v", got)
+	}
+}